@@ -0,0 +1,44 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+// AliasesToRegister is the config type of the alias config
+const AliasesToRegister = "aliasesToRegister"
+
+// readAliases loads the custom resource aliases specified in the alias config file, e.g., for
+// CRDs. They take precedence over cleaner.DefaultAliases.
+func readAliases() cleaner.Aliases {
+	aliases := cleaner.Aliases{}
+	bytes := readConfig(aliasConfigPath, AliasesToRegister)
+	if bytes == nil {
+		return aliases
+	}
+	if err := json.Unmarshal(bytes, &aliases); err != nil {
+		panic(fmt.Errorf("%w: %w", cleaner.ErrConfigInvalid, err))
+	}
+	for alias, gvr := range aliases {
+		if gvr.Version == "" || gvr.Resource == "" {
+			panic(fmt.Errorf("%w: alias %q requires a version and resource", cleaner.ErrConfigInvalid, alias))
+		}
+	}
+	return aliases
+}
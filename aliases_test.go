@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestReadResourceConfigAliases(t *testing.T) {
+	tests := []struct {
+		name          string
+		aliases       string
+		config        string
+		expected      schema.GroupVersionResource
+		expectedPanic bool
+	}{
+		{
+			name:     "default alias",
+			config:   `[{"resource": "deploy", "name": "widget-operator", "namespace": "widgets"}]`,
+			expected: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+		},
+		{
+			name:     "custom alias",
+			aliases:  `{"widget": {"group": "example.com", "version": "v1", "resource": "widgets"}}`,
+			config:   `[{"resource": "widget", "name": "a", "namespace": "widgets"}]`,
+			expected: schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"},
+		},
+		{
+			name:          "unknown alias",
+			config:        `[{"resource": "widget", "name": "a", "namespace": "widgets"}]`,
+			expectedPanic: true,
+		},
+		{
+			name:          "alias without version",
+			aliases:       `{"widget": {"group": "example.com", "resource": "widgets"}}`,
+			config:        `[{"resource": "widget", "name": "a", "namespace": "widgets"}]`,
+			expectedPanic: true,
+		},
+	}
+
+	defaultAliasPath, defaultResourcePath := aliasConfigPath, resourceConfigPath
+	defer func() { aliasConfigPath, resourceConfigPath = defaultAliasPath, defaultResourcePath }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			aliasConfigPath = filepath.Join(dir, "alias-config.json")
+			resourceConfigPath = filepath.Join(dir, "resource-config.json")
+			if tt.aliases != "" {
+				if err := os.WriteFile(aliasConfigPath, []byte(tt.aliases), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := os.WriteFile(resourceConfigPath, []byte(tt.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				if r := recover(); (r != nil) != tt.expectedPanic {
+					t.Errorf("expected panic %v, got %v", tt.expectedPanic, r)
+				}
+			}()
+
+			entries := readResourceConfig()
+			if len(entries) != 1 || entries[0].GroupVersionResource != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, entries)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var apiServicesGVR = schema.GroupVersionResource{Group: "apiregistration.k8s.io", Version: "v1", Resource: "apiservices"}
+
+// cleanupOrphanedAPIServices deletes aggregated APIServices whose backing Service no longer exists,
+// or whose Service no longer selects any Deployment, StatefulSet or DaemonSet. Such APIServices
+// cause discovery to fail for every client. If apiServiceMustDel is set, the deletion failures are returned.
+func cleanupOrphanedAPIServices(ctx context.Context, client ctrlclient.Client, dynamic dynamic.Interface) error {
+	if !orphanAPIServices {
+		return nil
+	}
+
+	log.Info("Searching for orphaned APIServices")
+	apiServices, err := dynamic.Resource(apiServicesGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Error(err, "failed to list APIServices")
+		if apiServiceMustDel {
+			return err
+		}
+		return nil
+	}
+
+	var errs []error
+	for _, apiService := range apiServices.Items {
+		if apiService.GetDeletionTimestamp() != nil {
+			continue
+		}
+		// APIServices without a Service are served by kube-apiserver itself
+		svcName, _, _ := unstructured.NestedString(apiService.Object, "spec", "service", "name")
+		svcNamespace, _, _ := unstructured.NestedString(apiService.Object, "spec", "service", "namespace")
+		if svcName == "" {
+			continue
+		}
+
+		orphaned, err := isOrphanedAPIService(ctx, client, svcNamespace, svcName)
+		if err != nil {
+			log.Error(err, "failed to check APIService backend", "apiService", apiService.GetName())
+			continue
+		}
+		if !orphaned || !policyAllows(ctx, apiServicesGVR, &apiService) {
+			continue
+		}
+
+		log.Info("Deleting orphaned APIService", "apiService", apiService.GetName(), "service", svcName, "namespace", svcNamespace)
+		if err := retryMutation(ctx, func(ctx context.Context) error {
+			return dynamic.Resource(apiServicesGVR).Delete(ctx, apiService.GetName(), metav1.DeleteOptions{})
+		}); err != nil && !apierrors.IsNotFound(err) {
+			log.Error(err, "APIService deletion failed", "apiService", apiService.GetName())
+			errs = append(errs, fmt.Errorf("failed to delete APIService %s: %w", apiService.GetName(), err))
+			continue
+		}
+		log.Info("APIService deletion successful", "apiService", apiService.GetName())
+	}
+
+	if apiServiceMustDel {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// isOrphanedAPIService reports whether an APIService's Service is missing, or selects no workload
+func isOrphanedAPIService(ctx context.Context, client ctrlclient.Client, namespace, name string) (bool, error) {
+	svc := &corev1.Service{}
+	if err := client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, svc); err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	// Services without a selector have manually managed endpoints, which cannot be traced to a workload
+	if len(svc.Spec.Selector) == 0 {
+		return false, nil
+	}
+
+	templates := []map[string]string{}
+	inNs := ctrlclient.InNamespace(namespace)
+	deployments := &appsv1.DeploymentList{}
+	if err := client.List(ctx, deployments, inNs); err != nil {
+		return false, err
+	}
+	for _, d := range deployments.Items {
+		templates = append(templates, d.Spec.Template.Labels)
+	}
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := client.List(ctx, statefulSets, inNs); err != nil {
+		return false, err
+	}
+	for _, s := range statefulSets.Items {
+		templates = append(templates, s.Spec.Template.Labels)
+	}
+	daemonSets := &appsv1.DaemonSetList{}
+	if err := client.List(ctx, daemonSets, inNs); err != nil {
+		return false, err
+	}
+	for _, d := range daemonSets.Items {
+		templates = append(templates, d.Spec.Template.Labels)
+	}
+
+	return !selectsAny(svc.Spec.Selector, templates), nil
+}
+
+// selectsAny reports whether a Service selector matches any of the pod template labels
+func selectsAny(selector map[string]string, templates []map[string]string) bool {
+	s := labels.SelectorFromSet(selector)
+	for _, t := range templates {
+		if s.Matches(labels.Set(t)) {
+			return true
+		}
+	}
+	return false
+}
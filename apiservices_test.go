@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestSelectsAny(t *testing.T) {
+	selector := map[string]string{"app": "metrics-server"}
+
+	tests := []struct {
+		name      string
+		templates []map[string]string
+		expected  bool
+	}{
+		{
+			name:      "matching template",
+			templates: []map[string]string{{"app": "other"}, {"app": "metrics-server", "version": "v1"}},
+			expected:  true,
+		},
+		{
+			name:      "no matching template",
+			templates: []map[string]string{{"app": "other"}, {"name": "metrics-server"}},
+			expected:  false,
+		},
+		{
+			name:      "no templates",
+			templates: nil,
+			expected:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if matches := selectsAny(selector, tt.templates); matches != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, matches)
+			}
+		})
+	}
+}
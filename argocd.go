@@ -0,0 +1,75 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+// maxTerminationMessageLen is the most bytes of a termination message the kubelet reports
+const maxTerminationMessageLen = 4096
+
+// terminationMessagePath is the file whose contents the kubelet reports as the container's
+// termination message, which Argo CD displays as a hook's status message
+var terminationMessagePath = corev1.TerminationMessagePathDefault
+
+// runArgoCDHook deletes every resource in the resource config as an Argo CD PreDelete hook, and
+// reports the result as the container's termination message. Argo CD deletes the hook's own
+// resources per their hook-delete-policy, so spectro-cleanup never self destructs.
+func runArgoCDHook(ctx context.Context, opts cleaner.Options, resourcesToDelete []cleaner.DeleteObj, hooks PhaseHooks) {
+	result, err := cleaner.New(opts).CleanupResources(ctx, resourcesToDelete)
+	logResult("resources", result)
+	reportHookResult(result, err)
+	if err != nil {
+		exitIfStopped(ctx)
+		panic(err)
+	}
+	exitIfStopped(ctx)
+	runPhaseHooks(ctx, "afterResources", hooks.AfterResources)
+	removeImages(ctx)
+}
+
+// reportHookResult writes a summary of a resource cleanup to the termination message file. Failing
+// to do so doesn't fail the cleanup.
+func reportHookResult(result *cleaner.Result, err error) {
+	if err := os.WriteFile(terminationMessagePath, []byte(hookResultMessage(result, err)), 0o600); err != nil {
+		log.Error(err, "failed to write termination message", "path", terminationMessagePath)
+	}
+}
+
+// hookResultMessage summarizes a resource cleanup, truncated to the termination message limit
+func hookResultMessage(result *cleaner.Result, err error) string {
+	msg := "spectro-cleanup succeeded"
+	if err != nil {
+		msg = "spectro-cleanup failed"
+	}
+	if result != nil {
+		msg += fmt.Sprintf(": %d entries succeeded, %d failed, %d skipped", result.Count(cleaner.StatusSucceeded),
+			result.Count(cleaner.StatusFailed), result.Count(cleaner.StatusSkipped))
+	}
+	if err != nil {
+		msg += ": " + err.Error()
+	}
+	if len(msg) > maxTerminationMessageLen {
+		msg = msg[:maxTerminationMessageLen-3] + "..."
+	}
+	return msg
+}
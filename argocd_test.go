@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+func TestHookResultMessage(t *testing.T) {
+	result := &cleaner.Result{Entries: []cleaner.EntryResult{
+		{Status: cleaner.StatusSucceeded},
+		{Status: cleaner.StatusSucceeded},
+		{Status: cleaner.StatusFailed},
+		{Status: cleaner.StatusSkipped},
+	}}
+
+	tests := []struct {
+		name     string
+		result   *cleaner.Result
+		err      error
+		expected string
+	}{
+		{
+			name:     "succeeded",
+			result:   &cleaner.Result{Entries: []cleaner.EntryResult{{Status: cleaner.StatusSucceeded}}},
+			expected: "spectro-cleanup succeeded: 1 entries succeeded, 0 failed, 0 skipped",
+		},
+		{
+			name:     "failed",
+			result:   result,
+			err:      errors.New("configmaps default/settings: forbidden"),
+			expected: "spectro-cleanup failed: 2 entries succeeded, 1 failed, 1 skipped: configmaps default/settings: forbidden",
+		},
+		{
+			name:     "failed without result",
+			err:      cleaner.ErrConfigInvalid,
+			expected: "spectro-cleanup failed: " + cleaner.ErrConfigInvalid.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if msg := hookResultMessage(tt.result, tt.err); msg != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, msg)
+			}
+		})
+	}
+}
+
+func TestHookResultMessageTruncated(t *testing.T) {
+	msg := hookResultMessage(nil, errors.New(strings.Repeat("x", 2*maxTerminationMessageLen)))
+	if len(msg) != maxTerminationMessageLen || !strings.HasSuffix(msg, "...") {
+		t.Errorf("expected %d bytes ending in ..., got %d bytes", maxTerminationMessageLen, len(msg))
+	}
+}
+
+func TestReportHookResult(t *testing.T) {
+	defaultTerminationMessagePath := terminationMessagePath
+	defer func() { terminationMessagePath = defaultTerminationMessagePath }()
+	terminationMessagePath = filepath.Join(t.TempDir(), "termination-log")
+
+	reportHookResult(&cleaner.Result{}, nil)
+	data, err := os.ReadFile(terminationMessagePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "spectro-cleanup succeeded: 0 entries succeeded, 0 failed, 0 skipped"
+	if string(data) != expected {
+		t.Errorf("expected %q, got %q", expected, string(data))
+	}
+}
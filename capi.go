@@ -0,0 +1,92 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// machineAnnotation and machineNamespaceAnnotation are set on the Nodes of CAPI-managed clusters
+	// by the Machine controller, identifying the Machine backing each Node
+	machineAnnotation          = "cluster.x-k8s.io/machine"
+	machineNamespaceAnnotation = "cluster.x-k8s.io/cluster-namespace"
+)
+
+var machinesGVR = schema.GroupVersionResource{Group: "cluster.x-k8s.io", Version: "v1beta1", Resource: "machines"}
+
+// machinePollInterval is how often the Node's Machine is checked for deletion
+var machinePollInterval = 10 * time.Second
+
+var errNoMachine = errors.New("node is not backed by a Cluster API Machine")
+
+// waitForMachineDeletion blocks until the Cluster API Machine backing nodeName has a
+// deletionTimestamp, i.e., until the node is actually being decommissioned, so that host files
+// are not removed from nodes that remain in service. Errors looking up the Machine are retried.
+func waitForMachineDeletion(ctx context.Context, client ctrlclient.Client, dynamic dynamic.Interface) error {
+	log.Info("Waiting for the node's Machine to be deleted", "node", nodeName)
+	for {
+		deleting, err := machineDeleting(ctx, client, dynamic, nodeName)
+		switch {
+		case errors.Is(err, errNoMachine):
+			return err
+		case err != nil:
+			log.Error(err, "failed to get the node's Machine", "node", nodeName)
+		case deleting:
+			log.Info("Machine is being deleted", "node", nodeName)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(machinePollInterval):
+		}
+	}
+}
+
+// machineDeleting returns whether the Machine backing a Node has a deletionTimestamp. A Node or
+// Machine that no longer exists has already been decommissioned.
+func machineDeleting(ctx context.Context, client ctrlclient.Client, dynamic dynamic.Interface, node string) (bool, error) {
+	n := &corev1.Node{}
+	if err := client.Get(ctx, ctrlclient.ObjectKey{Name: node}, n); err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	name, namespace := n.Annotations[machineAnnotation], n.Annotations[machineNamespaceAnnotation]
+	if name == "" || namespace == "" {
+		return false, errNoMachine
+	}
+
+	machine, err := dynamic.Resource(machinesGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	return machine.GetDeletionTimestamp() != nil, nil
+}
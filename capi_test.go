@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMachineDeleting(t *testing.T) {
+	node := func(annotations map[string]string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1", Annotations: annotations}}
+	}
+	machine := func(deleting bool) *unstructured.Unstructured {
+		m := &unstructured.Unstructured{}
+		m.SetAPIVersion("cluster.x-k8s.io/v1beta1")
+		m.SetKind("Machine")
+		m.SetName("worker-1-abcde")
+		m.SetNamespace("capi-cluster")
+		if deleting {
+			now := metav1.Now()
+			m.SetDeletionTimestamp(&now)
+			m.SetFinalizers([]string{"machine.cluster.x-k8s.io"})
+		}
+		return m
+	}
+	capiNode := node(map[string]string{machineAnnotation: "worker-1-abcde", machineNamespaceAnnotation: "capi-cluster"})
+
+	tests := []struct {
+		name        string
+		nodes       []ctrlclient.Object
+		machines    []runtime.Object
+		expected    bool
+		expectedErr error
+	}{
+		{
+			name:     "machine not deleting",
+			nodes:    []ctrlclient.Object{capiNode},
+			machines: []runtime.Object{machine(false)},
+			expected: false,
+		},
+		{
+			name:     "machine deleting",
+			nodes:    []ctrlclient.Object{capiNode},
+			machines: []runtime.Object{machine(true)},
+			expected: true,
+		},
+		{
+			name:     "machine deleted",
+			nodes:    []ctrlclient.Object{capiNode},
+			expected: true,
+		},
+		{
+			name:     "node deleted",
+			expected: true,
+		},
+		{
+			name:        "node not backed by a machine",
+			nodes:       []ctrlclient.Object{node(nil)},
+			expectedErr: errNoMachine,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.nodes...).Build()
+			dynamic := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), tt.machines...)
+			deleting, err := machineDeleting(context.Background(), client, dynamic, "worker-1")
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			if deleting != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, deleting)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// certificatesGVR is the cert-manager Certificate resource. Its CertificateRequests, Orders
+// and Challenges are garbage collected once it is deleted.
+var certificatesGVR = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}
+
+var errNoCertificate = errors.New("no PEM encoded certificate found")
+
+// cleanupExpiredCertificates deletes TLS Secrets whose certificate expired more than tlsExpiredDays
+// ago. If enabled, the cert-manager Certificates issuing them are deleted first, so that the
+// Secrets are not reissued.
+func cleanupExpiredCertificates(ctx context.Context, client ctrlclient.Client, dynamic dynamic.Interface) {
+	if tlsExpiredDays < 0 {
+		return
+	}
+
+	log.Info("Searching for expired TLS Secrets", "expiredDays", tlsExpiredDays)
+	secrets := &corev1.SecretList{}
+	if err := client.List(ctx, secrets, ctrlclient.MatchingFields{"type": string(corev1.SecretTypeTLS)}); err != nil {
+		log.Error(err, "failed to list TLS Secrets")
+		return
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -tlsExpiredDays)
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if secret.DeletionTimestamp != nil {
+			continue
+		}
+		notAfter, err := certificateExpiry(secret.Data[corev1.TLSCertKey])
+		if err != nil {
+			log.Info("WARNING: failed to parse TLS certificate, skipping", "secret", secret.Name, "namespace", secret.Namespace, "error", err.Error())
+			continue
+		}
+		if !notAfter.Before(cutoff) {
+			continue
+		}
+
+		log.Info("Found expired TLS Secret", "secret", secret.Name, "namespace", secret.Namespace, "notAfter", notAfter)
+		if !policyAllowsObject(ctx, client, secret) {
+			continue
+		}
+		if tlsCertManager && !deleteIssuingCertificates(ctx, dynamic, secret) {
+			continue
+		}
+		if err := retryMutation(ctx, func(ctx context.Context) error { return client.Delete(ctx, secret) }); ctrlclient.IgnoreNotFound(err) != nil {
+			log.Error(err, "TLS Secret deletion failed", "secret", secret.Name, "namespace", secret.Namespace)
+			continue
+		}
+		log.Info("TLS Secret deletion successful", "secret", secret.Name, "namespace", secret.Namespace)
+	}
+}
+
+// deleteIssuingCertificates deletes the cert-manager Certificates whose spec.secretName is the given
+// Secret, returning false if they could not be deleted. Clusters without cert-manager are ignored.
+func deleteIssuingCertificates(ctx context.Context, dynamic dynamic.Interface, secret *corev1.Secret) bool {
+	certs, err := dynamic.Resource(certificatesGVR).Namespace(secret.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return true
+		}
+		log.Error(err, "failed to list cert-manager Certificates", "namespace", secret.Namespace)
+		return false
+	}
+
+	for _, cert := range certs.Items {
+		secretName, _, _ := unstructured.NestedString(cert.Object, "spec", "secretName")
+		if secretName != secret.Name {
+			continue
+		}
+		if !policyAllows(ctx, certificatesGVR, &cert) {
+			return false
+		}
+		log.Info("Deleting cert-manager Certificate", "certificate", cert.GetName(), "namespace", cert.GetNamespace())
+		if err := retryMutation(ctx, func(ctx context.Context) error {
+			return dynamic.Resource(certificatesGVR).Namespace(cert.GetNamespace()).Delete(
+				ctx, cert.GetName(), metav1.DeleteOptions{PropagationPolicy: &propagationPolicy},
+			)
+		}); err != nil && !apierrors.IsNotFound(err) {
+			log.Error(err, "cert-manager Certificate deletion failed", "certificate", cert.GetName(), "namespace", cert.GetNamespace())
+			return false
+		}
+		log.Info("cert-manager Certificate deletion successful")
+	}
+	return true
+}
+
+// certificateExpiry returns the expiry of the first certificate in a PEM bundle, i.e., the leaf
+func certificateExpiry(data []byte) (time.Time, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return time.Time{}, errNoCertificate
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return cert.NotAfter, nil
+	}
+}
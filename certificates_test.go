@@ -0,0 +1,86 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestCertificateExpiry(t *testing.T) {
+	leafNotAfter := time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)
+	leaf := selfSignedPEM(t, leafNotAfter)
+	ca := selfSignedPEM(t, leafNotAfter.AddDate(10, 0, 0))
+	key := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte("key")})
+
+	tests := []struct {
+		name          string
+		data          []byte
+		expected      time.Time
+		expectedError bool
+	}{
+		{
+			name:     "single certificate",
+			data:     leaf,
+			expected: leafNotAfter,
+		},
+		{
+			name:     "leaf precedes chain",
+			data:     append(append([]byte{}, leaf...), ca...),
+			expected: leafNotAfter,
+		},
+		{
+			name:     "non-certificate blocks skipped",
+			data:     append(append([]byte{}, key...), leaf...),
+			expected: leafNotAfter,
+		},
+		{
+			name:          "empty",
+			data:          nil,
+			expectedError: true,
+		},
+		{
+			name:          "invalid certificate",
+			data:          pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("invalid")}),
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notAfter, err := certificateExpiry(tt.data)
+			if err != nil && !tt.expectedError {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if err == nil && tt.expectedError {
+				t.Fatalf("expected error, got nil")
+			}
+			if !notAfter.Equal(tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, notAfter)
+			}
+		})
+	}
+}
+
+func selfSignedPEM(t *testing.T, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "spectro-cleanup"},
+		NotBefore:    notAfter.AddDate(-1, 0, 0),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -0,0 +1,182 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+const ChaosToInject = "chaosToInject"
+
+// ChaosConfig configures the faults injected into API requests in chaos mode, a test-only mode
+// verifying, e.g., the MustDelete, retry and reporting behavior of cleanup configs in CI
+type ChaosConfig struct {
+	// Seed seeds the choice of the requests that fail, so that a failing run can be reproduced.
+	// Defaults to a random seed.
+	Seed uint64 `json:"seed,omitempty"`
+
+	// ErrorRate is the fraction of requests failing with an API error, with one of ErrorCodes
+	ErrorRate float64 `json:"errorRate,omitempty"`
+
+	// ErrorCodes are the HTTP status codes of injected API errors. Defaults to 500.
+	ErrorCodes []int32 `json:"errorCodes,omitempty"`
+
+	// TimeoutRate is the fraction of requests timing out, i.e., failing with a 504 once TimeoutSeconds elapse
+	TimeoutRate float64 `json:"timeoutRate,omitempty"`
+
+	// TimeoutSeconds is how long timing out requests hang for. Defaults to 1.
+	TimeoutSeconds int64 `json:"timeoutSeconds,omitempty"`
+
+	// Methods and Resources, if set, restrict faults to requests with one of the HTTP methods, e.g.,
+	// DELETE, or for one of the resources, e.g., configmaps, so that entries fail partially
+	Methods   []string `json:"methods,omitempty"`
+	Resources []string `json:"resources,omitempty"`
+}
+
+// chaosReasons are the reasons of the API errors injected for common status codes
+var chaosReasons = map[int32]metav1.StatusReason{
+	http.StatusConflict:            metav1.StatusReasonConflict,
+	http.StatusForbidden:           metav1.StatusReasonForbidden,
+	http.StatusTooManyRequests:     metav1.StatusReasonTooManyRequests,
+	http.StatusInternalServerError: metav1.StatusReasonInternalError,
+	http.StatusServiceUnavailable:  metav1.StatusReasonServiceUnavailable,
+	http.StatusGatewayTimeout:      metav1.StatusReasonTimeout,
+}
+
+// readChaosConfig loads the chaos config file, returning nil if chaos mode is disabled
+func readChaosConfig() *ChaosConfig {
+	if chaosConfigPath == "" {
+		return nil
+	}
+	bytes := readConfig(chaosConfigPath, ChaosToInject)
+	if bytes == nil {
+		return nil
+	}
+	chaos := &ChaosConfig{}
+	if err := json.Unmarshal(bytes, chaos); err != nil {
+		panic(fmt.Errorf("%w: %w", cleaner.ErrConfigInvalid, err))
+	}
+	if chaos.ErrorRate < 0 || chaos.TimeoutRate < 0 || chaos.ErrorRate+chaos.TimeoutRate > 1 {
+		panic(fmt.Errorf("%w: chaos errorRate and timeoutRate must be non-negative and sum to at most 1", cleaner.ErrConfigInvalid))
+	}
+	if len(chaos.ErrorCodes) == 0 {
+		chaos.ErrorCodes = []int32{http.StatusInternalServerError}
+	}
+	if chaos.TimeoutSeconds <= 0 {
+		chaos.TimeoutSeconds = 1
+	}
+	if chaos.Seed == 0 {
+		chaos.Seed = rand.Uint64()
+	}
+	log.Info("WARNING: chaos mode enabled, API requests fail artificially. Never enable it outside of tests.",
+		"seed", chaos.Seed, "errorRate", chaos.ErrorRate, "timeoutRate", chaos.TimeoutRate)
+	return chaos
+}
+
+// chaosTransport injects the faults of a ChaosConfig into the requests of a client
+type chaosTransport struct {
+	rt    http.RoundTripper
+	chaos *ChaosConfig
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// wrap returns a client transport injecting the configured faults. Each client's faults are drawn
+// from the same seed.
+func (c *ChaosConfig) wrap(rt http.RoundTripper) http.RoundTripper {
+	return &chaosTransport{rt: rt, chaos: c, rand: rand.New(rand.NewPCG(c.Seed, c.Seed))}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *chaosTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.targets(req) {
+		return t.rt.RoundTrip(req)
+	}
+	t.mu.Lock()
+	p, code := t.rand.Float64(), t.chaos.ErrorCodes[t.rand.IntN(len(t.chaos.ErrorCodes))]
+	t.mu.Unlock()
+
+	switch {
+	case p < t.chaos.ErrorRate:
+		log.Info("Chaos: injecting API error", "method", req.Method, "path", req.URL.Path, "code", code)
+		return chaosResponse(req, code), nil
+	case p < t.chaos.ErrorRate+t.chaos.TimeoutRate:
+		log.Info("Chaos: injecting timeout", "method", req.Method, "path", req.URL.Path, "timeoutSeconds", t.chaos.TimeoutSeconds)
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(time.Duration(t.chaos.TimeoutSeconds) * time.Second):
+		}
+		return chaosResponse(req, http.StatusGatewayTimeout), nil
+	}
+	return t.rt.RoundTrip(req)
+}
+
+// targets reports whether faults may be injected into a request, per the configured methods and resources
+func (t *chaosTransport) targets(req *http.Request) bool {
+	if len(t.chaos.Methods) > 0 && !slices.ContainsFunc(t.chaos.Methods, func(m string) bool { return strings.EqualFold(m, req.Method) }) {
+		return false
+	}
+	if len(t.chaos.Resources) == 0 {
+		return true
+	}
+	segments := strings.Split(req.URL.Path, "/")
+	return slices.ContainsFunc(t.chaos.Resources, func(r string) bool { return slices.Contains(segments, r) })
+}
+
+// chaosResponse returns an API error response with a status code, as the API server would
+func chaosResponse(req *http.Request, code int32) *http.Response {
+	reason, ok := chaosReasons[code]
+	if !ok {
+		reason = metav1.StatusReasonUnknown
+	}
+	status := metav1.Status{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Status"},
+		Status:   metav1.StatusFailure,
+		Message:  fmt.Sprintf("injected by chaos mode: %s %s", req.Method, req.URL.Path),
+		Reason:   reason,
+		Code:     code,
+	}
+	body, _ := json.Marshal(status)
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	if code == http.StatusTooManyRequests {
+		header.Set("Retry-After", "1")
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", code, http.StatusText(int(code))),
+		StatusCode:    int(code),
+		Proto:         req.Proto,
+		ProtoMajor:    req.ProtoMajor,
+		ProtoMinor:    req.ProtoMinor,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
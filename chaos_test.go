@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+func TestChaosTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"NotFound","code":404}`))
+	}))
+	defer server.Close()
+	configMaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	secrets := schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+
+	tests := []struct {
+		name     string
+		chaos    ChaosConfig
+		gvr      schema.GroupVersionResource
+		delete   bool
+		expected func(err error) bool
+	}{
+		{
+			name:     "API error",
+			chaos:    ChaosConfig{ErrorRate: 1, ErrorCodes: []int32{http.StatusServiceUnavailable}},
+			gvr:      configMaps,
+			expected: apierrors.IsServiceUnavailable,
+		},
+		{
+			name:     "timeout",
+			chaos:    ChaosConfig{TimeoutRate: 1, ErrorCodes: []int32{http.StatusInternalServerError}},
+			gvr:      configMaps,
+			expected: apierrors.IsTimeout,
+		},
+		{
+			name:     "method not targeted",
+			chaos:    ChaosConfig{ErrorRate: 1, ErrorCodes: []int32{http.StatusInternalServerError}, Methods: []string{"DELETE"}},
+			gvr:      configMaps,
+			expected: apierrors.IsNotFound,
+		},
+		{
+			name:     "method targeted",
+			chaos:    ChaosConfig{ErrorRate: 1, ErrorCodes: []int32{http.StatusInternalServerError}, Methods: []string{"delete"}},
+			gvr:      configMaps,
+			delete:   true,
+			expected: apierrors.IsInternalError,
+		},
+		{
+			name:     "resource not targeted",
+			chaos:    ChaosConfig{ErrorRate: 1, ErrorCodes: []int32{http.StatusInternalServerError}, Resources: []string{"configmaps"}},
+			gvr:      secrets,
+			expected: apierrors.IsNotFound,
+		},
+		{
+			name:     "no faults",
+			chaos:    ChaosConfig{ErrorCodes: []int32{http.StatusInternalServerError}},
+			gvr:      configMaps,
+			expected: apierrors.IsNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.chaos.Seed = 1
+			client, err := dynamic.NewForConfig(&rest.Config{Host: server.URL, WrapTransport: tt.chaos.wrap})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ri := client.Resource(tt.gvr).Namespace("default")
+			if tt.delete {
+				err = ri.Delete(context.Background(), "a", metav1.DeleteOptions{})
+			} else {
+				_, err = ri.Get(context.Background(), "a", metav1.GetOptions{})
+			}
+			if !tt.expected(err) {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+// checkpoint records which resource config entries have been processed, so that a restarted
+// one-shot cleanup resumes where it left off rather than processing every entry from scratch.
+// A checkpoint only applies to the resource config it was recorded for. A checkpoint without a path
+// is only kept in memory, e.g., so that retries within the same run skip the completed entries.
+type checkpoint struct {
+	path string
+	mu   sync.Mutex
+
+	ConfigHash string `json:"configHash"`
+	Entries    []int  `json:"completed"`
+}
+
+// loadCheckpoint reads the checkpoint at path for the given entries, starting afresh if there is
+// none, or if it was recorded for a different resource config
+func loadCheckpoint(path string, objs []cleaner.DeleteObj) (*checkpoint, error) {
+	hash, err := configHash(objs)
+	if err != nil {
+		return nil, err
+	}
+	cp := &checkpoint{path: path}
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, cp); err != nil {
+			log.Info("WARNING: invalid checkpoint, starting afresh", "path", path, "error", err.Error())
+		}
+	}
+	if cp.ConfigHash != hash {
+		cp.ConfigHash, cp.Entries = hash, nil
+	} else if len(cp.Entries) > 0 {
+		log.Info("Resuming from checkpoint", "path", path, "completedEntries", len(cp.Entries))
+	}
+	return cp, nil
+}
+
+// Completed reports whether the i'th entry was processed by a prior run
+func (c *checkpoint) Completed(i int) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Contains(c.Entries, i)
+}
+
+// Complete records that the i'th entry has been processed
+func (c *checkpoint) Complete(i int) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Entries = append(c.Entries, i)
+	if c.path == "" {
+		return
+	}
+	if err := c.save(); err != nil {
+		log.Error(err, "failed to save checkpoint", "path", c.path)
+	}
+}
+
+// save atomically replaces the checkpoint file
+func (c *checkpoint) save() error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// remove deletes the checkpoint file once every entry has been processed
+func (c *checkpoint) remove() {
+	if c == nil || c.path == "" {
+		return
+	}
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Error(err, "failed to remove checkpoint", "path", c.path)
+	}
+}
+
+// configHash returns the hex-encoded sha256 digest of a resource config
+func configHash(objs []cleaner.DeleteObj) (string, error) {
+	data, err := json.Marshal(objs)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+func TestCheckpoint(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	objs := []cleaner.DeleteObj{
+		{GroupVersionResource: gvr, Name: "a", Namespace: "default"},
+		{GroupVersionResource: gvr, Name: "b", Namespace: "default"},
+	}
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	cp, err := loadCheckpoint(path, objs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.Completed(0) {
+		t.Error("expected no completed entries")
+	}
+	cp.Complete(0)
+
+	resumed, err := loadCheckpoint(path, objs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resumed.Completed(0) || resumed.Completed(1) {
+		t.Errorf("expected only entry 0 completed, got %v", resumed.Entries)
+	}
+
+	changed, err := loadCheckpoint(path, objs[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed.Completed(0) {
+		t.Errorf("expected checkpoint to be discarded after a config change, got %v", changed.Entries)
+	}
+
+	resumed.remove()
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected checkpoint to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+)
+
+// checksumSuffix is appended to a config file's path to name its optional sha256 sidecar file, e.g.,
+// resource-config.json.sha256, which may hold either the bare hex digest or sha256sum's output
+const checksumSuffix = ".sha256"
+
+// How many times, and how often, a config file is reread while its checksum mismatches. The kubelet
+// may not have propagated every key of an updated ConfigMap yet.
+var (
+	checksumAttempts      = 5
+	checksumRetryInterval = 2 * time.Second
+)
+
+// verifyChecksum compares a config file's contents to the digest in its sidecar file, if there is one
+func verifyChecksum(path string, bytes []byte) error {
+	sidecar, err := os.ReadFile(path + checksumSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	fields := strings.Fields(string(sidecar))
+	if len(fields) == 0 {
+		return fmt.Errorf("checksum file %s is empty", path+checksumSuffix)
+	}
+	sum := sha256.Sum256(bytes)
+	if actual := hex.EncodeToString(sum[:]); !strings.EqualFold(fields[0], actual) {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", path, fields[0], actual)
+	}
+	return nil
+}
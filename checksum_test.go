@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyChecksum(t *testing.T) {
+	content := []byte(`["/host/opt/cni/bin/multus"]`)
+	digest := "3f5c20595a4c714cd5f12ef51d004a58afff71d76d9db75234839445911a4259"
+
+	tests := []struct {
+		name          string
+		sidecar       string
+		expectedError bool
+	}{
+		{
+			name: "no sidecar",
+		},
+		{
+			name:    "bare digest",
+			sidecar: digest + "\n",
+		},
+		{
+			name:    "sha256sum output",
+			sidecar: digest + "  file-config.json\n",
+		},
+		{
+			name:          "mismatch",
+			sidecar:       "0000000000000000000000000000000000000000000000000000000000000000",
+			expectedError: true,
+		},
+		{
+			name:          "blank sidecar",
+			sidecar:       "\n",
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "file-config.json")
+			if tt.sidecar != "" {
+				if err := os.WriteFile(path+checksumSuffix, []byte(tt.sidecar), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := verifyChecksum(path, content)
+			if err != nil && !tt.expectedError {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if err == nil && tt.expectedError {
+				t.Fatalf("expected error, got nil")
+			}
+		})
+	}
+}
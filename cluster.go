@@ -0,0 +1,63 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// errClusterMismatch is returned if the target cluster isn't the one the cleanup config is intended for
+var errClusterMismatch = errors.New("unexpected target cluster")
+
+// verifyCluster returns an error unless the target cluster is the expected one, i.e., the UID of its
+// kube-system namespace, which identifies a cluster for its lifetime, and the URL of its API server
+// match those configured, so that a cleanup config intended for one cluster never runs against
+// another due to a wrong kubeconfig or Secret mount
+func verifyCluster(ctx context.Context, client ctrlclient.Client, host string) error {
+	if expectedServer != "" && normalizeServer(host) != normalizeServer(expectedServer) {
+		return fmt.Errorf("%w: API server is %s, expected %s", errClusterMismatch, host, expectedServer)
+	}
+	if expectedClusterID != "" {
+		ns := &corev1.Namespace{}
+		if err := client.Get(ctx, ctrlclient.ObjectKey{Name: metav1.NamespaceSystem}, ns); err != nil {
+			return fmt.Errorf("failed to get the cluster ID: %w", err)
+		}
+		if string(ns.UID) != expectedClusterID {
+			return fmt.Errorf("%w: cluster ID is %s, expected %s", errClusterMismatch, ns.UID, expectedClusterID)
+		}
+	}
+	log.Info("Target cluster verified", "server", host, "clusterID", expectedClusterID)
+	return nil
+}
+
+// normalizeServer returns an API server URL without a trailing slash or the default HTTPS port
+func normalizeServer(server string) string {
+	u, err := url.Parse(strings.TrimSuffix(server, "/"))
+	if err != nil {
+		return server
+	}
+	if u.Scheme == "https" && u.Port() == "443" {
+		u.Host = u.Hostname()
+	}
+	return u.String()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestVerifyCluster(t *testing.T) {
+	defaultExpectedClusterID, defaultExpectedServer := expectedClusterID, expectedServer
+	defer func() {
+		expectedClusterID, expectedServer = defaultExpectedClusterID, defaultExpectedServer
+	}()
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: metav1.NamespaceSystem, UID: "4c0e4f5e-cluster-a"},
+	}).Build()
+
+	tests := []struct {
+		name          string
+		clusterID     string
+		server        string
+		expectedError error
+	}{
+		{name: "matching cluster ID", clusterID: "4c0e4f5e-cluster-a"},
+		{name: "other cluster ID", clusterID: "9d1f2a3b-cluster-b", expectedError: errClusterMismatch},
+		{name: "matching server", server: "https://10.0.0.1:6443"},
+		{name: "matching server with trailing slash", server: "https://10.0.0.1:6443/"},
+		{name: "other server", server: "https://10.0.0.2:6443", expectedError: errClusterMismatch},
+		{name: "matching server, other cluster ID", clusterID: "9d1f2a3b-cluster-b", server: "https://10.0.0.1:6443", expectedError: errClusterMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectedClusterID, expectedServer = tt.clusterID, tt.server
+			if err := verifyCluster(context.Background(), client, "https://10.0.0.1:6443"); !errors.Is(err, tt.expectedError) {
+				t.Errorf("expected %v, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestNormalizeServer(t *testing.T) {
+	tests := []struct {
+		server   string
+		expected string
+	}{
+		{server: "https://api.example.com:443/", expected: "https://api.example.com"},
+		{server: "https://api.example.com", expected: "https://api.example.com"},
+		{server: "https://10.0.0.1:6443", expected: "https://10.0.0.1:6443"},
+		{server: "http://localhost:443", expected: "http://localhost:443"},
+	}
+
+	for _, tt := range tests {
+		if normalized := normalizeServer(tt.server); normalized != tt.expected {
+			t.Errorf("expected %s, got %s", tt.expected, normalized)
+		}
+	}
+}
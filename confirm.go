@@ -0,0 +1,100 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+// interactive reports whether spectro-cleanup runs out of cluster, e.g., from a laptop, with a
+// terminal on stdin
+func interactive() bool {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return false
+	}
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// confirmRun shows the resolved plan of a one-shot cleanup, kubectl-style, and exits unless "yes" is typed,
+// so that a destructive run against the wrong context is caught before anything is deleted
+func confirmRun(ctx context.Context, apiClients *clients, in io.Reader, out io.Writer) {
+	resources := readResourceConfig()
+	resources = discoverScopes(apiClients.discovery).resolve(resources)
+	plan, err := cleaner.New(cleanerOptions(apiClients.client, apiClients.metadata)).Plan(ctx, cleaner.Config{Files: readFileConfig(), Resources: resources})
+	if err != nil {
+		panic(err)
+	}
+	if !confirmPlan(plan, currentContext(), apiClients.host, in, out) {
+		log.Info("Cleanup not confirmed, exiting")
+		os.Exit(ExitCodeDeclined)
+	}
+}
+
+// confirmPlan writes a plan and the cluster it targets to out, and reports whether "yes" is read from in
+func confirmPlan(plan *cleaner.Plan, kubeContext, host string, in io.Reader, out io.Writer) bool {
+	fmt.Fprintf(out, "spectro-cleanup will clean up context %q (%s):\n", kubeContext, host)
+	if len(plan.Files) > 0 {
+		fmt.Fprintln(out, "Files:")
+		for _, file := range plan.Files {
+			fmt.Fprintf(out, "  delete %s\n", file.Path)
+		}
+	}
+	if len(plan.Resources) > 0 {
+		fmt.Fprintln(out, "Resources:")
+		for _, entry := range plan.Resources {
+			action := entry.Entry.Action
+			if action == "" {
+				action = cleaner.ActionDelete
+			}
+			gr := entry.Entry.GroupVersionResource.GroupResource().String()
+			switch {
+			case entry.Err != nil:
+				fmt.Fprintf(out, "  %s %s: %v\n", action, gr, entry.Err)
+			case len(entry.Resources) == 0:
+				fmt.Fprintf(out, "  %s %s: no matching resources\n", action, gr)
+			}
+			for _, r := range entry.Resources {
+				fmt.Fprintf(out, "  %s %s %s\n", action, gr, r)
+			}
+		}
+	}
+	fmt.Fprint(out, "Type \"yes\" to proceed, or set CLEANUP_ASSUME_YES=true to skip this confirmation: ")
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	return strings.TrimSpace(answer) == "yes"
+}
+
+// currentContext returns the kubeconfig context spectro-cleanup targets, if any
+func currentContext() string {
+	if kubeContext != "" {
+		return kubeContext
+	}
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfigPath
+	config, err := rules.Load()
+	if err != nil {
+		return ""
+	}
+	return config.CurrentContext
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+func TestConfirmPlan(t *testing.T) {
+	plan := &cleaner.Plan{
+		Files: []cleaner.FileEntry{{Path: "/etc/cni/net.d/00-multus.conf"}},
+		Resources: []cleaner.PlannedEntry{
+			{
+				Entry:     cleaner.DeleteObj{GroupVersionResource: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "daemonsets"}},
+				Resources: []types.NamespacedName{{Namespace: "kube-system", Name: "kube-multus-ds"}},
+			},
+			{
+				Entry: cleaner.DeleteObj{GroupVersionResource: schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, Action: cleaner.ActionRemoveFinalizers},
+			},
+			{
+				Entry: cleaner.DeleteObj{GroupVersionResource: schema.GroupVersionResource{Version: "v1", Resource: "secrets"}},
+				Err:   errors.New("forbidden"),
+			},
+		},
+	}
+	expectedLines := []string{
+		`spectro-cleanup will clean up context "kind-dev" (https://127.0.0.1:6443):`,
+		"  delete /etc/cni/net.d/00-multus.conf",
+		"  delete daemonsets.apps kube-system/kube-multus-ds",
+		"  removeFinalizers configmaps: no matching resources",
+		"  delete secrets: forbidden",
+	}
+
+	tests := []struct {
+		name      string
+		input     string
+		confirmed bool
+	}{
+		{name: "yes", input: "yes\n", confirmed: true},
+		{name: "yes without newline", input: "yes", confirmed: true},
+		{name: "y", input: "y\n", confirmed: false},
+		{name: "no input", input: "", confirmed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if confirmed := confirmPlan(plan, "kind-dev", "https://127.0.0.1:6443", strings.NewReader(tt.input), &out); confirmed != tt.confirmed {
+				t.Errorf("expected confirmed %v, got %v", tt.confirmed, confirmed)
+			}
+			for _, line := range expectedLines {
+				if !strings.Contains(out.String(), line+"\n") {
+					t.Errorf("expected output to contain %q, got %q", line, out.String())
+				}
+			}
+		})
+	}
+}
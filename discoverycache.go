@@ -0,0 +1,95 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/restmapper"
+)
+
+// discoveryCache returns the API group resources cached at discoveryCachePath. If the cache doesn't
+// exist yet, it is seeded by discovering the cluster's resources, e.g., in a connected staging
+// environment, and written to discoveryCachePath for later runs.
+func discoveryCache(dc discovery.DiscoveryInterface) ([]*restmapper.APIGroupResources, error) {
+	bytes, err := os.ReadFile(discoveryCachePath)
+	if err == nil {
+		groups := []*restmapper.APIGroupResources{}
+		if err := json.Unmarshal(bytes, &groups); err != nil {
+			return nil, fmt.Errorf("invalid discovery cache %s: %w", discoveryCachePath, err)
+		}
+		log.Info("Loaded discovery cache", "path", discoveryCachePath, "groups", len(groups))
+		return groups, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	log.Info("Discovery cache not found, seeding it", "path", discoveryCachePath)
+	groups, err := restmapper.GetAPIGroupResources(dc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to seed discovery cache: %w", err)
+	}
+	bytes, err = json.Marshal(groups)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(discoveryCachePath, bytes, 0o644); err != nil {
+		log.Info("WARNING: failed to write discovery cache", "path", discoveryCachePath, "error", err.Error())
+	}
+	return groups, nil
+}
+
+// cachedDiscovery serves the discovery of resource groups from a discovery cache, rather than from
+// the API server, and delegates all other requests, e.g., for the server version
+type cachedDiscovery struct {
+	discovery.DiscoveryInterface
+	groups []*restmapper.APIGroupResources
+}
+
+// ServerGroups implements discovery.DiscoveryInterface
+func (d *cachedDiscovery) ServerGroups() (*metav1.APIGroupList, error) {
+	list := &metav1.APIGroupList{}
+	for _, g := range d.groups {
+		list.Groups = append(list.Groups, g.Group)
+	}
+	return list, nil
+}
+
+// ServerResourcesForGroupVersion implements discovery.DiscoveryInterface
+func (d *cachedDiscovery) ServerResourcesForGroupVersion(groupVersion string) (*metav1.APIResourceList, error) {
+	gv, err := schema.ParseGroupVersion(groupVersion)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range d.groups {
+		if resources, ok := g.VersionedResources[gv.Version]; ok && g.Group.Name == gv.Group {
+			return &metav1.APIResourceList{GroupVersion: groupVersion, APIResources: resources}, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{Group: gv.Group, Resource: "discovery"}, groupVersion)
+}
+
+// ServerGroupsAndResources implements discovery.DiscoveryInterface
+func (d *cachedDiscovery) ServerGroupsAndResources() ([]*metav1.APIGroup, []*metav1.APIResourceList, error) {
+	return discovery.ServerGroupsAndResources(d)
+}
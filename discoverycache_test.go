@@ -0,0 +1,46 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/restmapper"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestDiscoveryCache(t *testing.T) {
+	defaultDiscoveryCachePath := discoveryCachePath
+	defer func() { discoveryCachePath = defaultDiscoveryCachePath }()
+	discoveryCachePath = filepath.Join(t.TempDir(), "discovery.json")
+
+	live := &discoveryfake.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "configmaps", Namespaced: true, Kind: "ConfigMap"}}},
+		{GroupVersion: "example.com/v1", APIResources: []metav1.APIResource{{Name: "widgets", Namespaced: false, Kind: "Widget"}}},
+	}}}
+	if _, err := discoveryCache(live); err != nil {
+		t.Fatalf("expected the cache to be seeded, got %v", err)
+	}
+
+	// once seeded, the cache is used rather than discovery
+	offline := &discoveryfake.FakeDiscovery{Fake: &clienttesting.Fake{}}
+	groups, err := discoveryCache(offline)
+	if err != nil {
+		t.Fatalf("expected the cache to be loaded, got %v", err)
+	}
+	if len(offline.Actions()) != 0 {
+		t.Errorf("expected no discovery requests, got %v", offline.Actions())
+	}
+
+	widgets := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	scopes := discoverScopes(&cachedDiscovery{DiscoveryInterface: offline, groups: groups})
+	if namespaced, ok := scopes.namespaced[widgets]; !ok || namespaced {
+		t.Errorf("expected %s to be cluster-scoped, got %v %v", widgets, namespaced, ok)
+	}
+	gvk, err := restmapper.NewDiscoveryRESTMapper(groups).KindFor(widgets)
+	if err != nil || gvk.Kind != "Widget" {
+		t.Errorf("expected kind Widget, got %v %v", gvk, err)
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+const GuardsToCheck = "guardsToCheck"
+
+// errGuardUnmet is returned when a guard's condition doesn't hold, i.e., the uninstall hasn't reached
+// the point at which the cleanup may run
+var errGuardUnmet = errors.New("guard condition not met")
+
+// GuardEntry is a condition on a resource that must hold for the cleanup to run, e.g., that an
+// uninstall lock ConfigMap is absent, or that a custom resource's status.phase is Deleting
+type GuardEntry struct {
+	schema.GroupVersionResource
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+
+	// Absent requires the resource not to exist. Otherwise, the resource must exist.
+	Absent bool `json:"absent,omitempty"`
+
+	// FieldPath, if set, is the dot-separated path of a field of the resource, e.g., status.phase,
+	// whose value must equal Value
+	FieldPath string `json:"fieldPath,omitempty"`
+	Value     string `json:"value,omitempty"`
+}
+
+// readGuards loads the guard entries specified in the guard config file
+func readGuards() []GuardEntry {
+	guards := []GuardEntry{}
+	bytes := readConfig(guardConfigPath, GuardsToCheck)
+	if bytes == nil {
+		return guards
+	}
+	if err := json.Unmarshal(bytes, &guards); err != nil {
+		panic(fmt.Errorf("%w: %w", cleaner.ErrConfigInvalid, err))
+	}
+	for _, g := range guards {
+		if g.Version == "" || g.Resource == "" || g.Name == "" {
+			panic(fmt.Errorf("%w: guard %s %s/%s requires a version, resource and name", cleaner.ErrConfigInvalid, g.GroupVersionResource, g.Namespace, g.Name))
+		}
+		if g.Absent && g.FieldPath != "" {
+			panic(fmt.Errorf("%w: guard %s %s/%s: absent and fieldPath are mutually exclusive", cleaner.ErrConfigInvalid, g.GroupVersionResource, g.Namespace, g.Name))
+		}
+	}
+	return guards
+}
+
+// checkGuards returns an error matching errGuardUnmet unless the condition of every guard holds.
+// Guards are checked in order, up to the first that isn't met.
+func checkGuards(ctx context.Context, dynamic dynamic.Interface, guards []GuardEntry) error {
+	for _, g := range guards {
+		if err := g.check(ctx, dynamic); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check returns an error matching errGuardUnmet unless the guard's condition holds
+func (g GuardEntry) check(ctx context.Context, dynamic dynamic.Interface) error {
+	u, err := dynamic.Resource(g.GroupVersionResource).Namespace(g.Namespace).Get(ctx, g.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		if g.Absent {
+			return nil
+		}
+		return fmt.Errorf("%w: %s %s/%s not found", errGuardUnmet, g.GroupVersionResource.Resource, g.Namespace, g.Name)
+	} else if err != nil {
+		return err
+	}
+	if g.Absent {
+		return fmt.Errorf("%w: %s %s/%s exists", errGuardUnmet, g.GroupVersionResource.Resource, g.Namespace, g.Name)
+	}
+	if g.FieldPath == "" {
+		return nil
+	}
+	value, found, err := unstructured.NestedFieldNoCopy(u.Object, strings.Split(g.FieldPath, ".")...)
+	if err != nil {
+		return err
+	}
+	if !found || fmt.Sprint(value) != g.Value {
+		return fmt.Errorf("%w: %s %s/%s %s is %v, expected %s", errGuardUnmet, g.GroupVersionResource.Resource, g.Namespace, g.Name, g.FieldPath, value, g.Value)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestCheckGuards(t *testing.T) {
+	configMapsGVR := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	widgetsGVR := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgetclusters"}
+	widget := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "WidgetCluster",
+		"metadata":   map[string]interface{}{"name": "prod"},
+		"status":     map[string]interface{}{"phase": "Deleting"},
+	}}
+	lock := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "uninstall-lock", "namespace": "widgets"},
+	}}
+
+	tests := []struct {
+		name          string
+		guards        []GuardEntry
+		expectedError error
+	}{
+		{
+			name:   "absent",
+			guards: []GuardEntry{{GroupVersionResource: configMapsGVR, Namespace: "widgets", Name: "other-lock", Absent: true}},
+		},
+		{
+			name:          "not absent",
+			guards:        []GuardEntry{{GroupVersionResource: configMapsGVR, Namespace: "widgets", Name: "uninstall-lock", Absent: true}},
+			expectedError: errGuardUnmet,
+		},
+		{
+			name:   "present",
+			guards: []GuardEntry{{GroupVersionResource: configMapsGVR, Namespace: "widgets", Name: "uninstall-lock"}},
+		},
+		{
+			name:          "not present",
+			guards:        []GuardEntry{{GroupVersionResource: widgetsGVR, Name: "staging"}},
+			expectedError: errGuardUnmet,
+		},
+		{
+			name:   "field matches",
+			guards: []GuardEntry{{GroupVersionResource: widgetsGVR, Name: "prod", FieldPath: "status.phase", Value: "Deleting"}},
+		},
+		{
+			name:          "field differs",
+			guards:        []GuardEntry{{GroupVersionResource: widgetsGVR, Name: "prod", FieldPath: "status.phase", Value: "Ready"}},
+			expectedError: errGuardUnmet,
+		},
+		{
+			name:          "field missing",
+			guards:        []GuardEntry{{GroupVersionResource: widgetsGVR, Name: "prod", FieldPath: "status.conditions", Value: "Deleting"}},
+			expectedError: errGuardUnmet,
+		},
+		{
+			name: "every guard must hold",
+			guards: []GuardEntry{
+				{GroupVersionResource: widgetsGVR, Name: "prod", FieldPath: "status.phase", Value: "Deleting"},
+				{GroupVersionResource: configMapsGVR, Namespace: "widgets", Name: "uninstall-lock", Absent: true},
+			},
+			expectedError: errGuardUnmet,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dynamic := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+				configMapsGVR: "ConfigMapList",
+				widgetsGVR:    "WidgetClusterList",
+			}, widget, lock)
+			if err := checkGuards(context.Background(), dynamic, tt.guards); !errors.Is(err, tt.expectedError) {
+				t.Errorf("expected %v, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
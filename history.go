@@ -0,0 +1,166 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// revisionAnnotation is set on each ReplicaSet by the Deployment controller
+	revisionAnnotation = "deployment.kubernetes.io/revision"
+
+	// Helm v3 stores each release revision in a Secret of this type, labelled with the release
+	// name, revision number and status
+	helmReleaseSecretType = "helm.sh/release.v1"
+	helmDeployedStatus    = "deployed"
+)
+
+// pruneReplicaSets deletes scaled down ReplicaSets beyond the newest rsHistoryLimit
+// revisions of each Deployment, e.g., when revisionHistoryLimit was historically set too high
+func pruneReplicaSets(ctx context.Context, client ctrlclient.Client) {
+	if rsHistoryLimit < 0 {
+		return
+	}
+
+	log.Info("Pruning old ReplicaSet revisions", "historyLimit", rsHistoryLimit)
+	rsList := &appsv1.ReplicaSetList{}
+	if err := client.List(ctx, rsList); err != nil {
+		log.Error(err, "failed to list ReplicaSets")
+		return
+	}
+
+	for _, history := range replicaSetHistories(rsList.Items) {
+		if len(history) <= rsHistoryLimit {
+			continue
+		}
+		for _, rs := range history[rsHistoryLimit:] {
+			if !policyAllowsObject(ctx, client, rs) {
+				continue
+			}
+			log.Info("Deleting old ReplicaSet revision", "name", rs.Name, "namespace", rs.Namespace,
+				"revision", rs.Annotations[revisionAnnotation])
+			if err := retryMutation(ctx, func(ctx context.Context) error {
+				return client.Delete(ctx, rs, ctrlclient.PropagationPolicy(propagationPolicy))
+			}); ctrlclient.IgnoreNotFound(err) != nil {
+				log.Error(err, "ReplicaSet deletion failed", "name", rs.Name, "namespace", rs.Namespace)
+				continue
+			}
+			log.Info("ReplicaSet deletion successful")
+		}
+	}
+}
+
+// replicaSetHistories groups the fully scaled down ReplicaSets by owning Deployment,
+// each group being sorted from newest to oldest revision
+func replicaSetHistories(replicaSets []appsv1.ReplicaSet) map[types.UID][]*appsv1.ReplicaSet {
+	histories := map[types.UID][]*appsv1.ReplicaSet{}
+	for i := range replicaSets {
+		rs := &replicaSets[i]
+		owner := metav1.GetControllerOfNoCopy(rs)
+		if owner == nil || owner.Kind != "Deployment" || rs.DeletionTimestamp != nil {
+			continue
+		}
+		if (rs.Spec.Replicas != nil && *rs.Spec.Replicas != 0) || rs.Status.Replicas != 0 {
+			continue
+		}
+		histories[owner.UID] = append(histories[owner.UID], rs)
+	}
+	for _, history := range histories {
+		sort.SliceStable(history, func(i, j int) bool {
+			return revision(history[i]) > revision(history[j])
+		})
+	}
+	return histories
+}
+
+// revision returns a ReplicaSet's Deployment revision, or 0 if it is missing or invalid
+func revision(rs *appsv1.ReplicaSet) int64 {
+	r, err := strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return r
+}
+
+// pruneHelmReleases deletes superseded Helm release Secrets beyond the newest helmHistoryLimit
+// revisions of each release. The deployed revision of a release is never deleted.
+func pruneHelmReleases(ctx context.Context, client ctrlclient.Client) {
+	if helmHistoryLimit < 0 {
+		return
+	}
+
+	log.Info("Pruning superseded Helm release revisions", "historyLimit", helmHistoryLimit)
+	secrets := &corev1.SecretList{}
+	if err := client.List(ctx, secrets, ctrlclient.MatchingLabels{"owner": "helm"}); err != nil {
+		log.Error(err, "failed to list Helm release Secrets")
+		return
+	}
+
+	for _, history := range helmReleaseHistories(secrets.Items) {
+		if len(history) <= helmHistoryLimit {
+			continue
+		}
+		for _, secret := range history[helmHistoryLimit:] {
+			if secret.Labels["status"] == helmDeployedStatus || !policyAllowsObject(ctx, client, secret) {
+				continue
+			}
+			log.Info("Deleting superseded Helm release revision", "name", secret.Name, "namespace", secret.Namespace,
+				"release", secret.Labels["name"], "revision", secret.Labels["version"])
+			if err := retryMutation(ctx, func(ctx context.Context) error { return client.Delete(ctx, secret) }); ctrlclient.IgnoreNotFound(err) != nil {
+				log.Error(err, "Helm release Secret deletion failed", "name", secret.Name, "namespace", secret.Namespace)
+				continue
+			}
+			log.Info("Helm release Secret deletion successful")
+		}
+	}
+}
+
+// helmReleaseHistories groups Helm release Secrets by namespace and release name,
+// each group being sorted from newest to oldest revision
+func helmReleaseHistories(secrets []corev1.Secret) map[types.NamespacedName][]*corev1.Secret {
+	histories := map[types.NamespacedName][]*corev1.Secret{}
+	for i := range secrets {
+		secret := &secrets[i]
+		if secret.Type != helmReleaseSecretType || secret.Labels["name"] == "" || secret.DeletionTimestamp != nil {
+			continue
+		}
+		key := types.NamespacedName{Namespace: secret.Namespace, Name: secret.Labels["name"]}
+		histories[key] = append(histories[key], secret)
+	}
+	for _, history := range histories {
+		sort.SliceStable(history, func(i, j int) bool {
+			return helmRevision(history[i]) > helmRevision(history[j])
+		})
+	}
+	return histories
+}
+
+// helmRevision returns a Helm release Secret's revision, or 0 if it is missing or invalid
+func helmRevision(secret *corev1.Secret) int64 {
+	r, err := strconv.ParseInt(secret.Labels["version"], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return r
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestReplicaSetHistories(t *testing.T) {
+	rs := func(name string, owner types.UID, revision string, replicas int32) appsv1.ReplicaSet {
+		r := appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Annotations: map[string]string{revisionAnnotation: revision},
+			},
+			Spec:   appsv1.ReplicaSetSpec{Replicas: &replicas},
+			Status: appsv1.ReplicaSetStatus{Replicas: replicas},
+		}
+		if owner != "" {
+			controller := true
+			r.OwnerReferences = []metav1.OwnerReference{{Kind: "Deployment", Name: string(owner), UID: owner, Controller: &controller}}
+		}
+		return r
+	}
+
+	histories := replicaSetHistories([]appsv1.ReplicaSet{
+		rs("app-1", "app", "1", 0),
+		rs("app-10", "app", "10", 0),
+		rs("app-2", "app", "2", 0),
+		rs("app-11", "app", "11", 3),
+		rs("other-4", "other", "4", 0),
+		rs("unowned", "", "1", 0),
+	})
+
+	expected := map[types.UID][]string{
+		"app":   {"app-10", "app-2", "app-1"},
+		"other": {"other-4"},
+	}
+	if len(histories) != len(expected) {
+		t.Fatalf("expected %d histories, got %d", len(expected), len(histories))
+	}
+	for owner, names := range expected {
+		history := histories[owner]
+		if len(history) != len(names) {
+			t.Fatalf("expected %d ReplicaSets for %s, got %d", len(names), owner, len(history))
+		}
+		for i, name := range names {
+			if history[i].Name != name {
+				t.Errorf("expected %s at index %d of %s history, got %s", name, i, owner, history[i].Name)
+			}
+		}
+	}
+}
+
+func TestHelmReleaseHistories(t *testing.T) {
+	secret := func(namespace, release, version string) corev1.Secret {
+		return corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "sh.helm.release.v1." + release + ".v" + version,
+				Namespace: namespace,
+				Labels:    map[string]string{"owner": "helm", "name": release, "version": version},
+			},
+			Type: helmReleaseSecretType,
+		}
+	}
+	opaque := secret("default", "app", "99")
+	opaque.Type = corev1.SecretTypeOpaque
+
+	histories := helmReleaseHistories([]corev1.Secret{
+		secret("default", "app", "9"),
+		secret("default", "app", "10"),
+		secret("default", "app", "1"),
+		secret("other", "app", "3"),
+		opaque,
+	})
+
+	expected := map[types.NamespacedName][]string{
+		{Namespace: "default", Name: "app"}: {"10", "9", "1"},
+		{Namespace: "other", Name: "app"}:   {"3"},
+	}
+	if len(histories) != len(expected) {
+		t.Fatalf("expected %d histories, got %d", len(expected), len(histories))
+	}
+	for key, versions := range expected {
+		history := histories[key]
+		if len(history) != len(versions) {
+			t.Fatalf("expected %d revisions for %s, got %d", len(versions), key, len(history))
+		}
+		for i, version := range versions {
+			if history[i].Labels["version"] != version {
+				t.Errorf("expected revision %s at index %d of %s history, got %s", version, i, key, history[i].Labels["version"])
+			}
+		}
+	}
+}
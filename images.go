@@ -0,0 +1,174 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/internal/command"
+)
+
+// criImage is an image as reported by `crictl images -o json`
+type criImage struct {
+	ID          string   `json:"id"`
+	RepoTags    []string `json:"repoTags"`
+	RepoDigests []string `json:"repoDigests"`
+}
+
+// criContainer is a container as reported by `crictl ps -a -o json`
+type criContainer struct {
+	ID       string            `json:"id"`
+	ImageRef string            `json:"imageRef"`
+	Labels   map[string]string `json:"labels"`
+	Image    struct {
+		Image string `json:"image"`
+	} `json:"image"`
+}
+
+// imagePollInterval is how often the node's containers are checked while waiting for images to be unused
+var imagePollInterval = 5 * time.Second
+
+// removeImages removes the node's container images matching any of the configured image patterns
+// via the CRI. The workloads using them are deleted beforehand, but their containers may still be
+// terminating, so each image is only removed once no container on the node uses it any more, except
+// those of spectro-cleanup's own Pod. Images still in use after imageWaitTimeout are skipped.
+func removeImages(ctx context.Context) {
+	if len(imagePatterns) == 0 {
+		return
+	}
+
+	log.Info("Removing container images", "patterns", imagePatterns, "endpoint", criEndpoint)
+	images := struct {
+		Images []criImage `json:"images"`
+	}{}
+	if err := crictlJSON(ctx, &images, "images", "-o", "json"); err != nil {
+		log.Error(err, "failed to list container images")
+		return
+	}
+	var matching []criImage
+	for _, image := range images.Images {
+		if image.matches(imagePatterns) {
+			matching = append(matching, image)
+		}
+	}
+	if len(matching) == 0 {
+		return
+	}
+
+	inUse, err := waitForImagesUnused(ctx, matching)
+	if err != nil {
+		log.Error(err, "failed to list containers, skipping container image removal")
+		return
+	}
+	for _, image := range matching {
+		if inUse[image.ID] {
+			log.Info("WARNING: skipping container image still in use", "id", image.ID, "tags", image.RepoTags)
+			continue
+		}
+		log.Info("Removing container image", "id", image.ID, "tags", image.RepoTags)
+		if err := command.Run(ctx, cmdTimeout, crictlCommand("rmi", image.ID)); err != nil {
+			log.Error(err, "container image removal failed", "id", image.ID)
+			continue
+		}
+		log.Info("Container image removal successful", "id", image.ID)
+	}
+}
+
+// waitForImagesUnused blocks until none of the images is used by a container other than those of
+// spectro-cleanup's own Pod, or until imageWaitTimeout elapses, returning the IDs of the images still in use
+func waitForImagesUnused(ctx context.Context, images []criImage) (map[string]bool, error) {
+	deadline := time.Now().Add(imageWaitTimeout)
+	for {
+		containers := struct {
+			Containers []criContainer `json:"containers"`
+		}{}
+		if err := crictlJSON(ctx, &containers, "ps", "-a", "-o", "json"); err != nil {
+			return nil, err
+		}
+		inUse := imagesInUse(images, containers.Containers, podName)
+		if len(inUse) == 0 || !time.Now().Before(deadline) {
+			return inUse, nil
+		}
+
+		log.Info("Waiting for containers using container images to be removed", "images", len(inUse))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(imagePollInterval):
+		}
+	}
+}
+
+// imagesInUse returns the IDs of the images used by any of the containers, ignoring those of the Pod named self
+func imagesInUse(images []criImage, containers []criContainer, self string) map[string]bool {
+	inUse := map[string]bool{}
+	for _, c := range containers {
+		if self != "" && c.Labels["io.kubernetes.pod.name"] == self {
+			continue
+		}
+		for _, image := range images {
+			if c.ImageRef == image.ID || c.Image.Image == image.ID {
+				inUse[image.ID] = true
+			}
+		}
+	}
+	return inUse
+}
+
+// crictlJSON runs a crictl command and parses its JSON output into v
+func crictlJSON(ctx context.Context, v interface{}, args ...string) error {
+	out, err := command.Output(ctx, cmdTimeout, crictlCommand(args...))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(out, v)
+}
+
+// crictlCommand returns a crictl command targeting the configured CRI endpoint
+func crictlCommand(args ...string) []string {
+	return append([]string{crictlPath, "--runtime-endpoint", criEndpoint, "--image-endpoint", criEndpoint}, args...)
+}
+
+// matches reports whether any of an image's tags or digests match any of the patterns. Patterns
+// use path.Match syntax, and patterns without a tag or digest match every tag of a repository.
+func (i criImage) matches(patterns []string) bool {
+	refs := append(append([]string{}, i.RepoTags...), i.RepoDigests...)
+	for _, ref := range refs {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, ref); ok {
+				return true
+			}
+			if ok, _ := path.Match(pattern, imageRepository(ref)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// imageRepository strips the tag or digest from an image reference
+func imageRepository(ref string) string {
+	if repo, _, ok := strings.Cut(ref, "@"); ok {
+		return repo
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return ref[:i]
+	}
+	return ref
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCRIImageMatches(t *testing.T) {
+	image := criImage{
+		ID:          "sha256:0123",
+		RepoTags:    []string{"gcr.io/spectro-images-public/agent:v4.2.0"},
+		RepoDigests: []string{"gcr.io/spectro-images-public/agent@sha256:abcd"},
+	}
+
+	tests := []struct {
+		name     string
+		pattern  string
+		expected bool
+	}{
+		{
+			name:     "exact tag",
+			pattern:  "gcr.io/spectro-images-public/agent:v4.2.0",
+			expected: true,
+		},
+		{
+			name:     "repository without tag",
+			pattern:  "gcr.io/spectro-images-public/agent",
+			expected: true,
+		},
+		{
+			name:     "wildcard repository",
+			pattern:  "gcr.io/spectro-images-public/*",
+			expected: true,
+		},
+		{
+			name:     "wildcard tag",
+			pattern:  "gcr.io/spectro-images-public/agent:v4.*",
+			expected: true,
+		},
+		{
+			name:     "other tag",
+			pattern:  "gcr.io/spectro-images-public/agent:v3.*",
+			expected: false,
+		},
+		{
+			name:     "wildcard does not cross path segments",
+			pattern:  "gcr.io/*",
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if matches := image.matches([]string{tt.pattern}); matches != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, matches)
+			}
+		})
+	}
+}
+
+func TestImageRepository(t *testing.T) {
+	tests := []struct {
+		ref      string
+		expected string
+	}{
+		{ref: "nginx", expected: "nginx"},
+		{ref: "nginx:1.25", expected: "nginx"},
+		{ref: "registry:5000/team/app", expected: "registry:5000/team/app"},
+		{ref: "registry:5000/team/app:v1", expected: "registry:5000/team/app"},
+		{ref: "docker.io/library/nginx@sha256:abcd", expected: "docker.io/library/nginx"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			if repo := imageRepository(tt.ref); repo != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, repo)
+			}
+		})
+	}
+}
+
+func TestImagesInUse(t *testing.T) {
+	images := []criImage{{ID: "sha256:0123"}, {ID: "sha256:4567"}}
+	container := func(pod, imageRef string) criContainer {
+		return criContainer{ImageRef: imageRef, Labels: map[string]string{"io.kubernetes.pod.name": pod}}
+	}
+
+	tests := []struct {
+		name       string
+		containers []criContainer
+		expected   map[string]bool
+	}{
+		{
+			name:     "no containers",
+			expected: map[string]bool{},
+		},
+		{
+			name:       "used by a workload",
+			containers: []criContainer{container("agent-5f7c9", "sha256:0123")},
+			expected:   map[string]bool{"sha256:0123": true},
+		},
+		{
+			name:       "used by spectro-cleanup",
+			containers: []criContainer{container("spectro-cleanup-x2x9q", "sha256:0123")},
+			expected:   map[string]bool{},
+		},
+		{
+			name:       "other image",
+			containers: []criContainer{container("agent-5f7c9", "sha256:89ab")},
+			expected:   map[string]bool{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inUse := imagesInUse(images, tt.containers, "spectro-cleanup-x2x9q")
+			if !reflect.DeepEqual(inUse, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, inUse)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package command runs host commands, e.g., to restart services or remove container images
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var log = ctrl.Log.WithName("spectro-cleanup")
+
+// ErrEmpty is returned for a command without any arguments
+var ErrEmpty = errors.New("command must not be empty")
+
+// Run executes a command, e.g., ["systemctl", "restart", "kubelet"], and logs its combined output.
+// The command is killed if it does not complete within timeout.
+func Run(ctx context.Context, timeout time.Duration, command []string) error {
+	return RunInput(ctx, timeout, command, nil)
+}
+
+// RunInput executes a command like Run, writing input to its standard input
+func RunInput(ctx context.Context, timeout time.Duration, command []string, input []byte) error {
+	if len(command) == 0 {
+		return ErrEmpty
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	log.Info("Running command", "command", strings.Join(command, " "))
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...) // #nosec G204
+	if input != nil {
+		cmd.Stdin = bytes.NewReader(input)
+	}
+	out, err := cmd.CombinedOutput()
+	if len(out) > 0 {
+		log.Info("Command output", "command", command[0], "output", strings.TrimSpace(string(out)))
+	}
+	if err != nil {
+		return err
+	}
+	log.Info("Command successful", "command", command[0])
+	return nil
+}
+
+// Output executes a command and returns its standard output. The command is killed
+// if it does not complete within timeout.
+func Output(ctx context.Context, timeout time.Duration, command []string) ([]byte, error) {
+	if len(command) == 0 {
+		return nil, ErrEmpty
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...) // #nosec G204
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,189 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package e2e runs package cleaner against a real API server, catching what fake clients can't, e.g.,
+// resource scopes, pagination and garbage collection. The API server is a local control plane started
+// by envtest, whose binaries are located by KUBEBUILDER_ASSETS, or, if USE_EXISTING_CLUSTER is true,
+// the existing cluster of the current kubeconfig, e.g., a kind cluster.
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/metadata"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+// pollInterval is how often Eventually checks the cluster state
+const pollInterval = 250 * time.Millisecond
+
+// Cluster is the API server the end-to-end tests run against, and its clients
+type Cluster struct {
+	Client    ctrlclient.Client
+	Dynamic   dynamic.Interface
+	Metadata  metadata.Interface
+	Discovery discovery.DiscoveryInterface
+
+	env      *envtest.Environment
+	existing bool
+}
+
+// Start starts envtest's control plane, or connects to the existing cluster, and installs the CRDs
+// in crdPaths, i.e., directories or files of CRD manifests
+func Start(crdPaths ...string) (*Cluster, error) {
+	existing := strings.EqualFold(os.Getenv("USE_EXISTING_CLUSTER"), "true")
+	env := &envtest.Environment{CRDDirectoryPaths: crdPaths, ErrorIfCRDPathMissing: true, UseExistingCluster: &existing}
+	config, err := env.Start()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the test cluster: %w", err)
+	}
+
+	c := &Cluster{env: env, existing: existing}
+	if c.Client, err = ctrlclient.New(config, ctrlclient.Options{Scheme: clientgoscheme.Scheme}); err != nil {
+		return nil, errors.Join(err, env.Stop())
+	}
+	if c.Dynamic, err = dynamic.NewForConfig(config); err != nil {
+		return nil, errors.Join(err, env.Stop())
+	}
+	if c.Metadata, err = metadata.NewForConfig(config); err != nil {
+		return nil, errors.Join(err, env.Stop())
+	}
+	if c.Discovery, err = discovery.NewDiscoveryClientForConfig(config); err != nil {
+		return nil, errors.Join(err, env.Stop())
+	}
+	return c, nil
+}
+
+// Stop stops envtest's control plane. The CRDs installed in an existing cluster are left behind.
+func (c *Cluster) Stop() error {
+	return c.env.Stop()
+}
+
+// Existing reports whether the tests run against an existing cluster. Unlike envtest's bare control
+// plane, an existing cluster runs controllers, e.g., the garbage collector.
+func (c *Cluster) Existing() bool {
+	return c.existing
+}
+
+// Cleaner returns a Cleaner of the cluster's resources, configured by opts
+func (c *Cluster) Cleaner(opts cleaner.Options) *cleaner.Cleaner {
+	opts.Client = c.Client
+	opts.MetadataClient = c.Metadata
+	return cleaner.New(opts)
+}
+
+// Namespace creates a uniquely named namespace, which is deleted once the test completes
+func (c *Cluster) Namespace(t testing.TB) string {
+	t.Helper()
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "e2e-"}}
+	if err := c.Client.Create(context.Background(), ns); err != nil {
+		t.Fatalf("failed to create namespace: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = c.Client.Delete(context.Background(), ns)
+	})
+	return ns.Name
+}
+
+// Apply creates the objects of a YAML fixture of one or more documents. Namespaced objects are
+// created in namespace, and cluster-scoped objects are deleted once the test completes.
+func (c *Cluster) Apply(t testing.TB, namespace, fixture string) {
+	t.Helper()
+	data, err := os.ReadFile(fixture)
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		u := &unstructured.Unstructured{}
+		if err := decoder.Decode(&u.Object); errors.Is(err, io.EOF) {
+			return
+		} else if err != nil {
+			t.Fatalf("invalid fixture %s: %v", fixture, err)
+		}
+		if len(u.Object) == 0 {
+			continue
+		}
+		mapping, err := c.Client.RESTMapper().RESTMapping(u.GroupVersionKind().GroupKind(), u.GroupVersionKind().Version)
+		if err != nil {
+			t.Fatalf("unknown kind %s in fixture %s: %v", u.GroupVersionKind(), fixture, err)
+		}
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			u.SetNamespace(namespace)
+		} else {
+			t.Cleanup(func() {
+				_ = c.Client.Delete(context.Background(), u)
+			})
+		}
+		if err := c.Client.Create(context.Background(), u); err != nil {
+			t.Fatalf("failed to create %s %s: %v", u.GetKind(), u.GetName(), err)
+		}
+	}
+}
+
+// Exists reports whether a resource exists, including if it is being deleted
+func (c *Cluster) Exists(t testing.TB, gvr schema.GroupVersionResource, namespace, name string) bool {
+	t.Helper()
+	_, err := c.Metadata.Resource(gvr).Namespace(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false
+	} else if err != nil {
+		t.Fatalf("failed to get %s %s/%s: %v", gvr, namespace, name, err)
+	}
+	return true
+}
+
+// Count returns how many resources in namespace match a label selector
+func (c *Cluster) Count(t testing.TB, gvr schema.GroupVersionResource, namespace, labelSelector string) int {
+	t.Helper()
+	list, err := c.Metadata.Resource(gvr).Namespace(namespace).List(context.Background(), metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		t.Fatalf("failed to list %s: %v", gvr, err)
+	}
+	return len(list.Items)
+}
+
+// Eventually fails the test unless condition holds before timeout, e.g., once the garbage collector
+// of an existing cluster has deleted dependents
+func Eventually(t testing.TB, timeout time.Duration, condition func() bool, msg string) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out after %s: %s", timeout, msg)
+		}
+		time.Sleep(pollInterval)
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package retry retries Kubernetes API calls failing with transient errors
+package retry
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var log = ctrl.Log.WithName("spectro-cleanup")
+
+// Retryable reports whether an API call failed with a transient error, and may succeed if retried
+func Retryable(err error) bool {
+	switch {
+	case apierrors.IsTooManyRequests(err), apierrors.IsServerTimeout(err), apierrors.IsTimeout(err),
+		apierrors.IsInternalError(err), apierrors.IsServiceUnavailable(err):
+		return true
+	}
+	return utilnet.IsConnectionReset(err) || utilnet.IsConnectionRefused(err) || utilnet.IsProbableEOF(err)
+}
+
+// Rules classify further errors as transient, in addition to those recognized by Retryable, e.g.,
+// the errors of a proxy or service mesh between the client and the API server
+type Rules struct {
+	// StatusCodes are the HTTP status codes of API errors to retry
+	StatusCodes []int32
+
+	// Messages are substrings of error messages to retry
+	Messages []string
+}
+
+// Retryable reports whether err is transient according to Retryable or any of the rules
+func (r Rules) Retryable(err error) bool {
+	if Retryable(err) {
+		return true
+	}
+	var status apierrors.APIStatus
+	if errors.As(err, &status) && slices.Contains(r.StatusCodes, status.Status().Code) {
+		return true
+	}
+	return slices.ContainsFunc(r.Messages, func(msg string) bool { return strings.Contains(err.Error(), msg) })
+}
+
+// Policy is how Mutation retries API calls
+type Policy struct {
+	// Backoff is the backoff between attempts, and its Steps the maximum number of attempts
+	Backoff wait.Backoff
+
+	// Limiter, if set, throttles every attempt
+	Limiter flowcontrol.RateLimiter
+
+	// Clock times the delays between attempts. Defaults to the real clock.
+	Clock clock.Clock
+
+	// Retryable reports whether an attempt failed with a transient error. Defaults to Retryable.
+	Retryable func(err error) bool
+}
+
+// Mutation performs a destructive API call, throttled by the policy's limiter. Transient failures are
+// retried up to Backoff.Steps times with exponential backoff, or after the delay requested by the
+// server's Retry-After header, e.g., when rejected by API priority and fairness.
+// No call is issued once ctx is done, but a call already issued is never cancelled by ctx.
+func Mutation(ctx context.Context, policy Policy, fn func(ctx context.Context) error) error {
+	backoff, steps := policy.Backoff, policy.Backoff.Steps
+	clk := policy.Clock
+	if clk == nil {
+		clk = clock.RealClock{}
+	}
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = Retryable
+	}
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if policy.Limiter != nil {
+			if err := policy.Limiter.Wait(ctx); err != nil {
+				return err
+			}
+		}
+		err := fn(context.WithoutCancel(ctx))
+		if err == nil || !retryable(err) || attempt >= steps {
+			return err
+		}
+
+		delay := backoff.Step()
+		if seconds, ok := apierrors.SuggestsClientDelay(err); ok && seconds > 0 {
+			delay = time.Duration(seconds) * time.Second
+		}
+		log.Info("Retrying after transient API error", "error", err.Error(), "attempt", attempt, "delay", delay.String())
+		select {
+		case <-ctx.Done():
+			return err
+		case <-clk.After(delay):
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestRetryable(t *testing.T) {
+	gr := schema.GroupResource{Resource: "configmaps"}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "too many requests", err: apierrors.NewTooManyRequests("slow down", 1), expected: true},
+		{name: "server timeout", err: apierrors.NewServerTimeout(gr, "delete", 1), expected: true},
+		{name: "service unavailable", err: apierrors.NewServiceUnavailable("unavailable"), expected: true},
+		{name: "internal error", err: apierrors.NewInternalError(errors.New("etcd")), expected: true},
+		{name: "connection refused", err: syscall.ECONNREFUSED, expected: true},
+		{name: "not found", err: apierrors.NewNotFound(gr, "cm"), expected: false},
+		{name: "forbidden", err: apierrors.NewForbidden(gr, "cm", errors.New("rbac")), expected: false},
+		{name: "conflict", err: apierrors.NewConflict(gr, "cm", errors.New("modified")), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Retryable(tt.err); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRulesRetryable(t *testing.T) {
+	gr := schema.GroupResource{Resource: "configmaps"}
+	rules := Rules{StatusCodes: []int32{http.StatusBadGateway}, Messages: []string{"upstream connect error"}}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "retryable by default", err: apierrors.NewTooManyRequests("slow down", 1), expected: true},
+		{name: "status code", err: apierrors.NewGenericServerResponse(http.StatusBadGateway, "delete", gr, "cm", "", 0, false), expected: true},
+		{name: "other status code", err: apierrors.NewGenericServerResponse(http.StatusBadRequest, "delete", gr, "cm", "", 0, false), expected: false},
+		{name: "message", err: errors.New("upstream connect error or disconnect/reset before headers"), expected: true},
+		{name: "not found", err: apierrors.NewNotFound(gr, "cm"), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rules.Retryable(tt.err); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMutation(t *testing.T) {
+	backoff := wait.Backoff{Steps: 3, Duration: time.Millisecond, Factor: 1}
+	gr := schema.GroupResource{Resource: "configmaps"}
+
+	tests := []struct {
+		name             string
+		errs             []error
+		retryable        func(error) bool
+		expectedAttempts int
+		expectedError    bool
+	}{
+		{
+			name:             "success",
+			errs:             []error{nil},
+			expectedAttempts: 1,
+		},
+		{
+			name:             "transient error retried",
+			errs:             []error{apierrors.NewServiceUnavailable("unavailable"), nil},
+			expectedAttempts: 2,
+		},
+		{
+			name:             "permanent error not retried",
+			errs:             []error{apierrors.NewForbidden(gr, "cm", errors.New("rbac"))},
+			expectedAttempts: 1,
+			expectedError:    true,
+		},
+		{
+			name: "attempts exhausted",
+			errs: []error{
+				apierrors.NewServiceUnavailable("unavailable"),
+				apierrors.NewServiceUnavailable("unavailable"),
+				apierrors.NewServiceUnavailable("unavailable"),
+			},
+			expectedAttempts: 3,
+			expectedError:    true,
+		},
+		{
+			name:             "custom predicate",
+			errs:             []error{errors.New("upstream connect error"), nil},
+			retryable:        Rules{Messages: []string{"upstream connect error"}}.Retryable,
+			expectedAttempts: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := Mutation(context.Background(), Policy{Backoff: backoff, Retryable: tt.retryable}, func(context.Context) error {
+				err := tt.errs[attempts]
+				attempts++
+				return err
+			})
+			if err != nil && !tt.expectedError {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if err == nil && tt.expectedError {
+				t.Fatalf("expected error, got nil")
+			}
+			if attempts != tt.expectedAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.expectedAttempts, attempts)
+			}
+		})
+	}
+}
+
+func TestMutationContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	err := Mutation(ctx, Policy{Backoff: wait.Backoff{Steps: 1}}, func(context.Context) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	if called {
+		t.Error("expected no call once the context is done")
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// clients are the API clients of the target cluster
+type clients struct {
+	client    ctrlclient.Client
+	dynamic   dynamic.Interface
+	metadata  metadata.Interface
+	discovery discovery.DiscoveryInterface
+
+	// host is the API server's URL, shown when confirming an interactive run
+	host string
+}
+
+// clientFactory constructs the API clients of the target cluster, e.g., from the in-cluster config
+// or a kubeconfig, or fake clients in tests
+type clientFactory interface {
+	clients() (*clients, error)
+}
+
+// restClientFactory constructs the API clients for a client config
+type restClientFactory struct {
+	config func() (*rest.Config, error)
+}
+
+func (f restClientFactory) clients() (*clients, error) {
+	config, err := f.config()
+	if err != nil {
+		return nil, err
+	}
+	configureRestConfig(config)
+	if offline {
+		if err := validateOffline(config, readPluginConfig()); err != nil {
+			return nil, err
+		}
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	// a discovery cache replaces the discovery of resources by the client's RESTMapper and by spectro-cleanup
+	var discoveryInterface discovery.DiscoveryInterface = discoveryClient
+	var mapper meta.RESTMapper
+	if discoveryCachePath != "" {
+		groups, err := discoveryCache(discoveryClient)
+		if err != nil {
+			return nil, err
+		}
+		discoveryInterface = &cachedDiscovery{DiscoveryInterface: discoveryClient, groups: groups}
+		mapper = restmapper.NewDiscoveryRESTMapper(groups)
+	}
+
+	client, err := ctrlclient.New(config, ctrlclient.Options{Scheme: scheme, Mapper: mapper})
+	if err != nil {
+		return nil, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	metadataClient, err := metadata.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &clients{client: client, dynamic: dynamicClient, metadata: metadataClient, discovery: discoveryInterface, host: config.Host}, nil
+}
+
+// newClientFactory returns the client factory for the target cluster. An explicit kubeconfig or
+// context allows spectro-cleanup to run out of cluster, e.g., from a laptop or CI runner. Otherwise,
+// the KUBECONFIG env var is honored, falling back to the in-cluster config and then ~/.kube/config.
+func newClientFactory() clientFactory {
+	if kubeconfigPath == "" && kubeContext == "" {
+		return restClientFactory{config: ctrl.GetConfig}
+	}
+	return restClientFactory{config: kubeconfigRestConfig}
+}
+
+// kubeconfigRestConfig loads the client config for the configured kubeconfig and context
+func kubeconfigRestConfig() (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfigPath
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		rules, &clientcmd.ConfigOverrides{CurrentContext: kubeContext},
+	).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	// match the controller-runtime defaults applied to every other config source
+	config.QPS, config.Burst = 20, 30
+	return config, nil
+}
+
+// configureRestConfig applies the configured rate limits, timeouts and impersonation to a client config
+func configureRestConfig(config *rest.Config) {
+	// client side rate limits, e.g., raised for large cleanups or lowered for fragile managed API servers
+	if kubeAPIQPS > 0 {
+		config.QPS = kubeAPIQPS
+	}
+	if kubeAPIBurst > 0 {
+		config.Burst = kubeAPIBurst
+	}
+
+	// transport timeouts, e.g., raised for slow API servers or proxies
+	if kubeAPITimeout > 0 {
+		config.Timeout = kubeAPITimeout
+	}
+	if kubeTLSTimeout > 0 || kubeRespTimeout > 0 {
+		config.WrapTransport = withTransportTimeouts
+	}
+
+	// test-only fault injection, e.g., to verify the MustDelete and retry behavior of cleanup configs in CI
+	if chaos := readChaosConfig(); chaos != nil {
+		config.Wrap(chaos.wrap)
+	}
+
+	// impersonating a constrained identity verifies a cleanup config can run with least privilege
+	if impersonateUser != "" {
+		log.Info("Impersonating user", "user", impersonateUser, "groups", impersonateGroups)
+		config.Impersonate = rest.ImpersonationConfig{UserName: impersonateUser, Groups: impersonateGroups}
+	}
+}
+
+// withTransportTimeouts applies the configured TLS handshake and response header timeouts to a
+// client's base transport. The transport is cloned, as client-go shares it between clients.
+func withTransportTimeouts(rt http.RoundTripper) http.RoundTripper {
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		log.Info("WARNING: unable to apply transport timeouts", "transport", fmt.Sprintf("%T", rt))
+		return rt
+	}
+	t = t.Clone()
+	if kubeTLSTimeout > 0 {
+		t.TLSHandshakeTimeout = kubeTLSTimeout
+	}
+	if kubeRespTimeout > 0 {
+		t.ResponseHeaderTimeout = kubeRespTimeout
+	}
+	return t
+}
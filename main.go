@@ -20,11 +20,14 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"math/rand/v2"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -37,15 +40,21 @@ import (
 	"golang.org/x/net/http2/h2c"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/util/flowcontrol"
 	"k8s.io/klog/v2/textlogger"
 	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
 )
 
 const (
@@ -53,14 +62,73 @@ const (
 	ResourcesToDelete = "resourcesToDelete"
 )
 
+// policyTimeout bounds each query of the deletion policy
+const policyTimeout = 10 * time.Second
+
 var (
 	scheme = runtime.NewScheme()
 	log    = ctrl.Log.WithName("spectro-cleanup")
-	notif  = new(chan bool)
 
 	// optional env vars to override default configuration
 	cleanupSeconds      int64
+	cmdTimeout          time.Duration
 	enableGrpcServer    bool
+	grpcLinger          time.Duration
+	enableUnmount       bool
+	clearImmutableAttrs bool
+	capiMachineWait     bool
+	statusGVR           schema.GroupVersionResource
+	statusName          string
+	offline             bool
+	tenantLabels        map[string]string
+	cleanupSchedule     *cronSchedule
+	enableWatch         bool
+	argoCDHook          bool
+	skipArgoCDHooks     bool
+	suspendFlux         bool
+	forceManaged        bool
+	watchDeleteQPS      float32
+	watchDeleteBurst    int
+	orphanNamespaces    []string
+	deleteOrphans       bool
+	presets             []string
+	presetMinAgeSeconds int64
+	staleLeaseSeconds   int64
+	rsHistoryLimit      int
+	helmHistoryLimit    int
+	pvcNamespaces       []string
+	pvcUnusedSeconds    int64
+	deletePVCs          bool
+	imagePatterns       []string
+	tlsExpiredDays      int
+	tlsCertManager      bool
+	danglingWebhooks    bool
+	orphanAPIServices   bool
+	apiServiceMustDel   bool
+	nodeProviderIDs     []string
+	nodeNotReadySeconds int64
+	deletionTimeout     time.Duration
+	entryConcurrency    = 1
+	maxRunDuration      time.Duration
+	runRetryAttempts    int
+	runRetryInterval    = 30 * time.Second
+	startJitter         time.Duration
+	aggregateFailures   bool
+	bypassWebhooks      bool
+	enablePreflight     bool
+	recreationWindow    time.Duration
+	lbReleaseTimeout    = 300 * time.Second
+	imageWaitTimeout    = 300 * time.Second
+	kubeAPIQPS          float32
+	kubeAPIBurst        int
+	kubeAPITimeout      time.Duration
+	kubeTLSTimeout      time.Duration
+	kubeRespTimeout     time.Duration
+	protectedNamespaces []string
+	riskThreshold       int
+	confirmHighRisk     bool
+	assumeYes           bool
+	impersonateGroups   []string
 	propagationPolicy   = metav1.DeletePropagationBackground
 	cleanupSecondsStr   = os.Getenv("CLEANUP_DELAY_SECONDS")
 	fileConfigPath      = os.Getenv("CLEANUP_FILE_CONFIG_PATH")
@@ -70,8 +138,95 @@ var (
 	roleBindingName     = os.Getenv("CLEANUP_ROLEBINDING_NAME")
 	enableGrpcServerStr = os.Getenv("CLEANUP_GRPC_SERVER_ENABLED")
 	grpcPortStr         = os.Getenv("CLEANUP_GRPC_SERVER_PORT")
-
-	ErrIllegalCleanupNotification = errors.New("illegally notified cleanup prior to cleanup resources call")
+	grpcLingerStr       = os.Getenv("CLEANUP_GRPC_SERVER_LINGER_SECONDS")
+	fileArchiveDir      = os.Getenv("CLEANUP_FILE_ARCHIVE_DIR")
+	manifestArchiveDir  = os.Getenv("CLEANUP_MANIFEST_ARCHIVE_DIR")
+	policyOPAURL        = os.Getenv("CLEANUP_POLICY_OPA_URL")
+	offlineStr          = os.Getenv("CLEANUP_OFFLINE_ENABLED")
+	discoveryCachePath  = os.Getenv("CLEANUP_DISCOVERY_CACHE_PATH")
+	tenantLabelStr      = os.Getenv("CLEANUP_TENANT_LABEL")
+	checkpointPath      = os.Getenv("CLEANUP_CHECKPOINT_PATH")
+	runStateConfigMap   = os.Getenv("CLEANUP_RUN_STATE_CONFIGMAP")
+	statusResourceStr   = os.Getenv("CLEANUP_STATUS_RESOURCE")
+	statusNamespace     = os.Getenv("CLEANUP_STATUS_NAMESPACE")
+	aggregateFailsStr   = os.Getenv("CLEANUP_MUST_DELETE_AGGREGATE")
+	bypassWebhooksStr   = os.Getenv("CLEANUP_WEBHOOK_BYPASS_ENABLED")
+	enablePreflightStr  = os.Getenv("CLEANUP_PREFLIGHT_ENABLED")
+	recreationCheckStr  = os.Getenv("CLEANUP_RECREATION_CHECK_SECONDS")
+	lbReleaseTimeoutStr = os.Getenv("CLEANUP_LB_RELEASE_TIMEOUT_SECONDS")
+	enableUnmountStr    = os.Getenv("CLEANUP_UNMOUNT_ENABLED")
+	hostRoot            = os.Getenv("CLEANUP_HOST_ROOT")
+	commandTimeoutStr   = os.Getenv("CLEANUP_COMMAND_TIMEOUT_SECONDS")
+	clearImmutableStr   = os.Getenv("CLEANUP_CLEAR_IMMUTABLE_ENABLED")
+	capiMachineWaitStr  = os.Getenv("CLEANUP_CAPI_MACHINE_WAIT_ENABLED")
+	nodeName            = os.Getenv("CLEANUP_NODE_NAME")
+	podName             = os.Getenv("CLEANUP_POD_NAME")
+	cleanupScheduleStr  = os.Getenv("CLEANUP_SCHEDULE")
+	ruleConfigPath      = os.Getenv("CLEANUP_RULE_CONFIG_PATH")
+	hookConfigPath      = os.Getenv("CLEANUP_HOOK_CONFIG_PATH")
+	pluginConfigPath    = os.Getenv("CLEANUP_PLUGIN_CONFIG_PATH")
+	aliasConfigPath     = os.Getenv("CLEANUP_ALIAS_CONFIG_PATH")
+	guardConfigPath     = os.Getenv("CLEANUP_GUARD_CONFIG_PATH")
+	chaosConfigPath     = os.Getenv("CLEANUP_CHAOS_CONFIG_PATH")
+	pluginDir           = os.Getenv("CLEANUP_PLUGIN_DIR")
+	enableWatchStr      = os.Getenv("CLEANUP_WATCH_ENABLED")
+	argoCDHookStr       = os.Getenv("CLEANUP_ARGOCD_HOOK_ENABLED")
+	skipArgoHooksStr    = os.Getenv("CLEANUP_ARGOCD_SKIP_HOOKS_ENABLED")
+	suspendFluxStr      = os.Getenv("CLEANUP_FLUX_SUSPEND_ENABLED")
+	forceManagedStr     = os.Getenv("CLEANUP_FORCE_MANAGED")
+	watchDeleteQPSStr   = os.Getenv("CLEANUP_WATCH_DELETE_QPS")
+	watchDeleteBurstStr = os.Getenv("CLEANUP_WATCH_DELETE_BURST")
+	orphanNamespacesStr = os.Getenv("CLEANUP_ORPHAN_NAMESPACES")
+	deleteOrphansStr    = os.Getenv("CLEANUP_ORPHAN_DELETE_ENABLED")
+	presetsStr          = os.Getenv("CLEANUP_PRESETS")
+	presetMinAgeStr     = os.Getenv("CLEANUP_PRESET_MIN_AGE_SECONDS")
+	staleLeaseSecStr    = os.Getenv("CLEANUP_LEASE_STALE_SECONDS")
+	rsHistoryLimitStr   = os.Getenv("CLEANUP_REPLICASET_HISTORY_LIMIT")
+	helmHistoryLimitStr = os.Getenv("CLEANUP_HELM_HISTORY_LIMIT")
+	pvcNamespacesStr    = os.Getenv("CLEANUP_PVC_NAMESPACES")
+	pvcUnusedSecondsStr = os.Getenv("CLEANUP_PVC_UNUSED_SECONDS")
+	deletePVCsStr       = os.Getenv("CLEANUP_PVC_DELETE_ENABLED")
+	imagePatternsStr    = os.Getenv("CLEANUP_IMAGE_PATTERNS")
+	criEndpoint         = os.Getenv("CLEANUP_CRI_ENDPOINT")
+	crictlPath          = os.Getenv("CLEANUP_CRICTL_PATH")
+	imageWaitTimeoutStr = os.Getenv("CLEANUP_IMAGE_WAIT_TIMEOUT_SECONDS")
+	tlsExpiredDaysStr   = os.Getenv("CLEANUP_TLS_EXPIRED_DAYS")
+	tlsCertManagerStr   = os.Getenv("CLEANUP_TLS_CERT_MANAGER_ENABLED")
+	danglingWebhooksStr = os.Getenv("CLEANUP_DANGLING_WEBHOOKS_ENABLED")
+	orphanAPISvcsStr    = os.Getenv("CLEANUP_ORPHAN_APISERVICES_ENABLED")
+	apiSvcMustDelStr    = os.Getenv("CLEANUP_ORPHAN_APISERVICES_MUST_DELETE")
+	nodeProviderIDsStr  = os.Getenv("CLEANUP_NODE_PROVIDER_IDS")
+	nodeNotReadySecStr  = os.Getenv("CLEANUP_NODE_NOT_READY_SECONDS")
+	protectedNsStr      = os.Getenv("CLEANUP_PROTECTED_NAMESPACES")
+	riskThresholdStr    = os.Getenv("CLEANUP_HIGH_RISK_THRESHOLD")
+	confirmHighRiskStr  = os.Getenv("CLEANUP_CONFIRM_HIGH_RISK")
+	kubeconfigPath      = os.Getenv("CLEANUP_KUBECONFIG")
+	kubeContext         = os.Getenv("CLEANUP_KUBE_CONTEXT")
+	assumeYesStr        = os.Getenv("CLEANUP_ASSUME_YES")
+	expectedClusterID   = os.Getenv("CLEANUP_EXPECT_CLUSTER_ID")
+	expectedServer      = os.Getenv("CLEANUP_EXPECT_SERVER")
+	defaultNamespace    = os.Getenv("CLEANUP_NAMESPACE")
+	impersonateUser     = os.Getenv("CLEANUP_AS")
+	impersonateGrpsStr  = os.Getenv("CLEANUP_AS_GROUPS")
+	deletionTimeoutStr  = os.Getenv("CLEANUP_DELETION_TIMEOUT_SECONDS")
+	entryConcurrencyStr = os.Getenv("CLEANUP_ENTRY_CONCURRENCY")
+	maxRunDurationStr   = os.Getenv("CLEANUP_MAX_RUN_DURATION_SECONDS")
+	runRetryAttemptsStr = os.Getenv("CLEANUP_RUN_RETRY_ATTEMPTS")
+	runRetryIntervalStr = os.Getenv("CLEANUP_RUN_RETRY_INTERVAL_SECONDS")
+	startJitterStr      = os.Getenv("CLEANUP_START_JITTER_SECONDS")
+	kubeAPIQPSStr       = os.Getenv("CLEANUP_KUBE_API_QPS")
+	kubeAPIBurstStr     = os.Getenv("CLEANUP_KUBE_API_BURST")
+	kubeAPITimeoutStr   = os.Getenv("CLEANUP_KUBE_API_TIMEOUT_SECONDS")
+	kubeTLSTimeoutStr   = os.Getenv("CLEANUP_KUBE_API_TLS_HANDSHAKE_TIMEOUT_SECONDS")
+	kubeRespTimeoutStr  = os.Getenv("CLEANUP_KUBE_API_RESPONSE_HEADER_TIMEOUT_SECONDS")
+	mutationQPSStr      = os.Getenv("CLEANUP_MUTATION_QPS")
+	mutationBurstStr    = os.Getenv("CLEANUP_MUTATION_BURST")
+	retryStepsStr       = os.Getenv("CLEANUP_RETRY_STEPS")
+	retryDurationStr    = os.Getenv("CLEANUP_RETRY_INITIAL_SECONDS")
+	retryFactorStr      = os.Getenv("CLEANUP_RETRY_FACTOR")
+	retryCapStr         = os.Getenv("CLEANUP_RETRY_CAP_SECONDS")
+	retryCodesStr       = os.Getenv("CLEANUP_RETRY_STATUS_CODES")
+	retryMessagesStr    = os.Getenv("CLEANUP_RETRY_MESSAGES")
 )
 
 func init() {
@@ -79,36 +234,111 @@ func init() {
 	initConfig()
 }
 
-type DeleteObj struct {
-	schema.GroupVersionResource
-	Name      string
-	Namespace string
-}
-
 func main() {
 	ctrl.SetLogger(textlogger.NewLogger(textlogger.NewConfig()))
-	ctx := context.Background()
+	run(context.Background(), newClientFactory())
+	os.Exit(0)
+}
 
+// run performs the configured cleanup of the target cluster, whose API clients are constructed by factory
+func run(ctx context.Context, factory clientFactory) {
+	apiClients, err := factory.clients()
+	if err != nil {
+		panic(err)
+	}
+	client, dynamic, metadataClient, discoveryClient := apiClients.client, apiClients.dynamic, apiClients.metadata, apiClients.discovery
+	if expectedClusterID != "" || expectedServer != "" {
+		if err := verifyCluster(ctx, client, apiClients.host); err != nil {
+			panic(err)
+		}
+	}
+
+	// the Cleaner deleting spectro-cleanup itself, notified by FinalizeCleanup requests
+	finalCleaner := cleaner.New(cleanerOptions(client, metadataClient))
 	var wg sync.WaitGroup
-	if enableGrpcServer {
+	serverCtx, stopServer := context.WithCancel(ctx)
+	defer stopServer()
+	if enableGrpcServer && cleanupSchedule == nil && !enableWatch && !argoCDHook {
 		wg.Add(1)
-		go startGRPCServer(&wg)
+		go startGRPCServer(serverCtx, &wg, finalCleaner)
 	}
 
-	config := ctrl.GetConfigOrDie()
-	client, err := ctrlclient.New(config, ctrlclient.Options{
-		Scheme: scheme,
-	})
-	if err != nil {
+	if startJitter > 0 {
+		delay := rand.N(startJitter)
+		log.Info("Delaying start", "delay", delay.Round(time.Millisecond).String())
+		time.Sleep(delay)
+	}
+
+	if enablePreflight {
+		if err := preflight(ctx, discoveryClient, cleaner.New(cleanerOptions(client, metadataClient)), readResourceConfig()); err != nil {
+			panic(err)
+		}
+	}
+
+	if cleanupSchedule != nil {
+		runScheduled(ctx, client, dynamic, metadataClient, discoveryClient)
+		return
+	}
+	if enableWatch {
+		runWatch(ctx, dynamic)
+		return
+	}
+
+	// the destructive phases only run once the uninstall has reached the point the guards declare
+	if err := checkGuards(ctx, dynamic, readGuards()); errors.Is(err, errGuardUnmet) {
+		log.Info("WARNING: skipping cleanup, a guard is not met", "reason", err.Error())
+		os.Exit(ExitCodeGuardUnmet)
+	} else if err != nil {
 		panic(err)
 	}
-	dynamic := dynamic.NewForConfigOrDie(config)
 
-	cleanupFiles()
-	cleanupResources(ctx, client, dynamic)
+	if !assumeYes && interactive() {
+		confirmRun(ctx, apiClients, os.Stdin, os.Stderr)
+	}
+
+	ctx, cancel := withStop(ctx)
+	defer cancel()
+	hooks := readPhaseHooks()
+	defer runFailureHooks(ctx, hooks)
+	for _, cleanup := range []func(){
+		func() {
+			if capiMachineWait {
+				if err := waitForMachineDeletion(ctx, client, dynamic); err != nil {
+					log.Error(err, "skipping file cleanup", "node", nodeName)
+					return
+				}
+			}
+			runPhaseHooks(ctx, "beforeFiles", hooks.BeforeFiles)
+			if err := cleanupFiles(ctx); err != nil {
+				panic(err)
+			}
+			runPhaseHooks(ctx, "afterFiles", hooks.AfterFiles)
+		},
+		func() { cleanupDanglingWebhooks(ctx, client) },
+		func() { cleanupOrphans(ctx, client) },
+		func() { pruneReplicaSets(ctx, client) },
+		func() { pruneHelmReleases(ctx, client) },
+		func() { cleanupUnusedPVCs(ctx, client) },
+		func() { cleanupDeletedNodes(ctx, client) },
+		func() { cleanupExpiredCertificates(ctx, client, dynamic) },
+		func() {
+			if err := cleanupOrphanedAPIServices(ctx, client, dynamic); err != nil {
+				panic(err)
+			}
+		},
+		func() {
+			if err := runPlugins(ctx); err != nil {
+				panic(err)
+			}
+		},
+		func() { cleanupResources(ctx, finalCleaner, client, dynamic, metadataClient, discoveryClient, hooks) },
+	} {
+		exitIfStopped(ctx)
+		cleanup()
+	}
 
+	stopServer()
 	wg.Wait()
-	os.Exit(0)
 }
 
 func initConfig() {
@@ -131,6 +361,26 @@ func initConfig() {
 	if resourceConfigPath == "" {
 		resourceConfigPath = "/tmp/spectro-cleanup/resource-config.json"
 	}
+	if ruleConfigPath == "" {
+		ruleConfigPath = "/tmp/spectro-cleanup/rule-config.json"
+	}
+	if hookConfigPath == "" {
+		hookConfigPath = "/tmp/spectro-cleanup/hook-config.json"
+	}
+	if pluginConfigPath == "" {
+		pluginConfigPath = "/tmp/spectro-cleanup/plugin-config.json"
+	}
+	if aliasConfigPath == "" {
+		aliasConfigPath = "/tmp/spectro-cleanup/alias-config.json"
+	}
+	if guardConfigPath == "" {
+		guardConfigPath = "/tmp/spectro-cleanup/guard-config.json"
+	}
+
+	// Directory the executables of cleanup plugins are discovered in
+	if pluginDir == "" {
+		pluginDir = "/opt/spectro-cleanup/plugins"
+	}
 
 	// How long the spectro cleanup Pod/DaemonSet/Job will wait before self-destructing
 	if cleanupSecondsStr == "" {
@@ -143,6 +393,424 @@ func initConfig() {
 		}
 	}
 
+	// How long a post-deletion command may run before it is killed
+	if commandTimeoutStr == "" {
+		cmdTimeout = 60 * time.Second
+	} else {
+		seconds, err := strconv.ParseInt(commandTimeoutStr, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+		cmdTimeout = time.Duration(seconds) * time.Second
+	}
+
+	// Cron schedule on which to repeatedly perform the cleanup, rather than once before self-destructing
+	if cleanupScheduleStr != "" {
+		var err error
+		cleanupSchedule, err = parseCronSchedule(cleanupScheduleStr)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// Whether to continuously delete resources matching the rule config as they appear, and how
+	// many deletions per second (with bursts) are permitted while doing so
+	enableWatch = enableWatchStr == "true"
+	if enableWatch && cleanupSchedule != nil {
+		panic("CLEANUP_WATCH_ENABLED and CLEANUP_SCHEDULE are mutually exclusive")
+	}
+
+	// Whether to run as an Argo CD PreDelete hook, which never self destructs, and whether to skip
+	// deleting Argo CD hook resources, which Argo CD deletes itself
+	argoCDHook = argoCDHookStr == "true"
+	if argoCDHook && (enableWatch || cleanupSchedule != nil) {
+		panic("CLEANUP_ARGOCD_HOOK_ENABLED is mutually exclusive with CLEANUP_WATCH_ENABLED and CLEANUP_SCHEDULE")
+	}
+	skipArgoCDHooks = skipArgoHooksStr == "true"
+
+	// Whether the Flux objects managing resources are suspended before the resources are deleted
+	suspendFlux = suspendFluxStr == "true"
+
+	// Whether resources managed by Crossplane or terraform-controller are deleted rather than skipped
+	forceManaged = forceManagedStr == "true"
+	if watchDeleteQPSStr == "" {
+		watchDeleteQPS = 5
+	} else {
+		qps, err := strconv.ParseFloat(watchDeleteQPSStr, 32)
+		if err != nil {
+			panic(err)
+		}
+		watchDeleteQPS = float32(qps)
+	}
+	if watchDeleteBurstStr == "" {
+		watchDeleteBurst = 10
+	} else {
+		var err error
+		watchDeleteBurst, err = strconv.Atoi(watchDeleteBurstStr)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// Namespaces searched for orphaned ConfigMaps and Secrets, and whether they are deleted or only reported
+	orphanNamespaces = splitList(orphanNamespacesStr)
+	deleteOrphans = deleteOrphansStr == "true"
+
+	// Groups to impersonate along with CLEANUP_AS
+	impersonateGroups = splitList(impersonateGrpsStr)
+	if len(impersonateGroups) > 0 && impersonateUser == "" {
+		panic(errors.New("CLEANUP_AS_GROUPS requires CLEANUP_AS"))
+	}
+
+	// Client side rate limits for all API requests. The client defaults apply if unset.
+	if kubeAPIQPSStr != "" {
+		qps, err := strconv.ParseFloat(kubeAPIQPSStr, 32)
+		if err != nil {
+			panic(err)
+		}
+		kubeAPIQPS = float32(qps)
+	}
+	if kubeAPIBurstStr != "" {
+		var err error
+		kubeAPIBurst, err = strconv.Atoi(kubeAPIBurstStr)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// Transport timeouts for all API requests. The client defaults apply if unset.
+	if kubeAPITimeoutStr != "" {
+		seconds, err := strconv.ParseInt(kubeAPITimeoutStr, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+		kubeAPITimeout = time.Duration(seconds) * time.Second
+	}
+	if kubeTLSTimeoutStr != "" {
+		seconds, err := strconv.ParseInt(kubeTLSTimeoutStr, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+		kubeTLSTimeout = time.Duration(seconds) * time.Second
+	}
+	if kubeRespTimeoutStr != "" {
+		seconds, err := strconv.ParseInt(kubeRespTimeoutStr, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+		kubeRespTimeout = time.Duration(seconds) * time.Second
+	}
+
+	// Token bucket limiting all destructive API calls, on top of the client side rate limit. Disabled if unset.
+	if mutationQPSStr != "" {
+		qps, err := strconv.ParseFloat(mutationQPSStr, 32)
+		if err != nil {
+			panic(err)
+		}
+		burst := 10
+		if mutationBurstStr != "" {
+			burst, err = strconv.Atoi(mutationBurstStr)
+			if err != nil {
+				panic(err)
+			}
+		}
+		mutationLimiter = flowcontrol.NewTokenBucketRateLimiter(float32(qps), burst)
+	}
+
+	// Backoff between attempts of destructive API calls failing with a transient error
+	if retryStepsStr != "" {
+		steps, err := strconv.Atoi(retryStepsStr)
+		if err != nil {
+			panic(err)
+		}
+		if steps < 1 {
+			panic(fmt.Errorf("CLEANUP_RETRY_STEPS must be at least 1, got %d", steps))
+		}
+		retryBackoff.Steps = steps
+	}
+	if retryDurationStr != "" {
+		seconds, err := strconv.ParseInt(retryDurationStr, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+		retryBackoff.Duration = time.Duration(seconds) * time.Second
+	}
+	if retryFactorStr != "" {
+		factor, err := strconv.ParseFloat(retryFactorStr, 64)
+		if err != nil {
+			panic(err)
+		}
+		retryBackoff.Factor = factor
+	}
+	if retryCapStr != "" {
+		seconds, err := strconv.ParseInt(retryCapStr, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+		retryBackoff.Cap = time.Duration(seconds) * time.Second
+	}
+
+	// Further errors to retry, e.g., the transient errors of a proxy or service mesh
+	for _, codeStr := range splitList(retryCodesStr) {
+		code, err := strconv.ParseInt(codeStr, 10, 32)
+		if err != nil {
+			panic(err)
+		}
+		retryRules.StatusCodes = append(retryRules.StatusCodes, int32(code))
+	}
+	retryRules.Messages = splitList(retryMessagesStr)
+
+	// How long deletions may block waiting for resources to be gone. Deletions don't block if unset.
+	if deletionTimeoutStr != "" {
+		seconds, err := strconv.ParseInt(deletionTimeoutStr, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+		deletionTimeout = time.Duration(seconds) * time.Second
+	}
+
+	// How long a one-shot cleanup may run before winding down and exiting. Unbounded if unset.
+	if maxRunDurationStr != "" {
+		seconds, err := strconv.ParseInt(maxRunDurationStr, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+		maxRunDuration = time.Duration(seconds) * time.Second
+	}
+
+	// How many times a one-shot cleanup whose mustDelete entries failed retries the entries that didn't
+	// succeed, and how long it waits before each retry. Failed runs aren't retried if unset.
+	if runRetryAttemptsStr != "" {
+		var err error
+		runRetryAttempts, err = strconv.Atoi(runRetryAttemptsStr)
+		if err != nil {
+			panic(err)
+		}
+	}
+	if runRetryIntervalStr != "" {
+		seconds, err := strconv.ParseInt(runRetryIntervalStr, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+		runRetryInterval = time.Duration(seconds) * time.Second
+	}
+
+	// Whether API server reachability and the resources of every resource config entry are verified before cleaning up
+	enablePreflight = enablePreflightStr == "true"
+
+	// Whether the configurations of admission webhooks blocking deletions because they can't be called are deleted
+	bypassWebhooks = bypassWebhooksStr == "true"
+
+	// Whether every resource entry is processed before failing on MustDelete entries, rather than failing on the first
+	aggregateFailures = aggregateFailsStr == "true"
+
+	// Upper bound of a random delay before starting, so that DaemonSet Pods don't all start at once
+	if startJitterStr != "" {
+		seconds, err := strconv.ParseInt(startJitterStr, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+		startJitter = time.Duration(seconds) * time.Second
+	}
+
+	// How long to watch for confirmed deletions being undone by a controller. Not checked if unset.
+	if recreationCheckStr != "" {
+		seconds, err := strconv.ParseInt(recreationCheckStr, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+		recreationWindow = time.Duration(seconds) * time.Second
+	}
+
+	// How long deleting a Service of type LoadBalancer blocks until its cloud load balancer is
+	// released, unless entries already block until their resources are gone. Disabled if zero.
+	if lbReleaseTimeoutStr != "" {
+		seconds, err := strconv.ParseInt(lbReleaseTimeoutStr, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+		lbReleaseTimeout = time.Duration(seconds) * time.Second
+	}
+
+	// How many resource config entries are processed concurrently. Entries are processed in order if unset.
+	if entryConcurrencyStr != "" {
+		var err error
+		entryConcurrency, err = strconv.Atoi(entryConcurrencyStr)
+		if err != nil {
+			panic(err)
+		}
+		if entryConcurrency < 1 {
+			panic(fmt.Errorf("CLEANUP_ENTRY_CONCURRENCY must be at least 1, got %d", entryConcurrency))
+		}
+	}
+
+	// Namespaces never deleted by rules or delete-all entries, in addition to the K8s system namespaces
+	protectedNamespaces = splitList(protectedNsStr)
+
+	// How many resources an entry without a name may match before it must be confirmed as high-risk. Unlimited if unset.
+	if riskThresholdStr != "" {
+		var err error
+		riskThreshold, err = strconv.Atoi(riskThresholdStr)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// Whether every high-risk entry is confirmed, rather than requiring confirmHighRisk on each
+	confirmHighRisk = confirmHighRiskStr == "true"
+
+	// Whether an interactive run proceeds without showing its plan and requiring a typed confirmation
+	assumeYes = assumeYesStr == "true"
+
+	// Built-in rule presets, and the minimum age of the objects they match
+	presets = splitList(presetsStr)
+	for _, preset := range presets {
+		if !slices.Contains(knownPresets, preset) {
+			panic(fmt.Errorf("unknown preset %q, must be one of %v", preset, knownPresets))
+		}
+	}
+	if presetMinAgeStr == "" {
+		presetMinAgeSeconds = 3600
+	} else {
+		var err error
+		presetMinAgeSeconds, err = strconv.ParseInt(presetMinAgeStr, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+	}
+	if staleLeaseSecStr == "" {
+		staleLeaseSeconds = 86400
+	} else {
+		var err error
+		staleLeaseSeconds, err = strconv.ParseInt(staleLeaseSecStr, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// How many scaled down ReplicaSet revisions to retain per Deployment. Pruning is disabled if unset.
+	if rsHistoryLimitStr == "" {
+		rsHistoryLimit = -1
+	} else {
+		var err error
+		rsHistoryLimit, err = strconv.Atoi(rsHistoryLimitStr)
+		if err != nil {
+			panic(err)
+		}
+		if rsHistoryLimit < 0 {
+			panic(fmt.Errorf("CLEANUP_REPLICASET_HISTORY_LIMIT must not be negative, got %d", rsHistoryLimit))
+		}
+	}
+
+	// How many Helm release revisions to retain per release. Pruning is disabled if unset.
+	if helmHistoryLimitStr == "" {
+		helmHistoryLimit = -1
+	} else {
+		var err error
+		helmHistoryLimit, err = strconv.Atoi(helmHistoryLimitStr)
+		if err != nil {
+			panic(err)
+		}
+		if helmHistoryLimit < 1 {
+			panic(fmt.Errorf("CLEANUP_HELM_HISTORY_LIMIT must be at least 1, got %d", helmHistoryLimit))
+		}
+	}
+
+	// Namespaces searched for unused PVCs, how long a PVC must be unused for, and whether they are deleted or only reported
+	pvcNamespaces = splitList(pvcNamespacesStr)
+	if pvcUnusedSecondsStr == "" {
+		pvcUnusedSeconds = 86400
+	} else {
+		var err error
+		pvcUnusedSeconds, err = strconv.ParseInt(pvcUnusedSecondsStr, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+	}
+	deletePVCs = deletePVCsStr == "true"
+
+	// Container images removed from the node via the CRI once all other resources are deleted
+	imagePatterns = splitList(imagePatternsStr)
+	if criEndpoint == "" {
+		criEndpoint = "unix:///run/containerd/containerd.sock"
+	}
+	if crictlPath == "" {
+		crictlPath = "crictl"
+	}
+	// a Pod's hostname is its name, unless it uses the host network
+	if podName == "" {
+		podName, _ = os.Hostname()
+	}
+	if imageWaitTimeoutStr != "" {
+		seconds, err := strconv.ParseInt(imageWaitTimeoutStr, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+		imageWaitTimeout = time.Duration(seconds) * time.Second
+	}
+
+	// How many days ago a TLS Secret's certificate must have expired for it to be deleted. Disabled if unset.
+	if tlsExpiredDaysStr == "" {
+		tlsExpiredDays = -1
+	} else {
+		var err error
+		tlsExpiredDays, err = strconv.Atoi(tlsExpiredDaysStr)
+		if err != nil {
+			panic(err)
+		}
+		if tlsExpiredDays < 0 {
+			panic(fmt.Errorf("CLEANUP_TLS_EXPIRED_DAYS must not be negative, got %d", tlsExpiredDays))
+		}
+	}
+	tlsCertManager = tlsCertManagerStr == "true"
+
+	// Whether webhook configurations backed only by deleted Services are deleted
+	danglingWebhooks = danglingWebhooksStr == "true"
+
+	// Whether APIServices backed by deleted Services or workloads are deleted, and whether failing to delete one fails the cleanup
+	orphanAPIServices = orphanAPISvcsStr == "true"
+	apiServiceMustDel = apiSvcMustDelStr == "true"
+
+	// providerIDs of the Nodes that are deleted once NotReady for long enough, e.g., decommissioned bare metal machines
+	nodeProviderIDs = splitList(nodeProviderIDsStr)
+	if nodeNotReadySecStr == "" {
+		nodeNotReadySeconds = 3600
+	} else {
+		var err error
+		nodeNotReadySeconds, err = strconv.ParseInt(nodeNotReadySecStr, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// Whether mount points are unmounted prior to removal. Requires CAP_SYS_ADMIN.
+	enableUnmount = enableUnmountStr == "true"
+
+	// Whether immutable/append-only inode flags are cleared prior to removal. Requires CAP_LINUX_IMMUTABLE.
+	clearImmutableAttrs = clearImmutableStr == "true"
+
+	// Whether host files are only deleted once the Cluster API Machine of the node is being deleted
+	capiMachineWait = capiMachineWaitStr == "true"
+	if capiMachineWait && nodeName == "" {
+		panic("CLEANUP_CAPI_MACHINE_WAIT_ENABLED requires CLEANUP_NODE_NAME")
+	}
+
+	// The custom resource whose CleanupComplete condition is set once the resource cleanup is done
+	if statusResourceStr != "" {
+		var err error
+		statusGVR, statusName, err = parseStatusResource(statusResourceStr)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// Whether to run offline, i.e., without network egress other than to the API server, and the
+	// discovery cache replacing the discovery of resources
+	offline = offlineStr == "true"
+	if offline && discoveryCachePath == "" {
+		panic("CLEANUP_OFFLINE_ENABLED requires CLEANUP_DISCOVERY_CACHE_PATH")
+	}
+
 	if enableGrpcServerStr == "true" {
 		enableGrpcServer = true
 
@@ -151,85 +819,341 @@ func initConfig() {
 			panic(err)
 		}
 	}
+
+	// How long the gRPC server keeps serving after cleanup completes, before shutting down
+	if grpcLingerStr != "" {
+		lingerSeconds, err := strconv.ParseInt(grpcLingerStr, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+		grpcLinger = time.Duration(lingerSeconds) * time.Second
+	}
+
+	// The tenant label every resource must carry to be deleted, confining a shared cleanup service to a tenant
+	if tenantLabelStr != "" {
+		var err error
+		tenantLabels, err = parseTenantLabel(tenantLabelStr)
+		if err != nil {
+			panic(err)
+		}
+		if conflicts := tenantConflicts(); len(conflicts) > 0 {
+			panic(fmt.Sprintf("CLEANUP_TENANT_LABEL is mutually exclusive with %s", strings.Join(conflicts, ", ")))
+		}
+	}
 }
 
-// readConfig loads a configuration file from the local filesystem
+// splitList parses a comma-separated list, ignoring empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// readConfig loads a configuration file from the local filesystem. If the file has a sha256 sidecar
+// file, it is reread until its contents match the checksum, so that a partially propagated
+// ConfigMap update is never acted upon.
 func readConfig(path, configType string) []byte {
 	path = filepath.Clean(path)
 	log.Info("Reading Spectro Cleanup config", "path", path, "configType", configType)
-	bytes, err := os.ReadFile(path)
-	if errors.Is(err, fs.ErrNotExist) {
-		log.Info("WARNING: config file not found. Skipping.", "configType", configType)
+	for attempt := 1; ; attempt++ {
+		bytes, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			log.Info("WARNING: config file not found. Skipping.", "configType", configType)
+			return nil
+		} else if err != nil {
+			panic(err)
+		}
+		err = verifyChecksum(path, bytes)
+		if err == nil {
+			return bytes
+		}
+		if attempt == checksumAttempts {
+			panic(fmt.Errorf("%w: %w", cleaner.ErrConfigInvalid, err))
+		}
+		log.Info("WARNING: config checksum mismatch, config may be partially updated. Retrying.", "configType", configType, "error", err.Error())
+		time.Sleep(checksumRetryInterval)
+	}
+}
+
+// cleanerOptions returns the options of the Cleaner configured by the CLEANUP_* env vars
+func cleanerOptions(client ctrlclient.Client, metadataClient metadata.Interface) cleaner.Options {
+	return cleaner.Options{
+		HostRoot:            hostRoot,
+		ArchiveDir:          fileArchiveDir,
+		Unmount:             enableUnmount,
+		ClearImmutable:      clearImmutableAttrs,
+		CommandTimeout:      cmdTimeout,
+		MetadataClient:      metadataClient,
+		Client:              client,
+		BypassWebhooks:      bypassWebhooks,
+		ManifestArchiveDir:  manifestArchiveDir,
+		EntryConcurrency:    entryConcurrency,
+		PropagationPolicy:   propagationPolicy,
+		ProtectedNamespaces: protectedNamespaces,
+		SkipArgoCDHooks:     skipArgoCDHooks,
+		SuspendFlux:         suspendFlux,
+		ForceManaged:        forceManaged,
+		TenantLabels:        tenantLabels,
+		HighRiskThreshold:   riskThreshold,
+		DeletionTimeout:     deletionTimeout,
+		RecreationWindow:    recreationWindow,
+		LoadBalancerTimeout: lbReleaseTimeout,
+		Backoff:             retryBackoff,
+		RateLimiter:         mutationLimiter,
+		Retryable:           retryRules.Retryable,
+		Policy:              deletionPolicy(),
+	}
+}
+
+// deletionPolicy returns the Policy evaluating each resource deletion, if any is configured
+func deletionPolicy() cleaner.Policy {
+	if policyOPAURL == "" {
 		return nil
-	} else if err != nil {
-		panic(err)
 	}
-	return bytes
+	return &cleaner.OPAPolicy{URL: policyOPAURL, Client: &http.Client{Timeout: policyTimeout}}
 }
 
-// cleanupFiles deletes all files specified in the file cleanup config file
-func cleanupFiles() {
-	filesToDelete := []string{}
-	bytes := readConfig(fileConfigPath, FilesToDelete)
-	if bytes == nil {
-		return
+// cleanupFiles deletes all files specified in the file cleanup config file, returning the failure
+// of the file cleanup, if any
+func cleanupFiles(ctx context.Context) error {
+	filesToDelete := readFileConfig()
+	if filesToDelete == nil {
+		return nil
 	}
-	if err := json.Unmarshal(bytes, &filesToDelete); err != nil {
-		panic(err)
+	// interruptions are handled by the caller once the file cleanup returns
+	result, err := cleaner.New(cleanerOptions(nil, nil)).CleanupFiles(ctx, filesToDelete)
+	logResult("files", result)
+	if err != nil && ctx.Err() == nil {
+		return err
 	}
+	return nil
+}
 
-	for _, filePath := range filesToDelete {
-		log.Info("Deleting file", "path", filePath)
-		if err := os.Remove(filePath); err != nil {
-			log.Error(err, "file deletion failed")
+// logResult logs the outcome of a file or resource cleanup, followed by statistics per GVR of its resource
+// entries and the pre-delete snapshots of the resources of failed entries, to debug failed uninstalls
+func logResult(phase string, result *cleaner.Result) {
+	if result == nil {
+		return
+	}
+	log.Info("Cleanup result", "phase", phase, "succeeded", result.Count(cleaner.StatusSucceeded),
+		"failed", result.Count(cleaner.StatusFailed), "skipped", result.Count(cleaner.StatusSkipped),
+		"duration", result.Duration.Round(time.Millisecond).String())
+	for _, s := range result.Stats() {
+		log.Info("Cleanup statistics", "phase", phase, "gvr", s.GroupVersionResource.String(), "entries", s.Entries,
+			"matched", s.Matched, "deleted", s.Deleted, "updated", s.Updated, "failed", s.Failed, "skipped", s.Skipped,
+			"duration", s.Duration.Round(time.Millisecond).String(), "averageWait", s.AverageWait().Round(time.Millisecond).String())
+	}
+	for _, e := range result.Entries {
+		if e.Status != cleaner.StatusFailed {
 			continue
 		}
-		log.Info("File deletion successful")
+		for _, s := range e.Snapshots {
+			log.Info("Pre-delete snapshot", "gvr", e.Entry.GroupVersionResource.String(), "name", s.Resource.Name, "namespace", s.Resource.Namespace,
+				"time", s.Time, "phase", s.Phase, "conditions", s.Conditions, "finalizers", s.Finalizers, "deletionTimestamp", s.DeletionTimestamp)
+		}
 	}
 }
 
-// cleanupResources deletes all K8s resources specified in the resource cleanup config file
-func cleanupResources(ctx context.Context, client ctrlclient.Client, dynamic dynamic.Interface) {
-	resourcesToDelete := []DeleteObj{}
-	bytes := readConfig(resourceConfigPath, ResourcesToDelete)
-	if err := json.Unmarshal(bytes, &resourcesToDelete); err != nil {
-		panic(err)
-	}
-
-	*notif = make(chan bool)
+// cleanupResources deletes all K8s resources specified in the resource cleanup config file. The
+// resource phase hooks are run before the resources are deleted, and before self destructing.
+// finalCleaner deletes the final entry once it is finalized, or the self destruct delay elapses.
+// As an Argo CD hook, every entry is deleted instead, and spectro-cleanup never self destructs.
+func cleanupResources(ctx context.Context, finalCleaner *cleaner.Cleaner, client ctrlclient.Client, dynamic dynamic.Interface,
+	metadataClient metadata.Interface, discoveryClient discovery.DiscoveryInterface, hooks PhaseHooks) {
+	resourcesToDelete := readResourceConfig()
+	resourcesToDelete = discoverScopes(discoveryClient).resolve(resourcesToDelete)
 
+	opts := cleanerOptions(client, metadataClient)
+	opts.FailFast = !aggregateFailures
 	numObjs := len(resourcesToDelete)
-	for i, obj := range resourcesToDelete {
+	runPhaseHooks(ctx, "beforeResources", hooks.BeforeResources)
+	if argoCDHook {
+		runArgoCDHook(ctx, opts, resourcesToDelete, hooks)
+		return
+	}
+	if numObjs == 0 {
+		runPhaseHooks(ctx, "afterResources", hooks.AfterResources)
+		removeImages(ctx)
+		reportCompletion(ctx, dynamic, &cleaner.Result{}, nil)
+	} else {
 		// the final object in the resource config must be the spectro-cleanup Pod/DaemonSet/Job
-		if i == numObjs-1 {
-			setOwnerReferences(ctx, client, dynamic, obj)
+		obj := resourcesToDelete[numObjs-1]
+		if err := verifyFinalTenant(ctx, dynamic, obj); err != nil {
+			panic(err)
+		}
+		hash, err := configHash(resourcesToDelete)
+		if err != nil {
+			panic(err)
+		}
 
-			log.Info("Self destructing...", "maxDelaySeconds", cleanupSeconds)
-			select {
-			case <-*notif:
-				log.Info("FinalizeCleanup notification received, self destructing")
-			case <-time.After(time.Duration(cleanupSeconds) * time.Second):
-				log.Info(fmt.Sprintf("%d seconds elapsed, self destructing", cleanupSeconds))
+		// skip straight to self destructing if a prior run already completed the same resource config
+		completed := runStateConfigMap != "" && runCompleted(ctx, client, obj.Namespace, hash)
+		var cp *checkpoint
+		if !completed && checkpointPath != "" {
+			cp, err = loadCheckpoint(checkpointPath, resourcesToDelete)
+			if err != nil {
+				panic(err)
+			}
+			opts.Checkpoint = cp
+		} else if !completed && runRetryAttempts > 0 {
+			// retries skip the entries earlier attempts completed, even if no checkpoint is persisted
+			cp = &checkpoint{}
+			opts.Checkpoint = cp
+		}
+		c := cleaner.New(opts)
+		var result *cleaner.Result
+		if !completed {
+			result, err = cleanupWithRetries(ctx, c, resourcesToDelete[:numObjs-1])
+			logResult("resources", result)
+			if err != nil {
+				exitIfStopped(ctx)
+				reportCompletion(ctx, dynamic, result, err)
+				panic(err)
 			}
+			exitIfStopped(ctx)
 		}
 
-		gvrStr := obj.GroupVersionResource.String()
-		log.Info("Deleting resource", "name", obj.Name, "namespace", obj.Namespace, "gvr", gvrStr)
-		if err := dynamic.Resource(obj.GroupVersionResource).Namespace(obj.Namespace).Delete(
-			ctx, obj.Name, metav1.DeleteOptions{PropagationPolicy: &propagationPolicy},
-		); err != nil {
-			log.Error(err, "resource deletion failed")
-			continue
+		runPhaseHooks(ctx, "afterResources", hooks.AfterResources)
+		removeImages(ctx)
+		reportCompletion(ctx, dynamic, result, nil)
+		ownerRef := setOwnerReferences(ctx, client, dynamic, obj)
+		if runStateConfigMap != "" && !completed {
+			recordRunCompleted(ctx, client, ownerRef, obj.Namespace, hash)
+		}
+
+		log.Info("Self destructing...", "maxDelaySeconds", cleanupSeconds)
+		select {
+		case <-finalCleaner.FinalizeCh():
+			log.Info("FinalizeCleanup notification received, self destructing")
+		case <-time.After(time.Duration(cleanupSeconds) * time.Second):
+			log.Info(fmt.Sprintf("%d seconds elapsed, self destructing", cleanupSeconds))
+		case <-ctx.Done():
+			log.Info("Stopped, self destructing")
+		}
+
+		// spectro-cleanup can't wait for its own deletion, and always self destructs once the wait has begun
+		cp.remove()
+		result, err = finalCleaner.CleanupFinalResource(context.WithoutCancel(ctx), obj)
+		if err != nil && obj.MustDelete && !apierrors.IsNotFound(err) {
+			panic(&cleaner.MustDeleteError{Entry: obj, Err: err})
+		}
+		if skipped := result.Entries[0].Skipped; len(skipped) > 0 {
+			log.Error(errors.New("final resource skipped"), "spectro-cleanup did not self destruct", "skipped", skipped)
 		}
-		log.Info("Resource deletion successful")
 	}
+}
 
-	close(*notif)
-	*notif = nil
+// readFileConfig loads the files specified in the file cleanup config file
+func readFileConfig() []cleaner.FileEntry {
+	bytes := readConfig(fileConfigPath, FilesToDelete)
+	if bytes == nil {
+		return nil
+	}
+	filesToDelete := []cleaner.FileEntry{}
+	if err := json.Unmarshal(bytes, &filesToDelete); err != nil {
+		panic(fmt.Errorf("%w: %w", cleaner.ErrConfigInvalid, err))
+	}
+	filesToDelete, err := cleaner.DefaultPresets.ExpandFiles(filesToDelete)
+	if err != nil {
+		panic(err)
+	}
+	return filesToDelete
+}
+
+// readResourceConfig loads the K8s resources specified in the resource cleanup config file
+func readResourceConfig() []cleaner.DeleteObj {
+	resourcesToDelete := []cleaner.DeleteObj{}
+	bytes := readConfig(resourceConfigPath, ResourcesToDelete)
+	if bytes == nil {
+		return resourcesToDelete
+	}
+	if err := json.Unmarshal(bytes, &resourcesToDelete); err != nil {
+		panic(fmt.Errorf("%w: %w", cleaner.ErrConfigInvalid, err))
+	}
+	resourcesToDelete, err := cleaner.DefaultPresets.ExpandResources(resourcesToDelete)
+	if err != nil {
+		panic(err)
+	}
+	if err := readAliases().ResolveEntries(resourcesToDelete); err != nil {
+		panic(err)
+	}
+	for i := range resourcesToDelete {
+		if confirmHighRisk {
+			resourcesToDelete[i].ConfirmHighRisk = true
+		}
+		if err := resourcesToDelete[i].Validate(); err != nil {
+			panic(err)
+		}
+	}
+	return resourcesToDelete
+}
+
+// runScheduled performs the configured cleanup, including orphan and rule config sweeps, each time the
+// cron schedule fires. spectro-cleanup never self destructs in scheduled mode, so every configured
+// resource is deleted each run.
+func runScheduled(ctx context.Context, client ctrlclient.Client, dynamic dynamic.Interface,
+	metadataClient metadata.Interface, discoveryClient discovery.DiscoveryInterface) {
+	for {
+		next := cleanupSchedule.next(time.Now())
+		if next.IsZero() {
+			panic(fmt.Errorf("cron schedule %q never fires", cleanupScheduleStr))
+		}
+		log.Info("Waiting for next scheduled cleanup", "schedule", cleanupScheduleStr, "next", next)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		if err := checkGuards(ctx, dynamic, readGuards()); err != nil {
+			log.Error(err, "skipping scheduled cleanup, a guard is not met")
+			continue
+		}
+		hooks := readPhaseHooks()
+		runPhaseHooks(ctx, "beforeFiles", hooks.BeforeFiles)
+		if err := cleanupFiles(ctx); err != nil {
+			log.Error(err, "file cleanup failed")
+			runPhaseHooks(ctx, "onFailure", hooks.OnFailure)
+		}
+		runPhaseHooks(ctx, "afterFiles", hooks.AfterFiles)
+		cleanupDanglingWebhooks(ctx, client)
+		cleanupOrphans(ctx, client)
+		pruneReplicaSets(ctx, client)
+		pruneHelmReleases(ctx, client)
+		cleanupUnusedPVCs(ctx, client)
+		cleanupDeletedNodes(ctx, client)
+		cleanupExpiredCertificates(ctx, client, dynamic)
+		if err := cleanupOrphanedAPIServices(ctx, client, dynamic); err != nil {
+			log.Error(err, "orphaned APIService cleanup failed")
+		}
+		if err := runPlugins(ctx); err != nil {
+			log.Error(err, "required cleanup plugin failed")
+		}
+		objs := readResourceConfig()
+		objs = discoverScopes(discoveryClient).resolve(objs)
+		runPhaseHooks(ctx, "beforeResources", hooks.BeforeResources)
+		result, err := cleaner.New(cleanerOptions(client, metadataClient)).CleanupResources(ctx, objs)
+		logResult("resources", result)
+		if err != nil {
+			log.Error(err, "required resource cleanup failed")
+			runPhaseHooks(ctx, "onFailure", hooks.OnFailure)
+		}
+		runPhaseHooks(ctx, "afterResources", hooks.AfterResources)
+		sweepRules(ctx, dynamic)
+		// images are only removed once the workloads using them are deleted
+		if err == nil {
+			removeImages(ctx)
+		}
+		log.Info("Scheduled cleanup complete")
+	}
 }
 
 // setOwnerReferences ensures garbage collection of RBAC resources used by cleanup Pod/DaemonSet/Job post self-destruction
-func setOwnerReferences(ctx context.Context, client ctrlclient.Client, dynamic dynamic.Interface, obj DeleteObj) {
+func setOwnerReferences(ctx context.Context, client ctrlclient.Client, dynamic dynamic.Interface, obj cleaner.DeleteObj) metav1.OwnerReference {
 	owner, err := dynamic.Resource(obj.GroupVersionResource).Namespace(obj.Namespace).Get(ctx, obj.Name, metav1.GetOptions{})
 	if err != nil {
 		panic(err)
@@ -276,13 +1200,16 @@ func setOwnerReferences(ctx context.Context, client ctrlclient.Client, dynamic d
 		panic(err)
 	}
 	log.Info("Set cleanup ownerReference", "roleBinding", roleBindingName)
+	return ownerRef
 }
 
-func startGRPCServer(wg *sync.WaitGroup) {
+// startGRPCServer serves FinalizeCleanup requests until a signal is received, or until ctx is done,
+// i.e., cleanup has completed, and the linger period has elapsed
+func startGRPCServer(ctx context.Context, wg *sync.WaitGroup, c *cleaner.Cleaner) {
 	defer wg.Done()
 
 	mux := http.NewServeMux()
-	path, handler := cleanupv1connect.NewCleanupServiceHandler(&cleanupServiceServer{})
+	path, handler := cleanupv1connect.NewCleanupServiceHandler(&cleanupServiceServer{cleaner: c})
 	mux.Handle(path, handler)
 	address := fmt.Sprintf("0.0.0.0:%s", grpcPortStr)
 	server := &http.Server{
@@ -301,11 +1228,22 @@ func startGRPCServer(wg *sync.WaitGroup) {
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
-	<-stop
+	defer signal.Stop(stop)
+	select {
+	case <-stop:
+	case <-ctx.Done():
+		if grpcLinger > 0 {
+			log.Info("Cleanup complete, gRPC server lingering before shutdown", "linger", grpcLinger.String())
+			select {
+			case <-stop:
+			case <-time.After(grpcLinger):
+			}
+		}
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
 	defer cancel()
-	if err := server.Shutdown(ctx); err != nil {
+	if err := server.Shutdown(shutdownCtx); err != nil {
 		log.Error(err, "Error while shutting down gRPC server")
 		return
 	}
@@ -316,6 +1254,9 @@ func startGRPCServer(wg *sync.WaitGroup) {
 // cleanupServiceServer implements the CleanupService API.
 type cleanupServiceServer struct {
 	cleanupv1connect.UnimplementedCleanupServiceHandler
+
+	// cleaner is finalized by FinalizeCleanup requests
+	cleaner *cleaner.Cleaner
 }
 
 // FinalizeCleanup notifies spectro-cleanup that it can now self destruct.
@@ -324,12 +1265,8 @@ func (s *cleanupServiceServer) FinalizeCleanup(
 	req *connect.Request[cleanv1.FinalizeCleanupRequest],
 ) (*connect.Response[cleanv1.FinalizeCleanupResponse], error) {
 	log.Info("Received request to FinalizeCleanup")
-	if *notif == nil {
-		err := ErrIllegalCleanupNotification
-		log.Error(err, "nil notification channel")
-		return connect.NewResponse(&cleanv1.FinalizeCleanupResponse{}), err
-	}
-
-	*notif <- true
+	// a request received before the self destruct wait is retained, so that callers racing
+	// spectro-cleanup's startup needn't retry
+	s.cleaner.Finalize()
 	return connect.NewResponse(&cleanv1.FinalizeCleanupResponse{}), nil
 }
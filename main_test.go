@@ -3,11 +3,29 @@ package main
 import (
 	"context"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
 	cleanv1 "buf.build/gen/go/spectrocloud/spectro-cleanup/protocolbuffers/go/cleanup/v1"
 	"connectrpc.com/connect"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	clienttesting "k8s.io/client-go/testing"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
 )
 
 func TestInitConfig(t *testing.T) {
@@ -157,50 +175,191 @@ func TestReadConfig(t *testing.T) {
 }
 
 func TestFinalizeCleanup(t *testing.T) {
-	server := &cleanupServiceServer{}
 	ctx := context.TODO()
 	req := connect.NewRequest(&cleanv1.FinalizeCleanupRequest{})
 
 	tests := []struct {
-		name        string
-		testChan    chan bool
-		expectedErr error
+		name     string
+		requests int
 	}{
 		{
-			name:     "valid notification channel",
-			testChan: make(chan bool),
+			name:     "single request",
+			requests: 1,
 		},
 		{
-			name:        "nil notification channel",
-			testChan:    nil,
-			expectedErr: ErrIllegalCleanupNotification,
+			name:     "repeated requests",
+			requests: 2,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			notif = &tt.testChan
-
-			go func() {
-				<-time.After(1 * time.Second)
-				<-tt.testChan
-				close(tt.testChan)
-			}()
-
-			resp, err := server.FinalizeCleanup(ctx, req)
-			if err != nil && tt.expectedErr == nil {
-				t.Fatalf("expected no error, got %v", err)
+			server := &cleanupServiceServer{cleaner: cleaner.New(cleaner.Options{})}
+			other := cleaner.New(cleaner.Options{})
+			for i := 0; i < tt.requests; i++ {
+				resp, err := server.FinalizeCleanup(ctx, req)
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				if resp == nil {
+					t.Fatalf("expected response, got nil")
+				}
 			}
-			if err == nil && tt.expectedErr != nil {
-				t.Fatalf("expected error %v, got nil", tt.expectedErr)
+
+			// the notification is retained until the self destruct wait begins
+			select {
+			case <-server.cleaner.FinalizeCh():
+			case <-time.After(100 * time.Millisecond):
+				t.Errorf("expected the server's cleaner to be finalized")
 			}
-			if err != nil && tt.expectedErr != nil && err.Error() != tt.expectedErr.Error() {
-				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			select {
+			case <-other.FinalizeCh():
+				t.Errorf("expected other cleaners not to be finalized")
+			default:
 			}
+		})
+	}
+}
 
-			if resp == nil {
-				t.Fatalf("expected response, got nil")
+func TestStartGRPCServerShutdown(t *testing.T) {
+	tests := []struct {
+		name   string
+		linger time.Duration
+	}{
+		{
+			name: "no linger",
+		},
+		{
+			name:   "linger",
+			linger: 100 * time.Millisecond,
+		},
+	}
+
+	defaultPort, defaultLinger := grpcPortStr, grpcLinger
+	defer func() { grpcPortStr, grpcLinger = defaultPort, defaultLinger }()
+	grpcPortStr = "0"
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grpcLinger = tt.linger
+			ctx, cancel := context.WithCancel(context.Background())
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go startGRPCServer(ctx, &wg, cleaner.New(cleaner.Options{}))
+
+			start := time.Now()
+			cancel()
+			done := make(chan struct{})
+			go func() {
+				wg.Wait()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("expected gRPC server to shut down after cleanup completed")
+			}
+			if elapsed := time.Since(start); elapsed < tt.linger {
+				t.Errorf("expected gRPC server to linger for %v, got %v", tt.linger, elapsed)
 			}
 		})
 	}
 }
+
+// fakeClientFactory returns the fake API clients of a test cluster
+type fakeClientFactory clients
+
+func (f *fakeClientFactory) clients() (*clients, error) {
+	return (*clients)(f), nil
+}
+
+func TestRun(t *testing.T) {
+	defaultFiles, defaultResources, defaultHooks, defaultPlugins := fileConfigPath, resourceConfigPath, hookConfigPath, pluginConfigPath
+	defaultCleanupSeconds := cleanupSeconds
+	defer func() {
+		fileConfigPath, resourceConfigPath, hookConfigPath, pluginConfigPath = defaultFiles, defaultResources, defaultHooks, defaultPlugins
+		cleanupSeconds = defaultCleanupSeconds
+	}()
+
+	dir := t.TempDir()
+	fileConfigPath = filepath.Join(dir, "file-config.json")
+	resourceConfigPath = filepath.Join(dir, "resource-config.json")
+	hookConfigPath = filepath.Join(dir, "hook-config.json")
+	pluginConfigPath = filepath.Join(dir, "plugin-config.json")
+	cleanupSeconds = 0
+	config := `[
+		{"version": "v1", "resource": "configmaps", "name": "spectro-cleanup-config", "namespace": "default"},
+		{"group": "batch", "version": "v1", "resource": "jobs", "name": "spectro-cleanup", "namespace": "default"}
+	]`
+	if err := os.WriteFile(resourceConfigPath, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	meta := func(apiVersion, kind, name string) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: apiVersion, Kind: kind},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID("uid-" + name)},
+		}
+	}
+	metadataScheme := runtime.NewScheme()
+	for _, gvk := range []schema.GroupVersionKind{
+		{Version: "v1", Kind: "ConfigMap"}, {Version: "v1", Kind: "ConfigMapList"},
+		{Group: "batch", Version: "v1", Kind: "Job"}, {Group: "batch", Version: "v1", Kind: "JobList"},
+	} {
+		metadataScheme.AddKnownTypeWithName(gvk, &metav1.PartialObjectMetadata{})
+	}
+	job := &unstructured.Unstructured{}
+	job.SetAPIVersion("batch/v1")
+	job.SetKind("Job")
+	job.SetName("spectro-cleanup")
+	job.SetNamespace("default")
+	job.SetUID("uid-spectro-cleanup")
+
+	objMeta := metav1.ObjectMeta{Namespace: "default"}
+	objMeta.Name = saName
+	sa := &corev1.ServiceAccount{ObjectMeta: objMeta}
+	objMeta.Name = roleName
+	role := &rbacv1.Role{ObjectMeta: objMeta}
+	objMeta.Name = roleBindingName
+	rb := &rbacv1.RoleBinding{ObjectMeta: objMeta}
+
+	factory := &fakeClientFactory{
+		client:    fake.NewClientBuilder().WithScheme(scheme).WithObjects(sa, role, rb).Build(),
+		dynamic:   dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), job),
+		metadata:  metadatafake.NewSimpleMetadataClient(metadataScheme, meta("v1", "ConfigMap", "spectro-cleanup-config"), meta("batch/v1", "Job", "spectro-cleanup")),
+		discovery: &discoveryfake.FakeDiscovery{Fake: &clienttesting.Fake{}},
+	}
+	run(context.Background(), factory)
+
+	configMaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	jobs := schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"}
+	for gvr, name := range map[schema.GroupVersionResource]string{configMaps: "spectro-cleanup-config", jobs: "spectro-cleanup"} {
+		_, err := factory.metadata.Resource(gvr).Namespace("default").Get(context.Background(), name, metav1.GetOptions{})
+		if !apierrors.IsNotFound(err) {
+			t.Errorf("expected %s %s to be deleted, got %v", gvr.Resource, name, err)
+		}
+	}
+	if err := factory.client.Get(context.Background(), ctrlclient.ObjectKeyFromObject(sa), sa); err != nil {
+		t.Fatal(err)
+	}
+	if len(sa.OwnerReferences) != 1 || sa.OwnerReferences[0].UID != job.GetUID() {
+		t.Errorf("expected ownerReference to the Job, got %v", sa.OwnerReferences)
+	}
+}
+
+func TestCleanupFilesReturnsFailure(t *testing.T) {
+	defaultFiles, defaultArchiveDir := fileConfigPath, fileArchiveDir
+	defer func() { fileConfigPath, fileArchiveDir = defaultFiles, defaultArchiveDir }()
+
+	dir := t.TempDir()
+	fileConfigPath = filepath.Join(dir, "file-config.json")
+	if err := os.WriteFile(fileConfigPath, []byte(`["`+filepath.Join(dir, "a")+`"]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// the archive can't be created beneath a regular file, failing the file cleanup
+	fileArchiveDir = filepath.Join(fileConfigPath, "archive")
+
+	if err := cleanupFiles(context.Background()); err == nil {
+		t.Error("expected the file cleanup to fail, got nil")
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// cleanupDeletedNodes deletes Node objects whose machine has been removed, i.e., Nodes that have been
+// NotReady for at least nodeNotReadySeconds and whose providerID matches one of nodeProviderIDs
+func cleanupDeletedNodes(ctx context.Context, client ctrlclient.Client) {
+	if len(nodeProviderIDs) == 0 {
+		return
+	}
+
+	log.Info("Searching for Nodes of deleted machines", "providerIDs", nodeProviderIDs, "notReadySeconds", nodeNotReadySeconds)
+	nodes := &corev1.NodeList{}
+	if err := client.List(ctx, nodes); err != nil {
+		log.Error(err, "failed to list Nodes")
+		return
+	}
+
+	now := time.Now()
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if node.DeletionTimestamp != nil || !matchesProviderID(node.Spec.ProviderID, nodeProviderIDs) {
+			continue
+		}
+		notReadyFor, ok := notReadyDuration(node, now)
+		if !ok || notReadyFor < time.Duration(nodeNotReadySeconds)*time.Second || !policyAllowsObject(ctx, client, node) {
+			continue
+		}
+
+		log.Info("Deleting Node", "node", node.Name, "providerID", node.Spec.ProviderID, "notReadyFor", notReadyFor.Round(time.Second).String())
+		if err := retryMutation(ctx, func(ctx context.Context) error { return client.Delete(ctx, node) }); ctrlclient.IgnoreNotFound(err) != nil {
+			log.Error(err, "Node deletion failed", "node", node.Name)
+			continue
+		}
+		log.Info("Node deletion successful", "node", node.Name)
+	}
+}
+
+// notReadyDuration returns how long a Node's Ready condition has not been True,
+// or false if the Node is Ready or has no Ready condition
+func notReadyDuration(node *corev1.Node, now time.Time) (time.Duration, bool) {
+	for _, c := range node.Status.Conditions {
+		if c.Type != corev1.NodeReady {
+			continue
+		}
+		if c.Status == corev1.ConditionTrue {
+			return 0, false
+		}
+		return now.Sub(c.LastTransitionTime.Time), true
+	}
+	return 0, false
+}
+
+// matchesProviderID reports whether a providerID equals any of the patterns, or starts
+// with any pattern ending in '*', e.g., "metal3://*". Empty providerIDs never match.
+func matchesProviderID(providerID string, patterns []string) bool {
+	if providerID == "" {
+		return false
+	}
+	for _, p := range patterns {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(providerID, prefix) {
+				return true
+			}
+		} else if providerID == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMatchesProviderID(t *testing.T) {
+	patterns := []string{"metal3://*", "maas://edge-01"}
+
+	tests := []struct {
+		providerID string
+		expected   bool
+	}{
+		{providerID: "metal3://default/worker-1/4f2c", expected: true},
+		{providerID: "maas://edge-01", expected: true},
+		{providerID: "maas://edge-02", expected: false},
+		{providerID: "aws:///us-east-1a/i-0123", expected: false},
+		{providerID: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.providerID, func(t *testing.T) {
+			if matches := matchesProviderID(tt.providerID, patterns); matches != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, matches)
+			}
+		})
+	}
+}
+
+func TestNotReadyDuration(t *testing.T) {
+	now := time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)
+	node := func(status corev1.ConditionStatus) *corev1.Node {
+		return &corev1.Node{Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+			{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse},
+			{Type: corev1.NodeReady, Status: status, LastTransitionTime: metav1.NewTime(now.Add(-2 * time.Hour))},
+		}}}
+	}
+
+	tests := []struct {
+		name       string
+		node       *corev1.Node
+		expected   time.Duration
+		expectedOk bool
+	}{
+		{
+			name:       "ready",
+			node:       node(corev1.ConditionTrue),
+			expectedOk: false,
+		},
+		{
+			name:       "not ready",
+			node:       node(corev1.ConditionFalse),
+			expected:   2 * time.Hour,
+			expectedOk: true,
+		},
+		{
+			name:       "unknown",
+			node:       node(corev1.ConditionUnknown),
+			expected:   2 * time.Hour,
+			expectedOk: true,
+		},
+		{
+			name:       "no ready condition",
+			node:       &corev1.Node{},
+			expectedOk: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, ok := notReadyDuration(tt.node, now)
+			if ok != tt.expectedOk {
+				t.Fatalf("expected ok %v, got %v", tt.expectedOk, ok)
+			}
+			if d != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, d)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/client-go/rest"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+// validateOffline verifies, at startup, that nothing configured requires network egress other than to
+// the API server, so that an offline cleanup in an air-gapped environment fails up front rather than
+// hanging or failing midway through. Phase hooks and plugins that don't declare network access are trusted.
+func validateOffline(config *rest.Config, plugins []PluginEntry) error {
+	var egress []string
+	if config.ExecProvider != nil {
+		egress = append(egress, fmt.Sprintf("the kubeconfig's exec credential plugin %s", config.ExecProvider.Command))
+	}
+	if config.AuthProvider != nil {
+		egress = append(egress, fmt.Sprintf("the kubeconfig's %s auth provider", config.AuthProvider.Name))
+	}
+	if policyOPAURL != "" {
+		egress = append(egress, "the OPA policy CLEANUP_POLICY_OPA_URL")
+	}
+	for _, p := range plugins {
+		if p.Network {
+			egress = append(egress, fmt.Sprintf("plugin %s", p.Plugin))
+		}
+	}
+	if len(egress) > 0 {
+		return fmt.Errorf("%w: offline mode forbids network egress, required by %s", cleaner.ErrConfigInvalid, strings.Join(egress, ", "))
+	}
+	log.Info("Offline mode: no network egress configured")
+	return nil
+}
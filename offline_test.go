@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+func TestValidateOffline(t *testing.T) {
+	defaultPolicyOPAURL := policyOPAURL
+	defer func() { policyOPAURL = defaultPolicyOPAURL }()
+
+	tests := []struct {
+		name        string
+		config      *rest.Config
+		opaURL      string
+		plugins     []PluginEntry
+		expectedErr bool
+	}{
+		{
+			name:    "offline",
+			config:  &rest.Config{BearerToken: "token"},
+			plugins: []PluginEntry{{Plugin: "db"}},
+		},
+		{
+			name:        "exec credential plugin",
+			config:      &rest.Config{ExecProvider: &clientcmdapi.ExecConfig{Command: "aws"}},
+			expectedErr: true,
+		},
+		{
+			name:        "OPA policy",
+			config:      &rest.Config{},
+			opaURL:      "http://opa:8181/v1/data/spectro_cleanup/delete",
+			expectedErr: true,
+		},
+		{
+			name:        "network plugin",
+			config:      &rest.Config{},
+			plugins:     []PluginEntry{{Plugin: "db"}, {Plugin: "aws-enis", Network: true}},
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policyOPAURL = tt.opaURL
+			err := validateOffline(tt.config, tt.plugins)
+			if errors.Is(err, cleaner.ErrConfigInvalid) != tt.expectedErr {
+				t.Errorf("expected error %v, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// rootCAConfigMap is published into every namespace by kube-controller-manager
+const rootCAConfigMap = "kube-root-ca.crt"
+
+// secret types managed by K8s or other tooling, which are never considered orphaned
+var ignoredSecretTypes = sets.New[corev1.SecretType](
+	corev1.SecretTypeServiceAccountToken,
+	corev1.SecretTypeBootstrapToken,
+	"helm.sh/release.v1",
+)
+
+// orphanRefs holds the names of the ConfigMaps and Secrets referenced within a namespace
+type orphanRefs struct {
+	configMaps sets.Set[string]
+	secrets    sets.Set[string]
+}
+
+// cleanupOrphans reports, and optionally deletes, the ConfigMaps and Secrets in each target namespace
+// that are neither owned by another object nor referenced by any workload, ServiceAccount or Ingress
+func cleanupOrphans(ctx context.Context, client ctrlclient.Client) {
+	for _, ns := range orphanNamespaces {
+		log.Info("Searching for orphaned ConfigMaps and Secrets", "namespace", ns)
+		refs, err := collectRefs(ctx, client, ns)
+		if err != nil {
+			log.Error(err, "failed to collect ConfigMap and Secret references", "namespace", ns)
+			continue
+		}
+
+		cms := &corev1.ConfigMapList{}
+		if err := client.List(ctx, cms, ctrlclient.InNamespace(ns)); err != nil {
+			log.Error(err, "failed to list ConfigMaps", "namespace", ns)
+			continue
+		}
+		for i := range cms.Items {
+			cm := &cms.Items[i]
+			if cm.Name == rootCAConfigMap || len(cm.OwnerReferences) > 0 || refs.configMaps.Has(cm.Name) {
+				continue
+			}
+			handleOrphan(ctx, client, cm, "configMap")
+		}
+
+		secrets := &corev1.SecretList{}
+		if err := client.List(ctx, secrets, ctrlclient.InNamespace(ns)); err != nil {
+			log.Error(err, "failed to list Secrets", "namespace", ns)
+			continue
+		}
+		for i := range secrets.Items {
+			secret := &secrets.Items[i]
+			if ignoredSecretTypes.Has(secret.Type) || len(secret.OwnerReferences) > 0 || refs.secrets.Has(secret.Name) {
+				continue
+			}
+			handleOrphan(ctx, client, secret, "secret")
+		}
+	}
+}
+
+// handleOrphan logs an orphaned object, deleting it if orphan deletion is enabled
+func handleOrphan(ctx context.Context, client ctrlclient.Client, obj ctrlclient.Object, kind string) {
+	log.Info("Found orphaned object", kind, obj.GetName(), "namespace", obj.GetNamespace())
+	if !deleteOrphans || !policyAllowsObject(ctx, client, obj) {
+		return
+	}
+	if err := retryMutation(ctx, func(ctx context.Context) error { return client.Delete(ctx, obj) }); ctrlclient.IgnoreNotFound(err) != nil {
+		log.Error(err, "orphan deletion failed", kind, obj.GetName(), "namespace", obj.GetNamespace())
+		return
+	}
+	log.Info("Orphan deletion successful", kind, obj.GetName(), "namespace", obj.GetNamespace())
+}
+
+// collectRefs gathers every ConfigMap and Secret referenced by the Pods, workload templates,
+// ServiceAccounts and Ingresses in a namespace
+func collectRefs(ctx context.Context, client ctrlclient.Client, ns string) (*orphanRefs, error) {
+	refs := &orphanRefs{configMaps: sets.New[string](), secrets: sets.New[string]()}
+	inNs := ctrlclient.InNamespace(ns)
+
+	pods := &corev1.PodList{}
+	if err := client.List(ctx, pods, inNs); err != nil {
+		return nil, err
+	}
+	for i := range pods.Items {
+		refs.addPodSpec(&pods.Items[i].Spec)
+	}
+
+	deployments := &appsv1.DeploymentList{}
+	if err := client.List(ctx, deployments, inNs); err != nil {
+		return nil, err
+	}
+	for i := range deployments.Items {
+		refs.addPodSpec(&deployments.Items[i].Spec.Template.Spec)
+	}
+
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := client.List(ctx, statefulSets, inNs); err != nil {
+		return nil, err
+	}
+	for i := range statefulSets.Items {
+		refs.addPodSpec(&statefulSets.Items[i].Spec.Template.Spec)
+	}
+
+	daemonSets := &appsv1.DaemonSetList{}
+	if err := client.List(ctx, daemonSets, inNs); err != nil {
+		return nil, err
+	}
+	for i := range daemonSets.Items {
+		refs.addPodSpec(&daemonSets.Items[i].Spec.Template.Spec)
+	}
+
+	jobs := &batchv1.JobList{}
+	if err := client.List(ctx, jobs, inNs); err != nil {
+		return nil, err
+	}
+	for i := range jobs.Items {
+		refs.addPodSpec(&jobs.Items[i].Spec.Template.Spec)
+	}
+
+	cronJobs := &batchv1.CronJobList{}
+	if err := client.List(ctx, cronJobs, inNs); err != nil {
+		return nil, err
+	}
+	for i := range cronJobs.Items {
+		refs.addPodSpec(&cronJobs.Items[i].Spec.JobTemplate.Spec.Template.Spec)
+	}
+
+	serviceAccounts := &corev1.ServiceAccountList{}
+	if err := client.List(ctx, serviceAccounts, inNs); err != nil {
+		return nil, err
+	}
+	for _, sa := range serviceAccounts.Items {
+		for _, s := range sa.Secrets {
+			refs.secrets.Insert(s.Name)
+		}
+		for _, s := range sa.ImagePullSecrets {
+			refs.secrets.Insert(s.Name)
+		}
+	}
+
+	ingresses := &networkingv1.IngressList{}
+	if err := client.List(ctx, ingresses, inNs); err != nil {
+		return nil, err
+	}
+	for _, ing := range ingresses.Items {
+		for _, tls := range ing.Spec.TLS {
+			refs.secrets.Insert(tls.SecretName)
+		}
+	}
+
+	return refs, nil
+}
+
+// addPodSpec records the ConfigMaps and Secrets referenced by a Pod spec's volumes, environment and image pull secrets
+func (r *orphanRefs) addPodSpec(spec *corev1.PodSpec) {
+	for _, s := range spec.ImagePullSecrets {
+		r.secrets.Insert(s.Name)
+	}
+
+	for _, v := range spec.Volumes {
+		if v.ConfigMap != nil {
+			r.configMaps.Insert(v.ConfigMap.Name)
+		}
+		if v.Secret != nil {
+			r.secrets.Insert(v.Secret.SecretName)
+		}
+		if v.Projected != nil {
+			for _, src := range v.Projected.Sources {
+				if src.ConfigMap != nil {
+					r.configMaps.Insert(src.ConfigMap.Name)
+				}
+				if src.Secret != nil {
+					r.secrets.Insert(src.Secret.Name)
+				}
+			}
+		}
+	}
+
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, c := range containers {
+		for _, from := range c.EnvFrom {
+			if from.ConfigMapRef != nil {
+				r.configMaps.Insert(from.ConfigMapRef.Name)
+			}
+			if from.SecretRef != nil {
+				r.secrets.Insert(from.SecretRef.Name)
+			}
+		}
+		for _, env := range c.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if env.ValueFrom.ConfigMapKeyRef != nil {
+				r.configMaps.Insert(env.ValueFrom.ConfigMapKeyRef.Name)
+			}
+			if env.ValueFrom.SecretKeyRef != nil {
+				r.secrets.Insert(env.ValueFrom.SecretKeyRef.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func TestAddPodSpec(t *testing.T) {
+	spec := &corev1.PodSpec{
+		ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry-creds"}},
+		Volumes: []corev1.Volume{
+			{Name: "config", VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"}},
+			}},
+			{Name: "certs", VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: "app-certs"},
+			}},
+			{Name: "projected", VolumeSource: corev1.VolumeSource{
+				Projected: &corev1.ProjectedVolumeSource{Sources: []corev1.VolumeProjection{
+					{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: corev1.LocalObjectReference{Name: "projected-config"}}},
+					{Secret: &corev1.SecretProjection{LocalObjectReference: corev1.LocalObjectReference{Name: "projected-secret"}}},
+				}},
+			}},
+		},
+		InitContainers: []corev1.Container{{
+			Name: "init",
+			EnvFrom: []corev1.EnvFromSource{
+				{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "init-env"}}},
+			},
+		}},
+		Containers: []corev1.Container{{
+			Name: "app",
+			EnvFrom: []corev1.EnvFromSource{
+				{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app-env"}}},
+			},
+			Env: []corev1.EnvVar{
+				{Name: "PLAIN", Value: "value"},
+				{Name: "LOG_LEVEL", ValueFrom: &corev1.EnvVarSource{
+					ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "log-config"}, Key: "level"},
+				}},
+				{Name: "PASSWORD", ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "db-creds"}, Key: "password"},
+				}},
+			},
+		}},
+	}
+
+	refs := &orphanRefs{configMaps: sets.New[string](), secrets: sets.New[string]()}
+	refs.addPodSpec(spec)
+
+	expectedConfigMaps := []string{"app-config", "init-env", "log-config", "projected-config"}
+	expectedSecrets := []string{"app-certs", "app-env", "db-creds", "projected-secret", "registry-creds"}
+	if got := sets.List(refs.configMaps); !reflect.DeepEqual(got, expectedConfigMaps) {
+		t.Errorf("expected configMaps %v, got %v", expectedConfigMaps, got)
+	}
+	if got := sets.List(refs.secrets); !reflect.DeepEqual(got, expectedSecrets) {
+		t.Errorf("expected secrets %v, got %v", expectedSecrets, got)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/internal/command"
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+const PhaseHooksToRun = "phaseHooksToRun"
+
+// PhaseHook is a command run at a boundary of a cleanup phase, e.g., to notify an agent or flush a cache
+type PhaseHook struct {
+	// Command is an argv list, e.g., ["systemctl", "restart", "containerd"]
+	Command []string `json:"command"`
+
+	// TimeoutSeconds overrides CLEANUP_COMMAND_TIMEOUT_SECONDS for the hook
+	TimeoutSeconds int64 `json:"timeoutSeconds,omitempty"`
+}
+
+// PhaseHooks are the commands run before and after the file and resource cleanup phases, and
+// when the cleanup fails. Each list is run in order, and a failing command never fails the cleanup.
+type PhaseHooks struct {
+	BeforeFiles     []PhaseHook `json:"beforeFiles,omitempty"`
+	AfterFiles      []PhaseHook `json:"afterFiles,omitempty"`
+	BeforeResources []PhaseHook `json:"beforeResources,omitempty"`
+	AfterResources  []PhaseHook `json:"afterResources,omitempty"`
+	OnFailure       []PhaseHook `json:"onFailure,omitempty"`
+}
+
+// readPhaseHooks loads the commands specified in the phase hook config file
+func readPhaseHooks() PhaseHooks {
+	hooks := PhaseHooks{}
+	bytes := readConfig(hookConfigPath, PhaseHooksToRun)
+	if bytes == nil {
+		return hooks
+	}
+	if err := json.Unmarshal(bytes, &hooks); err != nil {
+		panic(fmt.Errorf("%w: %w", cleaner.ErrConfigInvalid, err))
+	}
+	for _, hook := range slices.Concat(hooks.BeforeFiles, hooks.AfterFiles, hooks.BeforeResources, hooks.AfterResources, hooks.OnFailure) {
+		if len(hook.Command) == 0 {
+			panic(fmt.Errorf("%w: phase hook: %w", cleaner.ErrConfigInvalid, command.ErrEmpty))
+		}
+	}
+	return hooks
+}
+
+// runPhaseHooks runs a phase's hooks in order. Their output is logged, and failures are logged
+// without failing the cleanup.
+func runPhaseHooks(ctx context.Context, phase string, hooks []PhaseHook) {
+	for _, hook := range hooks {
+		timeout := cmdTimeout
+		if hook.TimeoutSeconds > 0 {
+			timeout = time.Duration(hook.TimeoutSeconds) * time.Second
+		}
+		log.Info("Running phase hook", "phase", phase, "command", hook.Command)
+		if err := command.Run(ctx, timeout, hook.Command); err != nil {
+			log.Error(err, "phase hook failed", "phase", phase, "command", hook.Command)
+		}
+	}
+}
+
+// runFailureHooks runs the onFailure hooks if the cleanup panics, i.e., fails, before propagating
+// the panic. It must be deferred.
+func runFailureHooks(ctx context.Context, hooks PhaseHooks) {
+	if r := recover(); r != nil {
+		runPhaseHooks(context.WithoutCancel(ctx), "onFailure", hooks.OnFailure)
+		panic(r)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadPhaseHooks(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        string
+		expectedHooks int
+		expectedPanic bool
+	}{
+		{
+			name:          "valid hooks",
+			config:        `{"beforeFiles": [{"command": ["systemctl", "stop", "kubelet"]}], "onFailure": [{"command": ["true"], "timeoutSeconds": 5}]}`,
+			expectedHooks: 2,
+		},
+		{
+			name:          "empty command",
+			config:        `{"afterResources": [{"command": []}]}`,
+			expectedPanic: true,
+		},
+		{
+			name:          "malformed config",
+			config:        `{"beforeFiles": "true"}`,
+			expectedPanic: true,
+		},
+	}
+
+	defaultPath := hookConfigPath
+	defer func() { hookConfigPath = defaultPath }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hookConfigPath = filepath.Join(t.TempDir(), "hook-config.json")
+			if err := os.WriteFile(hookConfigPath, []byte(tt.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				if r := recover(); (r != nil) != tt.expectedPanic {
+					t.Errorf("expected panic %v, got %v", tt.expectedPanic, r)
+				}
+			}()
+
+			hooks := readPhaseHooks()
+			count := len(hooks.BeforeFiles) + len(hooks.AfterFiles) + len(hooks.BeforeResources) + len(hooks.AfterResources) + len(hooks.OnFailure)
+			if count != tt.expectedHooks {
+				t.Errorf("expected %d hooks, got %d", tt.expectedHooks, count)
+			}
+		})
+	}
+}
+
+func TestRunFailureHooks(t *testing.T) {
+	tests := []struct {
+		name     string
+		fail     bool
+		expected bool
+	}{
+		{
+			name:     "cleanup failed",
+			fail:     true,
+			expected: true,
+		},
+		{
+			name: "cleanup succeeded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			marker := filepath.Join(t.TempDir(), "failed")
+			hooks := PhaseHooks{OnFailure: []PhaseHook{{Command: []string{"touch", marker}}}}
+
+			func() {
+				defer func() {
+					if r := recover(); (r != nil) != tt.fail {
+						t.Errorf("expected panic %v, got %v", tt.fail, r)
+					}
+				}()
+				defer runFailureHooks(context.Background(), hooks)
+				if tt.fail {
+					panic("cleanup failed")
+				}
+			}()
+
+			_, err := os.Stat(marker)
+			if (err == nil) != tt.expected {
+				t.Errorf("expected onFailure hook run %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
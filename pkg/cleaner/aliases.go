@@ -0,0 +1,96 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Aliases map shorthand names, e.g., "deploy", to the resources they stand for, so that config
+// entries may name a resource without its group and version
+type Aliases map[string]schema.GroupVersionResource
+
+// DefaultAliases are short names of common built-in resources, mostly as used by kubectl
+var DefaultAliases = Aliases{
+	"cm":       {Version: "v1", Resource: "configmaps"},
+	"secret":   {Version: "v1", Resource: "secrets"},
+	"sa":       {Version: "v1", Resource: "serviceaccounts"},
+	"svc":      {Version: "v1", Resource: "services"},
+	"po":       {Version: "v1", Resource: "pods"},
+	"pvc":      {Version: "v1", Resource: "persistentvolumeclaims"},
+	"pv":       {Version: "v1", Resource: "persistentvolumes"},
+	"ns":       {Version: "v1", Resource: "namespaces"},
+	"deploy":   {Group: "apps", Version: "v1", Resource: "deployments"},
+	"ds":       {Group: "apps", Version: "v1", Resource: "daemonsets"},
+	"sts":      {Group: "apps", Version: "v1", Resource: "statefulsets"},
+	"rs":       {Group: "apps", Version: "v1", Resource: "replicasets"},
+	"job":      {Group: "batch", Version: "v1", Resource: "jobs"},
+	"cj":       {Group: "batch", Version: "v1", Resource: "cronjobs"},
+	"ing":      {Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"},
+	"netpol":   {Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"},
+	"role":     {Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "roles"},
+	"rb":       {Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "rolebindings"},
+	"cr":       {Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"},
+	"crb":      {Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterrolebindings"},
+	"pdb":      {Group: "policy", Version: "v1", Resource: "poddisruptionbudgets"},
+	"hpa":      {Group: "autoscaling", Version: "v2", Resource: "horizontalpodautoscalers"},
+	"crd":      {Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"},
+	"mwc":      {Group: "admissionregistration.k8s.io", Version: "v1", Resource: "mutatingwebhookconfigurations"},
+	"vwc":      {Group: "admissionregistration.k8s.io", Version: "v1", Resource: "validatingwebhookconfigurations"},
+	"lease":    {Group: "coordination.k8s.io", Version: "v1", Resource: "leases"},
+	"sc":       {Group: "storage.k8s.io", Version: "v1", Resource: "storageclasses"},
+	"pc":       {Group: "scheduling.k8s.io", Version: "v1", Resource: "priorityclasses"},
+	"ev":       {Version: "v1", Resource: "events"},
+	"ep":       {Version: "v1", Resource: "endpoints"},
+	"limits":   {Version: "v1", Resource: "limitranges"},
+	"quota":    {Version: "v1", Resource: "resourcequotas"},
+	"no":       {Version: "v1", Resource: "nodes"},
+	"csr":      {Group: "certificates.k8s.io", Version: "v1", Resource: "certificatesigningrequests"},
+	"ingclass": {Group: "networking.k8s.io", Version: "v1", Resource: "ingressclasses"},
+}
+
+// Resolve returns the resource gvr stands for. A gvr with a version is returned unchanged. Otherwise,
+// its resource must be an alias, looked up in a, and then in DefaultAliases.
+func (a Aliases) Resolve(gvr schema.GroupVersionResource) (schema.GroupVersionResource, error) {
+	if gvr.Version != "" {
+		return gvr, nil
+	}
+	if gvr.Group == "" {
+		if resolved, ok := a[gvr.Resource]; ok {
+			return resolved, nil
+		}
+		if resolved, ok := DefaultAliases[gvr.Resource]; ok {
+			return resolved, nil
+		}
+	}
+	return gvr, fmt.Errorf("%w: resource %q has no version and is not a known alias", ErrConfigInvalid, gvr.String())
+}
+
+// ResolveEntries resolves the aliased resources of entries in place, except those setting AllResources or Manifests
+func (a Aliases) ResolveEntries(entries []DeleteObj) error {
+	for i := range entries {
+		if entries[i].AllResources || entries[i].Manifests != "" {
+			continue
+		}
+		gvr, err := a.Resolve(entries[i].GroupVersionResource)
+		if err != nil {
+			return err
+		}
+		entries[i].GroupVersionResource = gvr
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package cleaner
+
+import (
+	"errors"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestAliasesResolve(t *testing.T) {
+	widgets := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	aliases := Aliases{
+		"widget": widgets,
+		"cm":     {Group: "example.com", Version: "v1", Resource: "configmanagers"},
+	}
+
+	tests := []struct {
+		name          string
+		gvr           schema.GroupVersionResource
+		expected      schema.GroupVersionResource
+		expectedError error
+	}{
+		{
+			name:     "fully qualified",
+			gvr:      schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deploy"},
+			expected: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deploy"},
+		},
+		{
+			name:     "default alias",
+			gvr:      schema.GroupVersionResource{Resource: "deploy"},
+			expected: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+		},
+		{
+			name:     "custom alias",
+			gvr:      schema.GroupVersionResource{Resource: "widget"},
+			expected: widgets,
+		},
+		{
+			name:     "custom alias overrides default",
+			gvr:      schema.GroupVersionResource{Resource: "cm"},
+			expected: schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "configmanagers"},
+		},
+		{
+			name:          "unknown alias",
+			gvr:           schema.GroupVersionResource{Resource: "gadget"},
+			expected:      schema.GroupVersionResource{Resource: "gadget"},
+			expectedError: ErrConfigInvalid,
+		},
+		{
+			name:          "group without version",
+			gvr:           schema.GroupVersionResource{Group: "apps", Resource: "deploy"},
+			expected:      schema.GroupVersionResource{Group: "apps", Resource: "deploy"},
+			expectedError: ErrConfigInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := aliases.Resolve(tt.gvr)
+			if !errors.Is(err, tt.expectedError) {
+				t.Errorf("expected error %v, got %v", tt.expectedError, err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
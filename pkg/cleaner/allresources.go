@@ -0,0 +1,56 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"cmp"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ExpandAllResources replaces each resource entry setting AllResources with an entry per resource in
+// resources, e.g., every namespaced resource the cluster serves that can be listed and deleted, so that
+// a single entry removes everything matching its label selector from its namespace. The owners of
+// DependentResources come first, so that no workload remains to recreate what it owns.
+func ExpandAllResources(entries []DeleteObj, resources []schema.GroupVersionResource) []DeleteObj {
+	resources = slices.Clone(resources)
+	slices.SortFunc(resources, func(a, b schema.GroupVersionResource) int {
+		_, aOwner := DependentResources[a.GroupResource()]
+		_, bOwner := DependentResources[b.GroupResource()]
+		if aOwner != bOwner {
+			if aOwner {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(cmp.Compare(a.Group, b.Group), cmp.Compare(a.Resource, b.Resource))
+	})
+
+	expanded := make([]DeleteObj, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.AllResources {
+			expanded = append(expanded, entry)
+			continue
+		}
+		for _, gvr := range resources {
+			e := entry
+			e.AllResources = false
+			e.GroupVersionResource = gvr
+			expanded = append(expanded, e)
+		}
+	}
+	return expanded
+}
@@ -0,0 +1,65 @@
+package cleaner
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestExpandAllResources(t *testing.T) {
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	resources := []schema.GroupVersionResource{secretsGVR, configMapsGVR, deployments, podsGVR}
+	entries := []DeleteObj{
+		{GroupVersionResource: crdsGVR, Name: "widgets.example.com"},
+		{AllResources: true, Namespace: "widgets", LabelSelector: "app=widget", MustDelete: true},
+		{GroupVersionResource: jobsGVR, Name: "spectro-cleanup", Namespace: "widgets"},
+	}
+	expected := []DeleteObj{
+		{GroupVersionResource: crdsGVR, Name: "widgets.example.com"},
+		{GroupVersionResource: deployments, Namespace: "widgets", LabelSelector: "app=widget", MustDelete: true},
+		{GroupVersionResource: configMapsGVR, Namespace: "widgets", LabelSelector: "app=widget", MustDelete: true},
+		{GroupVersionResource: podsGVR, Namespace: "widgets", LabelSelector: "app=widget", MustDelete: true},
+		{GroupVersionResource: secretsGVR, Namespace: "widgets", LabelSelector: "app=widget", MustDelete: true},
+		{GroupVersionResource: jobsGVR, Name: "spectro-cleanup", Namespace: "widgets"},
+	}
+	if expanded := ExpandAllResources(entries, resources); !reflect.DeepEqual(expanded, expected) {
+		t.Errorf("expected %v, got %v", expected, expanded)
+	}
+}
+
+func TestValidateAllResources(t *testing.T) {
+	tests := []struct {
+		name          string
+		entry         DeleteObj
+		expectedError error
+	}{
+		{
+			name:  "namespace and label selector",
+			entry: DeleteObj{AllResources: true, Namespace: "widgets", LabelSelector: "app=widget"},
+		},
+		{
+			name:          "without label selector",
+			entry:         DeleteObj{AllResources: true, Namespace: "widgets"},
+			expectedError: ErrConfigInvalid,
+		},
+		{
+			name:          "without namespace",
+			entry:         DeleteObj{AllResources: true, LabelSelector: "app=widget"},
+			expectedError: ErrConfigInvalid,
+		},
+		{
+			name:          "with resource",
+			entry:         DeleteObj{AllResources: true, GroupVersionResource: podsGVR, Namespace: "widgets", LabelSelector: "app=widget"},
+			expectedError: ErrConfigInvalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.entry.Validate(); !errors.Is(err, tt.expectedError) {
+				t.Errorf("expected %v, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
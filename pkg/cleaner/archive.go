@@ -0,0 +1,212 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// tarball is a gzipped tarball being written to a local file
+type tarball struct {
+	path string
+	file *os.File
+	gz   *gzip.Writer
+	tw   *tar.Writer
+}
+
+// createTarball creates a uniquely named tarball in the given local directory. The node's hostname
+// and a timestamp are included in the name so that repeated runs never overwrite prior archives.
+func createTarball(dir, prefix string, now time.Time) (*tarball, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("%s-%s-%s.tar.gz", prefix, hostname, now.UTC().Format("20060102T150405Z"))
+	path := filepath.Join(filepath.Clean(dir), name)
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(file)
+	return &tarball{path: path, file: file, gz: gz, tw: tar.NewWriter(gz)}, nil
+}
+
+// Close flushes and closes the tarball
+func (t *tarball) Close() error {
+	if err := t.tw.Close(); err != nil {
+		return err
+	}
+	if err := t.gz.Close(); err != nil {
+		return err
+	}
+	return t.file.Close()
+}
+
+// fileArchive collects copies of deleted files into a gzipped tarball, providing an audit artifact
+type fileArchive struct {
+	*tarball
+	fsys FS
+}
+
+// newFileArchive creates a uniquely named tarball in the given local directory, archiving files from fsys
+func newFileArchive(dir string, fsys FS, now time.Time) (*fileArchive, error) {
+	t, err := createTarball(dir, "spectro-cleanup", now)
+	if err != nil {
+		return nil, err
+	}
+	return &fileArchive{tarball: t, fsys: fsys}, nil
+}
+
+// add copies a file into the archive, preserving its path, mode and ownership metadata.
+// Copying is abandoned once ctx is done.
+func (a *fileArchive) add(ctx context.Context, path string) error {
+	info, err := a.fsys.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	link := ""
+	if info.Mode()&fs.ModeSymlink != 0 {
+		if link, err = a.fsys.Readlink(path); err != nil {
+			return err
+		}
+	} else if !info.Mode().IsRegular() {
+		return fmt.Errorf("unable to archive %s: unsupported file mode %s", path, info.Mode())
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(path)), "/")
+	if err := a.tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if link != "" {
+		return nil
+	}
+
+	file, err := a.fsys.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(a.tw, contextReader{ctx: ctx, r: file})
+	return err
+}
+
+// redactedValue replaces the values of archived Secrets
+const redactedValue = "REDACTED"
+
+// manifestArchive collects the manifests of deleted resources into a gzipped tarball, so that a
+// deleted environment can be partially reconstructed. It is safe for concurrent use.
+type manifestArchive struct {
+	*tarball
+	mu     sync.Mutex
+	client ctrlclient.Client
+	now    time.Time
+}
+
+// newManifestArchive creates a uniquely named tarball in the given local directory, archiving
+// manifests read via client
+func newManifestArchive(dir string, client ctrlclient.Client, now time.Time) (*manifestArchive, error) {
+	t, err := createTarball(dir, "spectro-cleanup-manifests", now)
+	if err != nil {
+		return nil, err
+	}
+	return &manifestArchive{tarball: t, client: client, now: now}, nil
+}
+
+// add reads a resource's manifest and writes it to the archive, redacted, as YAML at
+// <group>/<version>/<resource>/[<namespace>/]<name>.yaml. The core group is named core.
+func (a *manifestArchive) add(ctx context.Context, gvr schema.GroupVersionResource, r metav1.PartialObjectMetadata) error {
+	gvk, err := a.client.RESTMapper().KindFor(gvr)
+	if err != nil {
+		return err
+	}
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	if err := a.client.Get(ctx, types.NamespacedName{Namespace: r.Namespace, Name: r.Name}, u); err != nil {
+		return err
+	}
+	redactManifest(u)
+	data, err := yaml.Marshal(u.Object)
+	if err != nil {
+		return err
+	}
+
+	group := gvr.Group
+	if group == "" {
+		group = "core"
+	}
+	name := path.Join(group, gvr.Version, gvr.Resource, r.Namespace, r.Name+".yaml")
+	header := &tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: a.now}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = a.tw.Write(data)
+	return err
+}
+
+// redactManifest strips a manifest of server-populated metadata and, for Secrets, of their values.
+// Redacted Secrets retain their keys as placeholder stringData, so that they remain valid manifests.
+func redactManifest(u *unstructured.Unstructured) {
+	unstructured.RemoveNestedField(u.Object, "metadata", "managedFields")
+	unstructured.RemoveNestedField(u.Object, "metadata", "resourceVersion")
+	unstructured.RemoveNestedField(u.Object, "metadata", "uid")
+	if u.GroupVersionKind().GroupKind() != (schema.GroupKind{Kind: "Secret"}) {
+		return
+	}
+
+	redacted := map[string]interface{}{}
+	for _, field := range []string{"data", "stringData"} {
+		values, _, _ := unstructured.NestedMap(u.Object, field)
+		for key := range values {
+			redacted[key] = redactedValue
+		}
+		unstructured.RemoveNestedField(u.Object, field)
+	}
+	if len(redacted) > 0 {
+		u.Object["stringData"] = redacted
+	}
+	// the last applied configuration may contain the Secret's values, too
+	unstructured.RemoveNestedField(u.Object, "metadata", "annotations", corev1.LastAppliedConfigAnnotation)
+}
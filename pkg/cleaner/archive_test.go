@@ -0,0 +1,198 @@
+package cleaner
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+)
+
+func TestFileArchive(t *testing.T) {
+	src := t.TempDir()
+	conf := filepath.Join(src, "00-multus.conf")
+	if err := os.WriteFile(conf, []byte(`{"name": "multus-cni-network"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(src, "multus.conf.link")
+	if err := os.Symlink(conf, link); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := newFileArchive(filepath.Join(t.TempDir(), "archive"), OSFS{}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{conf, link} {
+		if err := archive.add(context.Background(), path); err != nil {
+			t.Fatalf("expected no error archiving %s, got %v", path, err)
+		}
+	}
+	if err := archive.add(context.Background(), src); err == nil {
+		t.Errorf("expected error archiving directory, got nil")
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	contents := readTarball(t, archive.path)
+	expected := map[string]string{
+		strings.TrimPrefix(conf, "/"): `{"name": "multus-cni-network"}`,
+		strings.TrimPrefix(link, "/"): conf,
+	}
+	if len(contents) != len(expected) {
+		t.Fatalf("expected %d archived files, got %d", len(expected), len(contents))
+	}
+	for name, content := range expected {
+		if contents[name] != content {
+			t.Errorf("expected %s to contain %q, got %q", name, content, contents[name])
+		}
+	}
+}
+
+func TestManifestArchive(t *testing.T) {
+	secretsGVR := schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+	configMapsGVR := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{corev1.SchemeGroupVersion})
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Secret"), meta.RESTScopeNamespace)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "palette", Annotations: map[string]string{
+			corev1.LastAppliedConfigAnnotation: `{"data":{"password":"aHVudGVyMg=="}}`,
+			"owner":                            "palette",
+		}},
+		Data:       map[string][]byte{"password": []byte("hunter2")},
+		StringData: map[string]string{"username": "admin"},
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "palette"},
+		Data:       map[string]string{"mode": "edge"},
+	}
+	client := fake.NewClientBuilder().WithRESTMapper(mapper).WithObjects(secret, configMap).Build()
+
+	scheme := runtime.NewScheme()
+	for _, kind := range []string{"Secret", "ConfigMap"} {
+		scheme.AddKnownTypeWithName(corev1.SchemeGroupVersion.WithKind(kind), &metav1.PartialObjectMetadata{})
+		scheme.AddKnownTypeWithName(corev1.SchemeGroupVersion.WithKind(kind+"List"), &metav1.PartialObjectMetadataList{})
+	}
+	metadataClient := metadatafake.NewSimpleMetadataClient(scheme,
+		&metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "palette"},
+		},
+		&metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "palette"},
+		},
+	)
+
+	dir := t.TempDir()
+	entries := []DeleteObj{
+		{GroupVersionResource: secretsGVR, Name: "registry", Namespace: "palette"},
+		{GroupVersionResource: configMapsGVR, Name: "settings", Namespace: "palette"},
+	}
+	opts := Options{Client: client, MetadataClient: metadataClient, ManifestArchiveDir: dir}
+	if _, err := New(opts).CleanupResources(context.Background(), entries); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, gvr := range []schema.GroupVersionResource{secretsGVR, configMapsGVR} {
+		list, err := metadataClient.Resource(gvr).Namespace("palette").List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Items) != 0 {
+			t.Errorf("expected %s to be deleted, got %d remaining", gvr.Resource, len(list.Items))
+		}
+	}
+
+	archives, err := filepath.Glob(filepath.Join(dir, "spectro-cleanup-manifests-*.tar.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archives) != 1 {
+		t.Fatalf("expected 1 manifest archive, got %d", len(archives))
+	}
+	contents := readTarball(t, archives[0])
+	if len(contents) != 2 {
+		t.Fatalf("expected 2 archived manifests, got %d", len(contents))
+	}
+
+	archivedSecret := &corev1.Secret{}
+	if err := yaml.UnmarshalStrict([]byte(contents["core/v1/secrets/palette/registry.yaml"]), archivedSecret); err != nil {
+		t.Fatal(err)
+	}
+	expectedStringData := map[string]string{"password": redactedValue, "username": redactedValue}
+	if len(archivedSecret.Data) != 0 || !reflect.DeepEqual(archivedSecret.StringData, expectedStringData) {
+		t.Errorf("expected redacted stringData %v and no data, got %v and %v", expectedStringData, archivedSecret.StringData, archivedSecret.Data)
+	}
+	expectedAnnotations := map[string]string{"owner": "palette"}
+	if !reflect.DeepEqual(archivedSecret.Annotations, expectedAnnotations) {
+		t.Errorf("expected annotations %v, got %v", expectedAnnotations, archivedSecret.Annotations)
+	}
+	if archivedSecret.ResourceVersion != "" {
+		t.Errorf("expected no resourceVersion, got %s", archivedSecret.ResourceVersion)
+	}
+
+	archivedConfigMap := &corev1.ConfigMap{}
+	if err := yaml.UnmarshalStrict([]byte(contents["core/v1/configmaps/palette/settings.yaml"]), archivedConfigMap); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(archivedConfigMap.Data, configMap.Data) {
+		t.Errorf("expected data %v, got %v", configMap.Data, archivedConfigMap.Data)
+	}
+}
+
+func TestManifestArchiveRequiresClient(t *testing.T) {
+	entries := []DeleteObj{{GroupVersionResource: schema.GroupVersionResource{Version: "v1", Resource: "secrets"}, Name: "registry"}}
+	_, err := New(Options{ManifestArchiveDir: t.TempDir()}).CleanupResources(context.Background(), entries)
+	if !errors.Is(err, ErrConfigInvalid) {
+		t.Errorf("expected ErrConfigInvalid, got %v", err)
+	}
+}
+
+// readTarball returns the contents of each file in a gzipped tarball, or the target of each symlink
+func readTarball(t *testing.T, path string) map[string]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+
+	contents := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents[header.Name] = string(data) + header.Linkname
+	}
+	return contents
+}
@@ -0,0 +1,59 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// inode flags, see ioctl_iflags(2)
+const (
+	fsImmutableFl = 0x00000010
+	fsAppendFl    = 0x00000020
+)
+
+// clearImmutable removes the immutable and append-only inode flags (chattr -ia) from path,
+// either of which causes unlink to fail with EPERM. The caller must have CAP_LINUX_IMMUTABLE.
+func clearImmutable(path string) (bool, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return false, err
+	}
+	// inode flags can only be read through a file descriptor of a regular file or directory
+	if !info.Mode().IsRegular() && !info.IsDir() {
+		return false, nil
+	}
+
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	fd := int(f.Fd())
+	flags, err := unix.IoctlGetInt(fd, unix.FS_IOC_GETFLAGS)
+	if err != nil {
+		return false, err
+	}
+	if flags&(fsImmutableFl|fsAppendFl) == 0 {
+		return false, nil
+	}
+	if err := unix.IoctlSetPointerInt(fd, unix.FS_IOC_SETFLAGS, flags&^(fsImmutableFl|fsAppendFl)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
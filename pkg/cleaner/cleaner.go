@@ -0,0 +1,325 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cleaner deletes files from a node, and resources from a Kubernetes cluster. It is the
+// library behind the spectro-cleanup binary, allowing other components to embed the cleanup rather
+// than running the binary. The binary reads its configuration from CLEANUP_* environment variables;
+// library consumers provide the equivalent Options to New instead.
+package cleaner
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/internal/retry"
+)
+
+// defaultLogger is the logger of a Cleaner without the Logger option
+var defaultLogger = ctrl.Log.WithName("spectro-cleanup")
+
+// SystemNamespaces are never deleted by resource entries without a name, in addition to Options.ProtectedNamespaces
+var SystemNamespaces = []string{metav1.NamespaceDefault, metav1.NamespaceSystem, metav1.NamespacePublic, "kube-node-lease"}
+
+// Options configures a Cleaner. The zero value deletes files and resources once each, in order,
+// without retries, archival or waiting for deleted resources to be gone.
+type Options struct {
+	// HostRoot is the directory the host's root filesystem is mounted at. File entry paths are
+	// prefixed with it, allowing configs to reference real host paths.
+	HostRoot string
+
+	// FS is the filesystem files are deleted from. Defaults to OSFS. Checking the permissions to
+	// delete files is skipped for other filesystems, whereas unmounting and clearing immutable
+	// flags always operate on the local filesystem.
+	FS FS
+
+	// ArchiveDir, if set, is the directory a tarball of every deleted file is written to before deletion
+	ArchiveDir string
+
+	// Unmount unmounts file entries that are mount points before removing them. Requires CAP_SYS_ADMIN.
+	Unmount bool
+
+	// ClearImmutable clears the immutable and append-only inode flags of file entries before removing
+	// them. Requires CAP_LINUX_IMMUTABLE.
+	ClearImmutable bool
+
+	// CommandTimeout bounds each of a file entry's post-deletion commands
+	CommandTimeout time.Duration
+
+	// MetadataClient lists, deletes and patches resources. It is required to clean up resources.
+	MetadataClient metadata.Interface
+
+	// Client deletes the configurations of admission webhooks blocking deletions, if BypassWebhooks is set
+	Client         ctrlclient.Client
+	BypassWebhooks bool
+
+	// SuspendFlux suspends the Flux Kustomization or HelmRelease managing each resource, as identified
+	// by the labels Flux sets, before deleting it, so that Flux doesn't recreate it. Requires Client.
+	SuspendFlux bool
+
+	// ManifestArchiveDir, if set, is the directory a tarball of the manifest of every resource deleted
+	// by CleanupResources is written to before deletion. Secret values are redacted. Requires Client.
+	ManifestArchiveDir string
+
+	// EntryConcurrency is how many resource entries are processed at once. Defaults to 1, in which
+	// case entries are processed strictly in order.
+	EntryConcurrency int
+
+	// FailFast stops starting resource entries once a MustDelete entry has failed
+	FailFast bool
+
+	// PropagationPolicy is the propagation policy of resource deletions. Defaults to background.
+	PropagationPolicy metav1.DeletionPropagation
+
+	// ProtectedNamespaces are never deleted by resource entries without a name, in addition to SystemNamespaces
+	ProtectedNamespaces []string
+
+	// ForceManaged deletes resources managed by infrastructure-as-code controllers, i.e., Crossplane
+	// and terraform-controller, as identified by their ownership markers. By default, they are
+	// skipped, so that the cleanup doesn't fight the controllers.
+	ForceManaged bool
+
+	// TenantLabels, if set, confines the cleanup to a tenant: resources are only listed with the
+	// labels as a selector, and every resource is re-checked for carrying them immediately before
+	// its deletion, so that a shared cleanup service never crosses tenant boundaries
+	TenantLabels map[string]string
+
+	// SkipArgoCDHooks skips deleting resources annotated with an Argo CD hook deletion policy,
+	// which Argo CD deletes itself
+	SkipArgoCDHooks bool
+
+	// HighRiskThreshold, if positive, is how many resources an entry without a name may match
+	// before it must set ConfirmHighRisk
+	HighRiskThreshold int
+
+	// DeletionTimeout, if positive, makes each delete entry block until its resources are gone,
+	// i.e., until their finalizers have completed, or until the timeout elapses
+	DeletionTimeout time.Duration
+
+	// LoadBalancerTimeout, if positive, makes delete entries without a DeletionTimeout block until
+	// their deleted Services of type LoadBalancer are gone, i.e., until the cloud controller manager
+	// has released their load balancers, or until the timeout elapses
+	LoadBalancerTimeout time.Duration
+
+	// RecreationWindow, if positive, is how long after their deletion was confirmed an entry's
+	// resources are checked for having been recreated. The windows of a cleanup's entries elapse
+	// concurrently, and are waited out once every entry has been processed. Requires DeletionTimeout.
+	RecreationWindow time.Duration
+
+	// Backoff is the backoff between attempts of a destructive API call failing with a transient error
+	Backoff wait.Backoff
+
+	// RateLimiter, if set, throttles every destructive API call
+	RateLimiter flowcontrol.RateLimiter
+
+	// Retryable, if set, reports whether a destructive API call failed with a transient error and
+	// is retried. Defaults to Retryable; custom predicates may extend it, e.g., with the error
+	// signatures of a proxy or service mesh.
+	Retryable func(err error) bool
+
+	// Strategies are custom deletion strategies, referenced by name by the Strategy of resource
+	// config entries. They take precedence over the built-in strategies of the same name.
+	Strategies map[string]DeletionStrategy
+
+	// Policy, if set, is evaluated before each resource deletion. Denied deletions are skipped.
+	Policy Policy
+
+	// Hooks are called around deletions. Defaults to NopHooks.
+	Hooks Hooks
+
+	// EventSink, if set, receives structured progress events
+	EventSink EventSink
+
+	// Logger receives the cleanup's logs, e.g., to integrate them with the embedding application's
+	// logging pipeline. Defaults to controller-runtime's global logger, named spectro-cleanup.
+	Logger logr.Logger
+
+	// Clock times every wait, timeout and backoff, and timestamps events. Defaults to the real
+	// clock. Tests may set a fake clock, e.g., from k8s.io/utils/clock/testing, to step through
+	// timeouts without sleeping.
+	Clock clock.WithDelayedExecution
+
+	// Checkpoint, if set, records the resource entries processed successfully, so that they are
+	// skipped by a resumed cleanup
+	Checkpoint Checkpoint
+}
+
+// Checkpoint records the progress of a cleanup, so that an interrupted cleanup can be resumed.
+// Entries are identified by their index.
+type Checkpoint interface {
+	Completed(i int) bool
+	Complete(i int)
+}
+
+// Cleaner cleans up files and resources
+type Cleaner struct {
+	opts   Options
+	log    logr.Logger
+	waiter *deletionWaiter
+
+	// lbWaiter, if set, blocks until deleted Services have released their cloud load balancers
+	lbWaiter *deletionWaiter
+
+	// recreation, if set, checks the deleted resources of the entry being processed for having been recreated
+	recreation *recreationCheck
+
+	// manifests, if set, archives the manifests of deleted resources
+	manifests *manifestArchive
+
+	// flux records the Flux objects suspended, if SuspendFlux is set
+	flux *fluxSuspensions
+
+	// tenant selects the resources within the tenant, if TenantLabels is set
+	tenant labels.Selector
+
+	// finalize is closed by Finalize, exactly once
+	finalize     chan struct{}
+	finalizeOnce *sync.Once
+}
+
+// New returns a Cleaner configured by opts
+func New(opts Options) *Cleaner {
+	if opts.EntryConcurrency < 1 {
+		opts.EntryConcurrency = 1
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.RealClock{}
+	}
+	if opts.Hooks == nil {
+		opts.Hooks = NopHooks{}
+	}
+	if opts.FS == nil {
+		opts.FS = OSFS{}
+	}
+	if opts.PropagationPolicy == "" {
+		opts.PropagationPolicy = metav1.DeletePropagationBackground
+	}
+	if opts.Logger.IsZero() {
+		opts.Logger = defaultLogger
+	}
+	c := &Cleaner{opts: opts, log: opts.Logger, finalize: make(chan struct{}), finalizeOnce: &sync.Once{}}
+	if opts.SuspendFlux {
+		c.flux = &fluxSuspensions{suspended: map[string]bool{}}
+	}
+	if len(opts.TenantLabels) > 0 {
+		c.tenant = labels.SelectorFromSet(opts.TenantLabels)
+	}
+	if opts.DeletionTimeout > 0 && opts.MetadataClient != nil {
+		c.waiter = &deletionWaiter{metadataClient: opts.MetadataClient, log: opts.Logger, clock: opts.Clock, timeout: opts.DeletionTimeout, recreationWindow: opts.RecreationWindow}
+	}
+	if opts.LoadBalancerTimeout > 0 && opts.MetadataClient != nil {
+		c.lbWaiter = &deletionWaiter{metadataClient: opts.MetadataClient, log: opts.Logger, clock: opts.Clock, timeout: opts.LoadBalancerTimeout}
+	}
+	return c
+}
+
+// CleanupResources applies the action of each resource config entry, returning the outcome of each
+// entry along with the failures of MustDelete entries. See processEntries.
+func (c *Cleaner) CleanupResources(ctx context.Context, entries []DeleteObj) (*Result, error) {
+	if c.opts.ManifestArchiveDir == "" {
+		return c.processEntries(ctx, entries)
+	}
+	if c.opts.Client == nil {
+		return nil, fmt.Errorf("%w: archiving manifests requires a client", ErrConfigInvalid)
+	}
+	archive, err := newManifestArchive(c.opts.ManifestArchiveDir, c.opts.Client, c.opts.Clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	c.log.Info("Archiving deleted resource manifests", "path", archive.path)
+	defer func() {
+		if err := archive.Close(); err != nil {
+			c.log.Error(err, "failed to close manifest archive", "path", archive.path)
+		}
+	}()
+	ac := *c
+	ac.manifests = archive
+	return ac.processEntries(ctx, entries)
+}
+
+// CleanupFinalResource applies the action of a resource config entry without waiting for its
+// resources to be gone, e.g., of the final entry, which deletes spectro-cleanup itself. The
+// entry's failure is returned whether or not it is MustDelete.
+func (c *Cleaner) CleanupFinalResource(ctx context.Context, entry DeleteObj) (*Result, error) {
+	start := c.opts.Clock.Now()
+	// the final entry never blocks, not even on load balancers
+	fc := *c
+	fc.lbWaiter = nil
+	result := &Result{Entries: []EntryResult{fc.runEntry(ctx, entry, nil)}}
+	result.Duration = c.opts.Clock.Since(start)
+	return result, result.Entries[0].Err
+}
+
+// Finalize notifies the consumer of FinalizeCh, e.g., spectro-cleanup waiting to self destruct, that the
+// cleanup may be finalized. It may be called concurrently and more than once, and before the consumer
+// starts waiting, in which case the notification is retained. Each Cleaner is notified independently.
+func (c *Cleaner) Finalize() {
+	c.finalizeOnce.Do(func() { close(c.finalize) })
+}
+
+// FinalizeCh returns a channel that is closed once Finalize has been called
+func (c *Cleaner) FinalizeCh() <-chan struct{} {
+	return c.finalize
+}
+
+// Retryable reports whether an API call failed with a transient error, e.g., throttling or an
+// unavailable API server, and may succeed if retried
+func Retryable(err error) bool {
+	return retry.Retryable(err)
+}
+
+// retry performs a destructive API call, retrying transient failures
+func (c *Cleaner) retry(ctx context.Context, fn func(ctx context.Context) error) error {
+	policy := retry.Policy{Backoff: c.opts.Backoff, Limiter: c.opts.RateLimiter, Clock: c.opts.Clock, Retryable: c.opts.Retryable}
+	return retry.Mutation(ctx, policy, fn)
+}
+
+// protected reports whether a namespace must never be deleted
+func (c *Cleaner) protected(namespace string) bool {
+	return slices.Contains(SystemNamespaces, namespace) || slices.Contains(c.opts.ProtectedNamespaces, namespace)
+}
+
+// withTimeout returns a copy of ctx that is cancelled once timeout elapses on clk
+func withTimeout(ctx context.Context, clk clock.WithDelayedExecution, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	timer := clk.AfterFunc(timeout, cancel)
+	return ctx, func() {
+		timer.Stop()
+		cancel()
+	}
+}
+
+// entryTimeout returns how long an entry may wait: its TimeoutSeconds, or else the DeletionTimeout
+// option, or else fallback
+func (c *Cleaner) entryTimeout(obj DeleteObj, fallback time.Duration) time.Duration {
+	if obj.TimeoutSeconds > 0 {
+		return time.Duration(obj.TimeoutSeconds) * time.Second
+	}
+	if c.opts.DeletionTimeout > 0 {
+		return c.opts.DeletionTimeout
+	}
+	return fallback
+}
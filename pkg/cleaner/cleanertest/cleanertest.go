@@ -0,0 +1,74 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cleanertest provides deterministic fakes for testing code that uses package cleaner,
+// without touching the host filesystem or sleeping through real timeouts.
+package cleanertest
+
+import (
+	"io/fs"
+	"strings"
+	"testing/fstest"
+	"time"
+
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+// MapFS is an in-memory cleaner.FS of absolute paths, backed by a fstest.MapFS whose keys are the
+// paths without their leading slash. Symlinks are not supported.
+type MapFS struct {
+	fstest.MapFS
+}
+
+// Lstat returns the FileInfo of the named file
+func (m MapFS) Lstat(name string) (fs.FileInfo, error) {
+	return m.Stat(strings.TrimPrefix(name, "/"))
+}
+
+// Readlink always fails, as MapFS holds no symlinks
+func (m MapFS) Readlink(name string) (string, error) {
+	return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+}
+
+// Open opens the named file for reading
+func (m MapFS) Open(name string) (fs.File, error) {
+	return m.MapFS.Open(strings.TrimPrefix(name, "/"))
+}
+
+// Remove deletes the named file from the map
+func (m MapFS) Remove(name string) error {
+	if _, ok := m.MapFS[strings.TrimPrefix(name, "/")]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(m.MapFS, strings.TrimPrefix(name, "/"))
+	return nil
+}
+
+// Glob returns the absolute paths of all files matching pattern
+func (m MapFS) Glob(pattern string) ([]string, error) {
+	matches, err := fs.Glob(m.MapFS, strings.TrimPrefix(pattern, "/"))
+	for i := range matches {
+		matches[i] = "/" + matches[i]
+	}
+	return matches, err
+}
+
+// StepWhenWaiting advances clk by d as soon as something is waiting on it, e.g., a deletion timeout
+// set via cleaner.Options.Clock, so that the code under test observes the timeout expiring
+func StepWhenWaiting(clk *testingclock.FakeClock, d time.Duration) {
+	for !clk.HasWaiters() {
+		time.Sleep(time.Millisecond)
+	}
+	clk.Step(d)
+}
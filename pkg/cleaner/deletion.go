@@ -0,0 +1,271 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/metadata"
+	"k8s.io/utils/clock"
+)
+
+// deletionPollInterval is how often an entry's resources are relisted if they can't be watched
+var deletionPollInterval = 2 * time.Second
+
+// defaultWaitTimeout bounds the wait of an entry that sets Wait if neither its TimeoutSeconds nor
+// the DeletionTimeout option is set
+const defaultWaitTimeout = 2 * time.Minute
+
+// deletionWaiter blocks until deleted resources are gone, i.e., until their finalizers have
+// completed. Each entry has its own deletion timeout, so that resources stuck behind a finalizer
+// can't starve the entries after them.
+type deletionWaiter struct {
+	metadataClient metadata.Interface
+	log            logr.Logger
+	clock          clock.WithDelayedExecution
+	timeout        time.Duration
+
+	// recreationWindow is how long to watch for confirmed deletions being undone, if positive
+	recreationWindow time.Duration
+}
+
+// waiting returns a copy of the Cleaner whose deletions block until the deleted resources are gone,
+// for entries that set Wait although the DeletionTimeout option is unset
+func (c *Cleaner) waiting() *Cleaner {
+	wc := *c
+	wc.waiter = &deletionWaiter{metadataClient: c.opts.MetadataClient, log: c.log, clock: c.opts.Clock, timeout: defaultWaitTimeout}
+	return &wc
+}
+
+// waitForDeletion blocks until none of an entry's deleted resources remain. Rather than polling each
+// resource, the entry's resources are listed once and then watched, confirming deletions as events
+// arrive; the list is only repeated if the watch ends early. If watching is forbidden, the list is
+// repeated every deletionPollInterval instead, so that verification never requires a request per
+// resource. Resources deleted with an empty UID are matched by name alone.
+func (w *deletionWaiter) waitForDeletion(ctx context.Context, obj DeleteObj, deleted []metav1.PartialObjectMetadata) error {
+	if len(deleted) == 0 {
+		return nil
+	}
+	timeout := w.timeout
+	if obj.TimeoutSeconds > 0 {
+		timeout = time.Duration(obj.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := withTimeout(ctx, w.clock, timeout)
+	defer cancel()
+
+	pending := make(map[types.NamespacedName]*metav1.PartialObjectMetadata, len(deleted))
+	for i, d := range deleted {
+		pending[types.NamespacedName{Namespace: d.Namespace, Name: d.Name}] = &deleted[i]
+	}
+	opts := metav1.ListOptions{LabelSelector: obj.LabelSelector}
+	if obj.Name != "" {
+		opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", obj.Name).String()
+	}
+	ri := w.metadataClient.Resource(obj.GroupVersionResource).Namespace(obj.Namespace)
+	gvrStr := obj.GroupVersionResource.String()
+
+	for {
+		list, err := ri.List(ctx, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return deletionTimeoutError(pending, gvrStr)
+			}
+			return err
+		}
+		remaining := make(map[types.NamespacedName]*metav1.PartialObjectMetadata, len(pending))
+		for i := range list.Items {
+			item := &list.Items[i]
+			key := types.NamespacedName{Namespace: item.Namespace, Name: item.Name}
+			if p, ok := pending[key]; ok && (p.UID == "" || p.UID == item.UID) {
+				remaining[key] = item
+			}
+		}
+		pending = remaining
+		if len(pending) == 0 {
+			w.log.Info("Resource deletion confirmed", "gvr", gvrStr)
+			return nil
+		}
+		finalizers, notDeleting := deletionBlockers(pending)
+		w.log.Info("Waiting for resources to be deleted", "remaining", len(pending), "gvr", gvrStr,
+			"blockingFinalizers", finalizers, "notDeleting", notDeleting)
+
+		watchOpts := opts
+		watchOpts.ResourceVersion = list.ResourceVersion
+		watcher, err := ri.Watch(ctx, watchOpts)
+		if apierrors.IsForbidden(err) || apierrors.IsMethodNotSupported(err) {
+			// the watch isn't permitted, so poll instead, still with a single List for all resources
+			select {
+			case <-ctx.Done():
+				return deletionTimeoutError(pending, gvrStr)
+			case <-w.clock.After(deletionPollInterval):
+			}
+			continue
+		} else if err != nil {
+			if ctx.Err() != nil {
+				return deletionTimeoutError(pending, gvrStr)
+			}
+			return err
+		}
+		if confirmDeletions(ctx, watcher, pending) {
+			w.log.Info("Resource deletion confirmed", "gvr", gvrStr)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return deletionTimeoutError(pending, gvrStr)
+		}
+	}
+}
+
+// confirmDeletions removes each pending resource from the map as its deletion event arrives, and
+// tracks changes to the finalizers of the others, returning true once none remain, or false if the
+// watch or context ends first
+func confirmDeletions(ctx context.Context, watcher watch.Interface, pending map[types.NamespacedName]*metav1.PartialObjectMetadata) bool {
+	defer watcher.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case event, ok := <-watcher.ResultChan():
+			if !ok || event.Type == watch.Error {
+				return false
+			}
+			m, ok := event.Object.(*metav1.PartialObjectMetadata)
+			if !ok {
+				continue
+			}
+			key := types.NamespacedName{Namespace: m.Namespace, Name: m.Name}
+			p, ok := pending[key]
+			if !ok || (p.UID != "" && p.UID != m.UID) {
+				continue
+			}
+			switch event.Type {
+			case watch.Deleted:
+				delete(pending, key)
+			case watch.Modified:
+				pending[key] = m
+			}
+			if len(pending) == 0 {
+				return true
+			}
+		}
+	}
+}
+
+// deletionBlockers summarizes why pending resources remain: the number of resources blocked by each
+// finalizer, identifying the controllers responsible, and the number of resources without a
+// deletionTimestamp, i.e., that aren't being deleted at all
+func deletionBlockers(pending map[types.NamespacedName]*metav1.PartialObjectMetadata) (map[string]int, int) {
+	finalizers := map[string]int{}
+	notDeleting := 0
+	for _, p := range pending {
+		if p.DeletionTimestamp == nil {
+			notDeleting++
+		}
+		for _, f := range p.Finalizers {
+			finalizers[f]++
+		}
+	}
+	return finalizers, notDeleting
+}
+
+// deletionTimeoutError reports the pending resources, and the finalizers blocking them
+func deletionTimeoutError(pending map[types.NamespacedName]*metav1.PartialObjectMetadata, gvrStr string) error {
+	finalizers, notDeleting := deletionBlockers(pending)
+	remaining := make([]types.NamespacedName, 0, len(pending))
+	for key := range pending {
+		remaining = append(remaining, key)
+	}
+	slices.SortFunc(remaining, func(a, b types.NamespacedName) int { return strings.Compare(a.String(), b.String()) })
+	return &ResourcesError{Resources: remaining, Err: fmt.Errorf("%w: %d %s remaining, blocked by finalizers %v, %d not being deleted",
+		ErrDeletionTimeout, len(pending), gvrStr, finalizers, notDeleting)}
+}
+
+// recreationCheck checks an entry's deleted resources for having been recreated in the background, so
+// that the recreation windows of the entries of a cleanup elapse concurrently rather than in sequence
+type recreationCheck struct {
+	wg      sync.WaitGroup
+	started bool
+	err     error
+}
+
+// start starts checking whether the deleted resources are recreated within the recreation window, if set
+func (rc *recreationCheck) start(ctx context.Context, w *deletionWaiter, obj DeleteObj, deleted []metav1.PartialObjectMetadata) {
+	if w.recreationWindow <= 0 || len(deleted) == 0 {
+		return
+	}
+	rc.started = true
+	rc.wg.Add(1)
+	go func() {
+		defer rc.wg.Done()
+		if rc.err = w.checkRecreated(ctx, obj, deleted); rc.err != nil {
+			w.log.Info("WARNING: deleted resources reappeared, a controller may still be running", "error", rc.err.Error())
+		}
+	}()
+}
+
+// wait blocks until the check, if started, is done, returning the error naming any recreated resources
+func (rc *recreationCheck) wait() error {
+	rc.wg.Wait()
+	return rc.err
+}
+
+// checkRecreated waits for the recreation window to elapse, then returns an error naming any of an
+// entry's deleted resources that exist again, revealing controllers that are still running and
+// undoing the cleanup
+func (w *deletionWaiter) checkRecreated(ctx context.Context, obj DeleteObj, deleted []metav1.PartialObjectMetadata) error {
+	if w.recreationWindow <= 0 || len(deleted) == 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-w.clock.After(w.recreationWindow):
+	}
+
+	opts := metav1.ListOptions{LabelSelector: obj.LabelSelector}
+	if obj.Name != "" {
+		opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", obj.Name).String()
+	}
+	list, err := w.metadataClient.Resource(obj.GroupVersionResource).Namespace(obj.Namespace).List(ctx, opts)
+	if err != nil {
+		return err
+	}
+	wasDeleted := make(map[types.NamespacedName]bool, len(deleted))
+	for _, d := range deleted {
+		wasDeleted[types.NamespacedName{Namespace: d.Namespace, Name: d.Name}] = true
+	}
+	var recreated []types.NamespacedName
+	for _, item := range list.Items {
+		key := types.NamespacedName{Namespace: item.Namespace, Name: item.Name}
+		if wasDeleted[key] && item.DeletionTimestamp == nil {
+			recreated = append(recreated, key)
+		}
+	}
+	if len(recreated) > 0 {
+		return &ResourcesError{Resources: recreated, Err: fmt.Errorf("%w: %s %v", ErrRecreated, obj.GroupVersionResource, recreated)}
+	}
+	return nil
+}
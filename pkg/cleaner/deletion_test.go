@@ -0,0 +1,227 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/utils/clock"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner/cleanertest"
+)
+
+func TestWaitForDeletion(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	configMap := func(name string) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: k8stypes.UID("uid-" + name)},
+		}
+	}
+	entry := DeleteObj{GroupVersionResource: gvr, Namespace: "default"}
+
+	t.Run("already deleted", func(t *testing.T) {
+		client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), configMap("unrelated"))
+		w := &deletionWaiter{metadataClient: client, clock: clock.RealClock{}, timeout: time.Second}
+		if err := w.waitForDeletion(context.Background(), entry, []metav1.PartialObjectMetadata{*configMap("gone")}); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("deleted while watching", func(t *testing.T) {
+		client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), configMap("a"), configMap("b"))
+		w := &deletionWaiter{metadataClient: client, clock: clock.RealClock{}, timeout: 5 * time.Second}
+
+		done := make(chan error)
+		go func() {
+			done <- w.waitForDeletion(context.Background(), entry, []metav1.PartialObjectMetadata{*configMap("a"), *configMap("b")})
+		}()
+		for !hasWatchAction(client) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		for _, name := range []string{"a", "b"} {
+			if err := client.Resource(gvr).Namespace("default").Delete(context.Background(), name, metav1.DeleteOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := <-done; err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("recreated with a new uid", func(t *testing.T) {
+		recreated := configMap("a")
+		recreated.UID = "recreated"
+		client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), recreated)
+		w := &deletionWaiter{metadataClient: client, clock: clock.RealClock{}, timeout: time.Second}
+		if err := w.waitForDeletion(context.Background(), entry, []metav1.PartialObjectMetadata{*configMap("a")}); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		stuck := configMap("stuck")
+		stuck.Finalizers = []string{"example.com/finalizer"}
+		client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), stuck)
+		fakeClock := testingclock.NewFakeClock(time.Now())
+		w := &deletionWaiter{metadataClient: client, clock: fakeClock, timeout: time.Hour}
+		go cleanertest.StepWhenWaiting(fakeClock, time.Hour)
+		err := w.waitForDeletion(context.Background(), entry, []metav1.PartialObjectMetadata{*configMap("stuck")})
+		if !errors.Is(err, ErrDeletionTimeout) {
+			t.Errorf("expected %v, got %v", ErrDeletionTimeout, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), "example.com/finalizer") {
+			t.Errorf("expected blocking finalizer in error, got %v", err)
+		}
+	})
+
+	t.Run("entry timeout", func(t *testing.T) {
+		client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), configMap("stuck"))
+		fakeClock := testingclock.NewFakeClock(time.Now())
+		w := &deletionWaiter{metadataClient: client, clock: fakeClock, timeout: time.Hour}
+		withTimeout := entry
+		withTimeout.TimeoutSeconds = 60
+		go cleanertest.StepWhenWaiting(fakeClock, time.Minute)
+		err := w.waitForDeletion(context.Background(), withTimeout, []metav1.PartialObjectMetadata{*configMap("stuck")})
+		if !errors.Is(err, ErrDeletionTimeout) {
+			t.Errorf("expected %v, got %v", ErrDeletionTimeout, err)
+		}
+	})
+
+	t.Run("polled if watch forbidden", func(t *testing.T) {
+		defaultInterval := deletionPollInterval
+		deletionPollInterval = 10 * time.Millisecond
+		defer func() { deletionPollInterval = defaultInterval }()
+
+		client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), configMap("a"), configMap("b"))
+		client.PrependWatchReactor("configmaps", func(action clienttesting.Action) (bool, watch.Interface, error) {
+			return true, nil, apierrors.NewForbidden(gvr.GroupResource(), "", errors.New("watch denied"))
+		})
+		w := &deletionWaiter{metadataClient: client, clock: clock.RealClock{}, timeout: 5 * time.Second}
+
+		done := make(chan error)
+		go func() {
+			done <- w.waitForDeletion(context.Background(), entry, []metav1.PartialObjectMetadata{*configMap("a"), *configMap("b")})
+		}()
+		for !hasWatchAction(client) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		for _, name := range []string{"a", "b"} {
+			if err := client.Resource(gvr).Namespace("default").Delete(context.Background(), name, metav1.DeleteOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := <-done; err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+		for _, a := range client.Actions() {
+			if a.GetVerb() == "get" {
+				t.Errorf("expected no per-resource gets, got %v", a)
+			}
+		}
+	})
+}
+
+func hasWatchAction(client *metadatafake.FakeMetadataClient) bool {
+	for _, a := range client.Actions() {
+		if a.GetVerb() == "watch" {
+			return true
+		}
+	}
+	return false
+}
+
+func newMetadataScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	scheme.AddKnownTypeWithName(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, &metav1.PartialObjectMetadata{})
+	scheme.AddKnownTypeWithName(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMapList"}, &metav1.PartialObjectMetadataList{})
+	return scheme
+}
+
+func TestCheckRecreated(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	configMap := func(name string) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		}
+	}
+	entry := DeleteObj{GroupVersionResource: gvr, Namespace: "default"}
+	deleted := []metav1.PartialObjectMetadata{*configMap("a"), *configMap("b")}
+
+	tests := []struct {
+		name     string
+		existing []runtime.Object
+		expected error
+	}{
+		{name: "not recreated", existing: []runtime.Object{configMap("unrelated")}},
+		{name: "recreated", existing: []runtime.Object{configMap("a")}, expected: ErrRecreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), tt.existing...)
+			w := &deletionWaiter{metadataClient: client, clock: clock.RealClock{}, recreationWindow: 10 * time.Millisecond}
+			if err := w.checkRecreated(context.Background(), entry, deleted); !errors.Is(err, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestRecreationWindowsOverlap(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	configMap := func(name string) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		}
+	}
+	names := []string{"a", "b", "c", "d"}
+	var objs []runtime.Object
+	var entries []DeleteObj
+	for _, name := range names {
+		objs = append(objs, configMap(name))
+		entries = append(entries, DeleteObj{GroupVersionResource: gvr, Name: name, Namespace: "default", MustDelete: true})
+	}
+	client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), objs...)
+	// a controller recreates a while the later entries are processed, i.e., within a's window
+	sink := EventSinkFunc(func(event Event) {
+		if event.Type == EventEntryStarted && event.Entry.Name == "d" {
+			if err := client.Tracker().Add(configMap("a")); err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		}
+	})
+
+	window := 300 * time.Millisecond
+	c := New(Options{MetadataClient: client, DeletionTimeout: time.Second, RecreationWindow: window, EventSink: sink})
+	start := time.Now()
+	result, err := c.CleanupResources(context.Background(), entries)
+	if elapsed := time.Since(start); elapsed >= 2*window {
+		t.Errorf("expected the windows of %d entries to overlap, took %s", len(entries), elapsed)
+	}
+	if !errors.Is(err, ErrRecreated) {
+		t.Errorf("expected %v, got %v", ErrRecreated, err)
+	}
+	for i, entry := range result.Entries {
+		expected := StatusSucceeded
+		if i == 0 {
+			expected = StatusFailed
+		}
+		if entry.Status != expected {
+			t.Errorf("expected %s status %s, got %s", names[i], expected, entry.Status)
+		}
+	}
+}
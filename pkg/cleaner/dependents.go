@@ -0,0 +1,141 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	podsGVR                = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	replicaSetsGVR         = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"}
+	controllerRevisionsGVR = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "controllerrevisions"}
+	jobsGVR                = schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"}
+)
+
+// DependentResources are the resources searched for the dependents of each resource deleted by
+// entries setting WaitForDependents, i.e., the resources owned by it via ownerReferences, and their
+// own dependents in turn. Add the owned resources of custom resources to wait for their dependents too.
+var DependentResources = map[schema.GroupResource][]schema.GroupVersionResource{
+	{Group: "apps", Resource: "deployments"}:  {replicaSetsGVR},
+	{Group: "apps", Resource: "replicasets"}:  {podsGVR},
+	{Group: "apps", Resource: "statefulsets"}: {podsGVR, controllerRevisionsGVR},
+	{Group: "apps", Resource: "daemonsets"}:   {podsGVR, controllerRevisionsGVR},
+	{Group: "batch", Resource: "cronjobs"}:    {jobsGVR},
+	{Group: "batch", Resource: "jobs"}:        {podsGVR},
+	{Resource: "replicationcontrollers"}:      {podsGVR},
+}
+
+// dependent is a resource owned, directly or transitively, by a deleted resource
+type dependent struct {
+	gvr      schema.GroupVersionResource
+	resource metav1.PartialObjectMetadata
+}
+
+// dependentFinder finds the dependents of an entry's resources, listing each resource type once
+// per namespace
+type dependentFinder struct {
+	c     *Cleaner
+	lists map[string][]metav1.PartialObjectMetadata
+}
+
+// dependents returns the dependents of a resource about to be deleted, so that they are known even
+// once the garbage collector has deleted the intermediate owners, e.g., a Deployment's ReplicaSets
+// of its Pods. A resource planned without a UID is read first.
+func (f *dependentFinder) dependents(ctx context.Context, gvr schema.GroupVersionResource, r metav1.PartialObjectMetadata) ([]dependent, error) {
+	if r.UID == "" {
+		m, err := f.c.opts.MetadataClient.Resource(gvr).Namespace(r.Namespace).Get(ctx, r.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		r = *m
+	}
+
+	var found []dependent
+	owners := []dependent{{gvr: gvr, resource: r}}
+	for len(owners) > 0 {
+		owner := owners[0]
+		owners = owners[1:]
+		for _, dgvr := range DependentResources[owner.gvr.GroupResource()] {
+			items, err := f.list(ctx, dgvr, owner.resource.Namespace)
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range items {
+				if slices.ContainsFunc(item.OwnerReferences, func(ref metav1.OwnerReference) bool { return ref.UID == owner.resource.UID }) {
+					d := dependent{gvr: dgvr, resource: item}
+					found = append(found, d)
+					owners = append(owners, d)
+				}
+			}
+		}
+	}
+	return found, nil
+}
+
+// list returns the resources of a type in a namespace, as listed by the first call
+func (f *dependentFinder) list(ctx context.Context, gvr schema.GroupVersionResource, namespace string) ([]metav1.PartialObjectMetadata, error) {
+	key := gvr.String() + "/" + namespace
+	if items, ok := f.lists[key]; ok {
+		return items, nil
+	}
+	list, err := f.c.opts.MetadataClient.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if f.lists == nil {
+		f.lists = map[string][]metav1.PartialObjectMetadata{}
+	}
+	f.lists[key] = list.Items
+	return list.Items, nil
+}
+
+// waitForDependents blocks until none of an entry's dependents remain, waiting for each resource type
+// in turn, e.g., a Deployment's ReplicaSets and then their Pods. The wait is bounded by the entry's
+// TimeoutSeconds, or else by the DeletionTimeout option, or else by two minutes.
+func (c *Cleaner) waitForDependents(ctx context.Context, obj DeleteObj, dependents []dependent) error {
+	if len(dependents) == 0 {
+		return nil
+	}
+	timeout := c.entryTimeout(obj, defaultWaitTimeout)
+	ctx, cancel := withTimeout(ctx, c.opts.Clock, timeout)
+	defer cancel()
+
+	var gvrs []schema.GroupVersionResource
+	byGVR := map[schema.GroupVersionResource][]metav1.PartialObjectMetadata{}
+	for _, d := range dependents {
+		if _, ok := byGVR[d.gvr]; !ok {
+			gvrs = append(gvrs, d.gvr)
+		}
+		byGVR[d.gvr] = append(byGVR[d.gvr], d.resource)
+	}
+	waiter := &deletionWaiter{metadataClient: c.opts.MetadataClient, log: c.log, clock: c.opts.Clock, timeout: timeout}
+	for _, gvr := range gvrs {
+		c.log.Info("Waiting for dependents to be deleted", "name", obj.Name, "namespace", obj.Namespace, "gvr", obj.GroupVersionResource.String(),
+			"dependents", gvr.String(), "remaining", len(byGVR[gvr]))
+		if err := waiter.waitForDeletion(ctx, DeleteObj{GroupVersionResource: gvr, Namespace: obj.Namespace}, byGVR[gvr]); err != nil {
+			return fmt.Errorf("dependents of %s %s/%s: %w", obj.GroupVersionResource, obj.Namespace, obj.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	clienttesting "k8s.io/client-go/testing"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner/cleanertest"
+)
+
+func TestWaitForDependents(t *testing.T) {
+	deploymentsGVR := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	scheme := runtime.NewScheme()
+	for _, gvk := range []schema.GroupVersionKind{
+		{Group: "apps", Version: "v1", Kind: "Deployment"},
+		{Group: "apps", Version: "v1", Kind: "ReplicaSet"},
+		{Version: "v1", Kind: "Pod"},
+	} {
+		scheme.AddKnownTypeWithName(gvk, &metav1.PartialObjectMetadata{})
+		scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind+"List"), &metav1.PartialObjectMetadataList{})
+	}
+	object := func(apiVersion, kind, name string, uid, owner types.UID) *metav1.PartialObjectMetadata {
+		m := &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: apiVersion, Kind: kind},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: uid},
+		}
+		if owner != "" {
+			m.OwnerReferences = []metav1.OwnerReference{{UID: owner}}
+		}
+		return m
+	}
+
+	tests := []struct {
+		name          string
+		collected     bool
+		expectedError error
+	}{
+		{name: "dependents collected", collected: true},
+		{name: "dependents remain", expectedError: ErrDeletionTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := metadatafake.NewSimpleMetadataClient(scheme,
+				object("apps/v1", "Deployment", "web", "deployment", ""),
+				object("apps/v1", "ReplicaSet", "web-1", "replicaset", "deployment"),
+				object("v1", "Pod", "web-1-a", "pod", "replicaset"),
+				object("v1", "Pod", "other", "other", ""),
+			)
+			// the garbage collector deletes the dependents, unless it is lagging
+			client.PrependReactor("delete", "deployments", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				if tt.collected {
+					_ = client.Tracker().Delete(replicaSetsGVR, "default", "web-1")
+					_ = client.Tracker().Delete(podsGVR, "default", "web-1-a")
+				}
+				return false, nil, nil
+			})
+			fakeClock := testingclock.NewFakeClock(time.Now())
+			if !tt.collected {
+				go cleanertest.StepWhenWaiting(fakeClock, defaultWaitTimeout)
+			}
+
+			entries := []DeleteObj{{GroupVersionResource: deploymentsGVR, Name: "web", Namespace: "default", WaitForDependents: true}}
+			result, err := New(Options{MetadataClient: client, Clock: fakeClock}).CleanupResources(context.Background(), entries)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !errors.Is(result.Entries[0].Err, tt.expectedError) {
+				t.Errorf("expected entry error %v, got %v", tt.expectedError, result.Entries[0].Err)
+			}
+			if _, err := client.Tracker().Get(podsGVR, "default", "other"); err != nil {
+				t.Errorf("expected the unrelated Pod to remain, got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateWaitForDependents(t *testing.T) {
+	entry := DeleteObj{GroupVersionResource: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+		Name: "web", Action: ActionRemoveFinalizers, Finalizers: []string{"example.com/finalizer"}, WaitForDependents: true}
+	if err := entry.Validate(); !errors.Is(err, ErrConfigInvalid) {
+		t.Errorf("expected %v, got %v", ErrConfigInvalid, err)
+	}
+}
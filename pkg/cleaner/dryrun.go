@@ -0,0 +1,91 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// DryRun issues a server-side dry-run deletion of each resource the MustDelete delete entries would
+// delete, so that admission webhooks and policies that would block the real deletion, or missing RBAC
+// permissions, are found before anything is deleted. Nothing is modified. A MustDeleteError is returned
+// for each blocked entry, naming every blocked resource, so that all blockers are reported up front.
+// Webhooks that can't be called are only reported if bypassing webhooks is disabled, and webhooks
+// that don't support dry runs can't be verified.
+func (c *Cleaner) DryRun(ctx context.Context, entries []DeleteObj) error {
+	var errs []error
+	for _, obj := range entries {
+		if !obj.MustDelete || (obj.Action != "" && obj.Action != ActionDelete) {
+			continue
+		}
+		if err := c.dryRunEntry(ctx, obj); err != nil {
+			errs = append(errs, &MustDeleteError{Entry: obj, Resources: failedResources(err), Err: err})
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// dryRunEntry issues a dry-run deletion of each resource planned for an entry
+func (c *Cleaner) dryRunEntry(ctx context.Context, obj DeleteObj) error {
+	resources, err := c.planEntry(ctx, obj)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	gvrStr := obj.GroupVersionResource.String()
+	policy := c.opts.PropagationPolicy
+	var blocked []types.NamespacedName
+	var errs []error
+	for _, r := range resources {
+		err := c.opts.MetadataClient.Resource(obj.GroupVersionResource).Namespace(r.Namespace).Delete(
+			ctx, r.Name, metav1.DeleteOptions{PropagationPolicy: &policy, DryRun: []string{metav1.DryRunAll}},
+		)
+		if err == nil || apierrors.IsNotFound(err) || isNamespaceTerminating(err) {
+			continue
+		}
+		if webhook, ok := failingWebhook(err); ok && c.opts.BypassWebhooks && c.opts.Client != nil {
+			c.log.Info("Dry-run deletion blocked by an admission webhook that can't be called, its configuration will be deleted",
+				"name", r.Name, "namespace", r.Namespace, "gvr", gvrStr, "webhook", webhook)
+			continue
+		}
+		if strings.Contains(err.Error(), "does not support dry run") {
+			c.log.Info("WARNING: dry-run deletion not verified, an admission webhook doesn't support dry runs",
+				"name", r.Name, "namespace", r.Namespace, "gvr", gvrStr, "error", err.Error())
+			continue
+		}
+		if apierrors.IsForbidden(err) {
+			err = fmt.Errorf("%w: %w", ErrForbidden, err)
+		}
+		c.log.Error(err, "dry-run deletion blocked", "name", r.Name, "namespace", r.Namespace, "gvr", gvrStr)
+		key := types.NamespacedName{Namespace: r.Namespace, Name: r.Name}
+		blocked = append(blocked, key)
+		errs = append(errs, fmt.Errorf("%s: %w", key, err))
+	}
+	if len(blocked) == 0 {
+		return nil
+	}
+	return &ResourcesError{Resources: blocked, Err: errors.Join(errs...)}
+}
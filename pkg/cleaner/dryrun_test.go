@@ -0,0 +1,94 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestDryRun(t *testing.T) {
+	configMap := func(name string) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "widgets", Labels: map[string]string{"app": "widget"}},
+		}
+	}
+	denied := apierrors.NewForbidden(schema.GroupResource{Resource: "configmaps"}, "b",
+		errors.New(`admission webhook "deny.example.com" denied the request`))
+	unsupported := apierrors.NewBadRequest(`admission webhook "audit.example.com" does not support dry run`)
+
+	tests := []struct {
+		name            string
+		entries         []DeleteObj
+		expectedBlocked []types.NamespacedName
+		expectedDeletes int
+	}{
+		{
+			name:            "blocked resources reported",
+			entries:         []DeleteObj{{GroupVersionResource: configMapsGVR, Namespace: "widgets", LabelSelector: "app=widget", MustDelete: true}},
+			expectedBlocked: []types.NamespacedName{{Namespace: "widgets", Name: "b"}},
+			expectedDeletes: 3,
+		},
+		{
+			name:    "named resource already gone",
+			entries: []DeleteObj{{GroupVersionResource: configMapsGVR, Name: "gone", Namespace: "widgets", MustDelete: true}},
+		},
+		{
+			name: "only mustDelete delete entries",
+			entries: []DeleteObj{
+				{GroupVersionResource: configMapsGVR, Name: "b", Namespace: "widgets"},
+				{GroupVersionResource: configMapsGVR, Name: "b", Namespace: "widgets", MustDelete: true, Action: ActionRemoveMetadata, Labels: []string{"app"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), configMap("a"), configMap("b"), configMap("c"))
+			deletes := 0
+			client.PrependReactor("delete", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				deletes++
+				switch action.(clienttesting.DeleteAction).GetName() {
+				case "b":
+					return true, nil, denied
+				case "c":
+					return true, nil, unsupported
+				case "gone":
+					return true, nil, apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, "gone")
+				}
+				return true, nil, nil
+			})
+
+			err := New(Options{MetadataClient: client}).DryRun(context.Background(), tt.entries)
+			if deletes != tt.expectedDeletes {
+				t.Errorf("expected %d dry-run deletes, got %d", tt.expectedDeletes, deletes)
+			}
+			if tt.expectedBlocked == nil {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrMustDeleteFailed) || !errors.Is(err, ErrForbidden) {
+				t.Errorf("expected %v and %v, got %v", ErrMustDeleteFailed, ErrForbidden, err)
+			}
+			var mustDeleteErr *MustDeleteError
+			if !errors.As(err, &mustDeleteErr) || !reflect.DeepEqual(mustDeleteErr.Resources, tt.expectedBlocked) {
+				t.Errorf("expected blocked resources %v, got %v", tt.expectedBlocked, err)
+			}
+			for _, name := range []string{"a", "b", "c"} {
+				if _, err := client.Resource(configMapsGVR).Namespace("widgets").Get(context.Background(), name, metav1.GetOptions{}); err != nil {
+					t.Errorf("expected %s to remain, got %v", name, err)
+				}
+			}
+		})
+	}
+}
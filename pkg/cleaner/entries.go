@@ -0,0 +1,694 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+)
+
+var namespacesGVR = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+
+// ArgoCDHookDeletePolicyAnnotation marks an Argo CD hook resource that Argo CD deletes itself, per its policy
+const ArgoCDHookDeletePolicyAnnotation = "argocd.argoproj.io/hook-delete-policy"
+
+// DeleteObj is a resource config entry, i.e., the resources to apply an action to
+type DeleteObj struct {
+	schema.GroupVersionResource
+	Name      string
+	Namespace string
+
+	// LabelSelector applies an entry without a Name to every matching resource
+	// in Namespace, or in all namespaces if Namespace is empty
+	LabelSelector string
+
+	// Action is applied to each matching resource: delete (the default), removeFinalizers or removeMetadata
+	Action string
+
+	// Finalizers are stripped from each matching resource by the removeFinalizers action
+	Finalizers []string
+
+	// Labels and Annotations are the keys removed from each matching resource by the removeMetadata action
+	Labels      []string
+	Annotations []string
+
+	// MustDelete aborts the cleanup with an error if the entry's action fails,
+	// rather than logging the failure and moving on to the next resource
+	MustDelete bool
+
+	// TimeoutSeconds overrides Options.DeletionTimeout for the entry
+	TimeoutSeconds int64
+
+	// Wait makes the delete action block until the entry's resources are gone even if
+	// Options.DeletionTimeout is unset, e.g., so that later entries don't race their finalizers.
+	// The wait is bounded by TimeoutSeconds, or else by two minutes.
+	Wait bool
+
+	// WaitForDependents makes the delete action block until the dependents of the entry's resources,
+	// e.g., a Deployment's ReplicaSets and Pods, are gone too, which deleting with the background
+	// propagation policy doesn't guarantee. Dependents are found via DependentResources before each
+	// deletion. The wait is bounded by TimeoutSeconds, or else by Options.DeletionTimeout, or else by two minutes.
+	WaitForDependents bool
+
+	// Strategy names the DeletionStrategy the delete action uses: direct (the default), scaleThenDelete,
+	// finalizerStrip, foregroundWithTimeout, or one registered via Options.Strategies
+	Strategy string
+
+	// ConfirmHighRisk permits an entry without a name to delete every namespace, node or CRD, or
+	// to match more resources than Options.HighRiskThreshold
+	ConfirmHighRisk bool
+
+	// Preset, if set, selects a preset whose resource entries replace this one. See Presets.
+	Preset string
+
+	// AllResources applies the entry to every resource in Namespace matching LabelSelector, e.g., an
+	// ownership label, whatever its type. ExpandAllResources replaces the entry with one per resource
+	// type, so it may not set a resource or name.
+	AllResources bool
+
+	// Manifests, if set, is the path of previously applied manifests, e.g., a chart's rendered manifests:
+	// a file, directory or tarball. ExpandManifests replaces the entry with one per object of the
+	// manifests, in reverse order, so it may not set a resource, name or label selector.
+	Manifests string
+}
+
+// Resource config entry actions
+const (
+	// ActionDelete deletes the resource. This is the default.
+	ActionDelete = "delete"
+	// ActionRemoveFinalizers strips the entry's finalizers from the resource without deleting it, e.g.,
+	// when the controller responsible for a finalizer has been uninstalled
+	ActionRemoveFinalizers = "removeFinalizers"
+	// ActionRemoveMetadata removes the entry's labels and annotations from the resource without
+	// deleting it, e.g., to detach injection labels or ownership annotations
+	ActionRemoveMetadata = "removeMetadata"
+)
+
+// highRiskResources are those an entry may only delete cluster-wide, i.e., without a name or label
+// selector, if confirmed, as a mistyped entry would otherwise take down the cluster
+var highRiskResources = []schema.GroupResource{
+	{Resource: "namespaces"},
+	{Resource: "nodes"},
+	{Group: "apiextensions.k8s.io", Resource: "customresourcedefinitions"},
+}
+
+// Validate returns an error matching ErrConfigInvalid if an entry is misconfigured
+func (o DeleteObj) Validate() error {
+	if o.AllResources {
+		if o.GroupVersionResource != (schema.GroupVersionResource{}) || o.Name != "" {
+			return fmt.Errorf("%w: allResources entry in namespace %s may not set a resource or name", ErrConfigInvalid, o.Namespace)
+		}
+		if o.Namespace == "" || o.LabelSelector == "" {
+			return fmt.Errorf("%w: allResources entry requires a namespace and label selector", ErrConfigInvalid)
+		}
+	}
+	if o.Manifests != "" && (o.GroupVersionResource != (schema.GroupVersionResource{}) || o.Name != "" || o.LabelSelector != "" || o.AllResources) {
+		return fmt.Errorf("%w: manifests entry %s may not set a resource, name, label selector or allResources", ErrConfigInvalid, o.Manifests)
+	}
+	if o.Strategy != "" && o.Action != "" && o.Action != ActionDelete {
+		return fmt.Errorf("%w: resource entry %s %s/%s: strategy %q requires the %s action", ErrConfigInvalid, o.GroupVersionResource, o.Namespace, o.Name, o.Strategy, ActionDelete)
+	}
+	if o.Wait && o.Action != "" && o.Action != ActionDelete {
+		return fmt.Errorf("%w: resource entry %s %s/%s: wait requires the %s action", ErrConfigInvalid, o.GroupVersionResource, o.Namespace, o.Name, ActionDelete)
+	}
+	if o.WaitForDependents && o.Action != "" && o.Action != ActionDelete {
+		return fmt.Errorf("%w: resource entry %s %s/%s: waitForDependents requires the %s action", ErrConfigInvalid, o.GroupVersionResource, o.Namespace, o.Name, ActionDelete)
+	}
+	switch o.Action {
+	case "", ActionDelete:
+		if o.Name == "" && o.LabelSelector == "" && slices.Contains(highRiskResources, o.GroupVersionResource.GroupResource()) && !o.ConfirmHighRisk {
+			return fmt.Errorf("%w: %w: resource entry %s deletes every %s, confirmHighRisk is required", ErrConfigInvalid, ErrHighRiskUnconfirmed,
+				o.GroupVersionResource, o.GroupVersionResource.GroupResource())
+		}
+	case ActionRemoveFinalizers:
+		if len(o.Finalizers) == 0 {
+			return fmt.Errorf("%w: resource entry %s %s/%s: finalizers are required by the %s action", ErrConfigInvalid, o.GroupVersionResource, o.Namespace, o.Name, o.Action)
+		}
+	case ActionRemoveMetadata:
+		if len(o.Labels) == 0 && len(o.Annotations) == 0 {
+			return fmt.Errorf("%w: resource entry %s %s/%s: labels or annotations are required by the %s action", ErrConfigInvalid, o.GroupVersionResource, o.Namespace, o.Name, o.Action)
+		}
+	default:
+		return fmt.Errorf("%w: resource entry %s %s/%s: unknown action %q", ErrConfigInvalid, o.GroupVersionResource, o.Namespace, o.Name, o.Action)
+	}
+	return nil
+}
+
+// errUnexpanded is the failure of an entry setting AllResources or Manifests that wasn't replaced by
+// ExpandAllResources or ExpandManifests
+func errUnexpanded(obj DeleteObj) error {
+	if obj.Manifests != "" {
+		return fmt.Errorf("%w: manifests entry %s wasn't expanded by ExpandManifests", ErrConfigInvalid, obj.Manifests)
+	}
+	return fmt.Errorf("%w: allResources entry in namespace %s wasn't expanded by ExpandAllResources", ErrConfigInvalid, obj.Namespace)
+}
+
+// processEntry applies a resource config entry's action to each resource it matches. Resources
+// are listed and deleted via the metadata API, as only their object metadata is ever required,
+// and it negotiates protobuf rather than JSON with the API server for built-in types.
+// Resources are deleted by the entry's DeletionStrategy. If waiter is non-nil, deletions block until
+// the strategy has verified that the deleted resources are gone. Otherwise, if LoadBalancerTimeout
+// is set, deletions of Services block until their cloud load balancers have been released. If
+// bypassing webhooks is enabled, the configurations of admission webhooks blocking deletions because
+// they can't be called are deleted. If the entry sets WaitForDependents, the dependents of the deleted
+// resources must be gone too. Failures due to missing RBAC permissions match ErrForbidden.
+func (c *Cleaner) processEntry(ctx context.Context, obj DeleteObj, waiter *deletionWaiter) (err error) {
+	defer func() {
+		if apierrors.IsForbidden(err) && !isNamespaceTerminating(err) {
+			err = fmt.Errorf("%w: %w", ErrForbidden, err)
+		}
+	}()
+
+	if obj.AllResources || obj.Manifests != "" {
+		return errUnexpanded(obj)
+	}
+	switch obj.Action {
+	case ActionRemoveFinalizers:
+		return c.removeFinalizers(ctx, obj)
+	case ActionRemoveMetadata:
+		return c.removeMetadata(ctx, obj)
+	}
+
+	strategy, err := c.strategy(obj)
+	if err != nil {
+		return err
+	}
+	deleted, dependents, err := c.deleteResources(ctx, obj, strategy)
+	if waiter != nil {
+		if verifyErr := strategy.Verify(ctx, obj, deleted); verifyErr != nil {
+			return errors.Join(err, verifyErr)
+		}
+		if c.recreation != nil {
+			c.recreation.start(ctx, waiter, obj, deleted)
+		}
+	} else if c.lbWaiter != nil && obj.GroupVersionResource.GroupResource() == servicesGR {
+		if waitErr := c.waitForLoadBalancers(ctx, obj, deleted); waitErr != nil {
+			return errors.Join(err, waitErr)
+		}
+	}
+	if waitErr := c.waitForDependents(ctx, obj, dependents); waitErr != nil {
+		c.log.Error(waitErr, "dependent deletion not confirmed", "name", obj.Name, "namespace", obj.Namespace, "gvr", obj.GroupVersionResource.String())
+		return errors.Join(err, waitErr)
+	}
+	return err
+}
+
+// processEntries processes resource config entries, up to EntryConcurrency at a time. With the default
+// concurrency of 1, entries are processed strictly in order, so later entries may depend on earlier ones.
+// No further entries are started once ctx is done. The failures of MustDelete entries are returned;
+// if FailFast is set, no further entries are started once one has failed. The failures of delete-all
+// entries record which of the matched resources failed, rather than failing the entry as a whole.
+// If the Checkpoint option is set, entries
+// it records as completed are skipped, and each entry processed successfully is recorded.
+// If the RecreationWindow option is set, the entries' deleted resources are checked for having been
+// recreated concurrently, so that the entries' windows overlap, and the completion of each entry awaits
+// its check once every entry has been processed.
+// The outcome of every entry is returned, including those that were skipped.
+func (c *Cleaner) processEntries(ctx context.Context, objs []DeleteObj) (*Result, error) {
+	start := c.opts.Clock.Now()
+	result := &Result{Entries: make([]EntryResult, len(objs))}
+	for i, obj := range objs {
+		result.Entries[i] = EntryResult{Entry: obj, Status: StatusSkipped}
+	}
+	cp := c.opts.Checkpoint
+	sem := make(chan struct{}, c.opts.EntryConcurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	// pending holds the entries whose completion awaits their recreation check, by index
+	pending := map[int]*entryRun{}
+	complete := func(i int, entry EntryResult) {
+		result.Entries[i] = entry
+		if entry.Status == StatusSucceeded {
+			if cp != nil {
+				cp.Complete(i)
+			}
+		} else if entry.Entry.MustDelete {
+			mu.Lock()
+			errs = append(errs, &MustDeleteError{Entry: entry.Entry, Resources: failedResources(entry.Err), Err: entry.Err})
+			mu.Unlock()
+		}
+	}
+	for i, obj := range objs {
+		if cp != nil && cp.Completed(i) {
+			continue
+		}
+		sem <- struct{}{}
+		mu.Lock()
+		failed := len(errs) > 0
+		mu.Unlock()
+		if (failed && c.opts.FailFast) || ctx.Err() != nil {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, obj DeleteObj) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			ec := c
+			if obj.Wait && c.waiter == nil {
+				ec = c.waiting()
+			}
+			run := ec.startEntry(ctx, obj, ec.waiter)
+			if run.recreation.started {
+				mu.Lock()
+				pending[i] = run
+				mu.Unlock()
+				return
+			}
+			complete(i, run.complete(ctx))
+		}(i, obj)
+	}
+	wg.Wait()
+	for i := range objs {
+		if run, ok := pending[i]; ok {
+			complete(i, run.complete(ctx))
+		}
+	}
+	result.Duration = c.opts.Clock.Since(start)
+	return result, errors.Join(errs...)
+}
+
+// runEntry processes a resource config entry, calling the OnEntryComplete hook and emitting the
+// entry's progress events, and returns its outcome. An entry whose resources are already gone succeeds.
+func (c *Cleaner) runEntry(ctx context.Context, obj DeleteObj, waiter *deletionWaiter) EntryResult {
+	return c.startEntry(ctx, obj, waiter).complete(ctx)
+}
+
+// entryRun is a resource config entry that has been processed, but whose completion may await the
+// check of its deleted resources for having been recreated
+type entryRun struct {
+	c          *Cleaner
+	obj        DeleteObj
+	entry      *EntryResult
+	start      time.Time
+	err        error
+	recreation *recreationCheck
+}
+
+// startEntry processes a resource config entry, emitting its progress events, and starts the check
+// of its deleted resources for having been recreated, if enabled
+func (c *Cleaner) startEntry(ctx context.Context, obj DeleteObj, waiter *deletionWaiter) *entryRun {
+	run := &entryRun{obj: obj, entry: &EntryResult{Entry: obj}, start: c.opts.Clock.Now(), recreation: &recreationCheck{}}
+	run.c = c.recording(run.entry.record)
+	run.c.recreation = run.recreation
+	run.c.emit(Event{Type: EventEntryStarted, Entry: &obj, GroupVersionResource: obj.GroupVersionResource})
+	run.err = run.c.processEntry(ctx, obj, waiter)
+	return run
+}
+
+// complete waits for the entry's recreation check, if any, then calls the OnEntryComplete hook, emits
+// EventEntryCompleted, and returns the entry's outcome
+func (r *entryRun) complete(ctx context.Context) EntryResult {
+	err := r.err
+	if recreatedErr := r.recreation.wait(); recreatedErr != nil {
+		err = errors.Join(err, recreatedErr)
+	}
+	r.c.opts.Hooks.OnEntryComplete(ctx, r.obj, err)
+	r.c.emit(Event{Type: EventEntryCompleted, Entry: &r.obj, GroupVersionResource: r.obj.GroupVersionResource, Err: err})
+	entry := *r.entry
+	entry.Duration = r.c.opts.Clock.Since(r.start)
+	if err == nil || apierrors.IsNotFound(err) {
+		entry.Status = StatusSucceeded
+	} else {
+		entry.Status, entry.Err = StatusFailed, err
+	}
+	return entry
+}
+
+// matchingResources returns the metadata of the resource named by an entry or, if the entry has
+// no name, of every resource in its namespace matching its label selector. Entries without a name
+// matching more resources than the high-risk threshold fail unless confirmed.
+func (c *Cleaner) matchingResources(ctx context.Context, obj DeleteObj) ([]metav1.PartialObjectMetadata, error) {
+	ri := c.opts.MetadataClient.Resource(obj.GroupVersionResource).Namespace(obj.Namespace)
+	if obj.Name != "" {
+		m, err := ri.Get(ctx, obj.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if !c.withinTenant(*m) {
+			c.log.Info("WARNING: skipping resource outside the tenant", "name", m.Name, "namespace", m.Namespace,
+				"gvr", obj.GroupVersionResource.String(), "tenant", c.tenant.String())
+			return nil, nil
+		}
+		return []metav1.PartialObjectMetadata{*m}, nil
+	}
+	list, err := ri.List(ctx, metav1.ListOptions{LabelSelector: c.tenantSelector(obj.LabelSelector)})
+	if err != nil {
+		return nil, err
+	}
+	if threshold := c.opts.HighRiskThreshold; threshold > 0 && len(list.Items) > threshold && !obj.ConfirmHighRisk {
+		return nil, fmt.Errorf("%w: %d %s match, more than the high-risk threshold %d, confirmHighRisk is required",
+			ErrHighRiskUnconfirmed, len(list.Items), obj.GroupVersionResource, threshold)
+	}
+	return list.Items, nil
+}
+
+// planDeletion returns the resources planned for an entry by its strategy, except protected namespaces,
+// resources managed by infrastructure-as-code controllers unless ForceManaged is set, and, if
+// SkipArgoCDHooks is set, Argo CD hook resources
+func (c *Cleaner) planDeletion(ctx context.Context, obj DeleteObj, strategy DeletionStrategy) ([]metav1.PartialObjectMetadata, error) {
+	resources, err := strategy.Plan(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(resources, func(r metav1.PartialObjectMetadata) bool {
+		if obj.Name == "" && obj.GroupVersionResource == namespacesGVR && c.protected(r.Name) {
+			c.log.Info("Skipping protected namespace", "namespace", r.Name)
+			return true
+		}
+		if c.opts.SkipArgoCDHooks && r.Annotations[ArgoCDHookDeletePolicyAnnotation] != "" {
+			c.log.Info("Skipping Argo CD hook resource, Argo CD deletes it itself", "name", r.Name, "namespace", r.Namespace,
+				"gvr", obj.GroupVersionResource.String(), "policy", r.Annotations[ArgoCDHookDeletePolicyAnnotation])
+			return true
+		}
+		if controller := managingController(r); controller != "" && !c.opts.ForceManaged {
+			c.log.Info("WARNING: skipping resource managed by an external controller, set Options.ForceManaged to delete it",
+				"name", r.Name, "namespace", r.Namespace, "gvr", obj.GroupVersionResource.String(), "controller", controller)
+			return true
+		}
+		return false
+	}), nil
+}
+
+// deleteResources deletes the resources planned for an entry by its strategy, returning those deleted
+// and, if the entry sets WaitForDependents, their dependents. A resource that was already gone, or will
+// be deleted along with its namespace, needn't be waited for.
+func (c *Cleaner) deleteResources(ctx context.Context, obj DeleteObj, strategy DeletionStrategy) ([]metav1.PartialObjectMetadata, []dependent, error) {
+	gvrStr := obj.GroupVersionResource.String()
+	if obj.Name == "" {
+		c.log.Info("Deleting all matching resources", "namespace", obj.Namespace, "labelSelector", obj.LabelSelector, "gvr", gvrStr)
+	}
+	resources, err := c.planDeletion(ctx, obj, strategy)
+	if err != nil {
+		c.log.Error(err, "failed to list resources", "gvr", gvrStr)
+		return nil, nil, err
+	}
+	c.emit(Event{Type: EventResourcesMatched, Entry: &obj, GroupVersionResource: obj.GroupVersionResource, Count: len(resources)})
+
+	var deleted []metav1.PartialObjectMetadata
+	var dependents []dependent
+	var finder *dependentFinder
+	if obj.WaitForDependents {
+		finder = &dependentFinder{c: c}
+	}
+	var failed []types.NamespacedName
+	var errs []error
+	for _, r := range resources {
+		resource := types.NamespacedName{Namespace: r.Namespace, Name: r.Name}
+		if err := c.opts.Hooks.BeforeResourceDelete(ctx, obj, resource); err != nil {
+			c.log.Info("WARNING: resource deletion vetoed", "name", r.Name, "namespace", r.Namespace, "gvr", gvrStr, "reason", err.Error())
+			err = fmt.Errorf("%w: %w", ErrVetoed, err)
+			c.emit(resourceEvent(EventResourceFailed, obj, resource, err))
+			failed = append(failed, resource)
+			errs = append(errs, err)
+			continue
+		}
+		if allowed, err := c.checkPolicy(ctx, obj, r); err != nil {
+			c.emit(resourceEvent(EventResourceFailed, obj, resource, err))
+			failed = append(failed, resource)
+			errs = append(errs, err)
+			continue
+		} else if !allowed {
+			continue
+		}
+		if c.tenant != nil {
+			within, err := c.recheckTenant(ctx, obj, r)
+			if apierrors.IsNotFound(err) {
+				continue
+			} else if err != nil {
+				c.log.Error(err, "failed to verify tenant, skipping deletion", "name", r.Name, "namespace", r.Namespace, "gvr", gvrStr)
+				c.emit(resourceEvent(EventResourceFailed, obj, resource, err))
+				failed = append(failed, resource)
+				errs = append(errs, err)
+				continue
+			}
+			if !within {
+				c.log.Info("WARNING: resource outside the tenant, skipping", "name", r.Name, "namespace", r.Namespace, "gvr", gvrStr, "tenant", c.tenant.String())
+				event := resourceEvent(EventResourceSkipped, obj, resource, nil)
+				event.Reason = "outside tenant " + c.tenant.String()
+				c.emit(event)
+				continue
+			}
+		}
+		if c.manifests != nil {
+			if err := c.manifests.add(ctx, obj.GroupVersionResource, r); apierrors.IsNotFound(err) {
+				continue
+			} else if err != nil {
+				c.log.Error(err, "failed to archive resource manifest, skipping deletion", "name", r.Name, "namespace", r.Namespace, "gvr", gvrStr)
+				c.emit(resourceEvent(EventResourceFailed, obj, resource, err))
+				failed = append(failed, resource)
+				errs = append(errs, err)
+				continue
+			}
+		}
+		if c.flux != nil {
+			if err := c.suspendFluxOwners(ctx, r); err != nil {
+				c.log.Error(err, "failed to suspend Flux, skipping deletion", "name", r.Name, "namespace", r.Namespace, "gvr", gvrStr)
+				c.emit(resourceEvent(EventResourceFailed, obj, resource, err))
+				failed = append(failed, resource)
+				errs = append(errs, err)
+				continue
+			}
+		}
+		var owned []dependent
+		if finder != nil {
+			var err error
+			if owned, err = finder.dependents(ctx, obj.GroupVersionResource, r); err != nil {
+				c.log.Error(err, "failed to find dependents, skipping deletion", "name", r.Name, "namespace", r.Namespace, "gvr", gvrStr)
+				c.emit(resourceEvent(EventResourceFailed, obj, resource, err))
+				failed = append(failed, resource)
+				errs = append(errs, err)
+				continue
+			}
+		}
+		var snapshot *ResourceSnapshot
+		if obj.MustDelete {
+			snapshot = c.snapshot(ctx, obj.GroupVersionResource, r)
+		}
+		c.log.Info("Deleting resource", "name", r.Name, "namespace", r.Namespace, "gvr", gvrStr)
+		err := strategy.Delete(ctx, obj, r)
+		c.opts.Hooks.AfterResourceDelete(ctx, obj, resource, err)
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if isNamespaceTerminating(err) {
+			c.log.Info("Namespace terminating, resource will be deleted along with it", "name", r.Name, "namespace", r.Namespace, "gvr", gvrStr)
+			continue
+		} else if err != nil {
+			c.log.Error(err, "resource deletion failed", "name", r.Name, "namespace", r.Namespace, "gvr", gvrStr)
+			event := resourceEvent(EventResourceFailed, obj, resource, err)
+			event.Snapshot = snapshot
+			c.emit(event)
+			failed = append(failed, resource)
+			errs = append(errs, err)
+			continue
+		}
+		c.log.Info("Resource deletion successful", "name", r.Name, "namespace", r.Namespace, "gvr", gvrStr)
+		event := resourceEvent(EventResourceDeleted, obj, resource, nil)
+		event.Snapshot = snapshot
+		c.emit(event)
+		deleted = append(deleted, r)
+		dependents = append(dependents, owned...)
+	}
+	if len(errs) > 0 {
+		if obj.Name == "" {
+			c.log.Info("Deleted matching resources", "deleted", len(deleted), "failed", len(failed), "gvr", gvrStr)
+		}
+		return deleted, dependents, &ResourcesError{Resources: failed, Err: errors.Join(errs...)}
+	}
+	return deleted, dependents, nil
+}
+
+// removeFinalizers strips an entry's finalizers from each resource it matches. Each patch is
+// conditional on the resourceVersion, so that concurrent finalizer changes are never overwritten.
+func (c *Cleaner) removeFinalizers(ctx context.Context, obj DeleteObj) error {
+	gvrStr := obj.GroupVersionResource.String()
+	c.log.Info("Removing finalizers", "finalizers", obj.Finalizers, "name", obj.Name, "namespace", obj.Namespace,
+		"labelSelector", obj.LabelSelector, "gvr", gvrStr)
+	resources, err := c.matchingResources(ctx, obj)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		c.log.Error(err, "failed to get resources", "gvr", gvrStr)
+		return err
+	}
+	c.emit(Event{Type: EventResourcesMatched, Entry: &obj, GroupVersionResource: obj.GroupVersionResource, Count: len(resources)})
+
+	var failed []types.NamespacedName
+	var errs []error
+	for i := range resources {
+		r := &resources[i]
+		if allowed, err := c.checkPolicy(ctx, obj, *r); err != nil {
+			resource := types.NamespacedName{Namespace: r.Namespace, Name: r.Name}
+			c.emit(resourceEvent(EventResourceFailed, obj, resource, err))
+			failed = append(failed, resource)
+			errs = append(errs, err)
+			continue
+		} else if !allowed {
+			continue
+		}
+		ri := c.opts.MetadataClient.Resource(obj.GroupVersionResource).Namespace(r.Namespace)
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			finalizers, changed := withoutFinalizers(r.Finalizers, obj.Finalizers)
+			if !changed {
+				return nil
+			}
+			// a resource relabeled since it was listed may have left the tenant
+			if !c.withinTenant(*r) {
+				c.log.Info("WARNING: skipping resource outside the tenant", "name", r.Name, "namespace", r.Namespace, "gvr", gvrStr)
+				return nil
+			}
+			patch, _ := json.Marshal(map[string]interface{}{
+				"metadata": map[string]interface{}{"finalizers": finalizers, "resourceVersion": r.ResourceVersion},
+			})
+			err := c.retry(ctx, func(ctx context.Context) error {
+				_, err := ri.Patch(ctx, r.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+				return err
+			})
+			if apierrors.IsConflict(err) {
+				if latest, getErr := ri.Get(ctx, r.Name, metav1.GetOptions{}); getErr == nil {
+					r = latest
+				}
+				return err
+			} else if err != nil {
+				return err
+			}
+			c.log.Info("Finalizer removal successful", "name", r.Name, "namespace", r.Namespace, "gvr", gvrStr)
+			c.emit(resourceEvent(EventResourceUpdated, obj, types.NamespacedName{Namespace: r.Namespace, Name: r.Name}, nil))
+			return nil
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			c.log.Error(err, "finalizer removal failed", "name", r.Name, "namespace", r.Namespace, "gvr", gvrStr)
+			resource := types.NamespacedName{Namespace: r.Namespace, Name: r.Name}
+			c.emit(resourceEvent(EventResourceFailed, obj, resource, err))
+			failed = append(failed, resource)
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return &ResourcesError{Resources: failed, Err: errors.Join(errs...)}
+	}
+	return nil
+}
+
+// removeMetadata removes an entry's labels and annotations from each resource it matches
+func (c *Cleaner) removeMetadata(ctx context.Context, obj DeleteObj) error {
+	gvrStr := obj.GroupVersionResource.String()
+	c.log.Info("Removing labels and annotations", "labels", obj.Labels, "annotations", obj.Annotations, "name", obj.Name,
+		"namespace", obj.Namespace, "labelSelector", obj.LabelSelector, "gvr", gvrStr)
+	resources, err := c.matchingResources(ctx, obj)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		c.log.Error(err, "failed to get resources", "gvr", gvrStr)
+		return err
+	}
+	c.emit(Event{Type: EventResourcesMatched, Entry: &obj, GroupVersionResource: obj.GroupVersionResource, Count: len(resources)})
+
+	var failed []types.NamespacedName
+	var errs []error
+	for _, r := range resources {
+		patch, ok := metadataRemovalPatch(r.Labels, r.Annotations, obj.Labels, obj.Annotations)
+		if !ok {
+			continue
+		}
+		if allowed, err := c.checkPolicy(ctx, obj, r); err != nil {
+			resource := types.NamespacedName{Namespace: r.Namespace, Name: r.Name}
+			c.emit(resourceEvent(EventResourceFailed, obj, resource, err))
+			failed = append(failed, resource)
+			errs = append(errs, err)
+			continue
+		} else if !allowed {
+			continue
+		}
+		if err := c.retry(ctx, func(ctx context.Context) error {
+			_, err := c.opts.MetadataClient.Resource(obj.GroupVersionResource).Namespace(r.Namespace).Patch(
+				ctx, r.Name, types.MergePatchType, patch, metav1.PatchOptions{},
+			)
+			return err
+		}); err != nil && !apierrors.IsNotFound(err) {
+			c.log.Error(err, "label and annotation removal failed", "name", r.Name, "namespace", r.Namespace, "gvr", gvrStr)
+			resource := types.NamespacedName{Namespace: r.Namespace, Name: r.Name}
+			c.emit(resourceEvent(EventResourceFailed, obj, resource, err))
+			failed = append(failed, resource)
+			errs = append(errs, err)
+			continue
+		}
+		c.log.Info("Label and annotation removal successful", "name", r.Name, "namespace", r.Namespace, "gvr", gvrStr)
+		c.emit(resourceEvent(EventResourceUpdated, obj, types.NamespacedName{Namespace: r.Namespace, Name: r.Name}, nil))
+	}
+	if len(errs) > 0 {
+		return &ResourcesError{Resources: failed, Err: errors.Join(errs...)}
+	}
+	return nil
+}
+
+// metadataRemovalPatch returns a JSON merge patch removing whichever of the given label and
+// annotation keys are present, or false if none are
+func metadataRemovalPatch(labels, annotations map[string]string, removeLabels, removeAnnotations []string) ([]byte, bool) {
+	nulls := func(present map[string]string, remove []string) map[string]interface{} {
+		m := map[string]interface{}{}
+		for _, k := range remove {
+			if _, ok := present[k]; ok {
+				m[k] = nil
+			}
+		}
+		return m
+	}
+
+	metadata := map[string]interface{}{}
+	if l := nulls(labels, removeLabels); len(l) > 0 {
+		metadata["labels"] = l
+	}
+	if a := nulls(annotations, removeAnnotations); len(a) > 0 {
+		metadata["annotations"] = a
+	}
+	if len(metadata) == 0 {
+		return nil, false
+	}
+	patch, _ := json.Marshal(map[string]interface{}{"metadata": metadata})
+	return patch, true
+}
+
+// withoutFinalizers returns finalizers minus those in remove, and whether any were removed
+func withoutFinalizers(finalizers, remove []string) ([]string, bool) {
+	kept := make([]string, 0, len(finalizers))
+	for _, f := range finalizers {
+		if !slices.Contains(remove, f) {
+			kept = append(kept, f)
+		}
+	}
+	return kept, len(kept) != len(finalizers)
+}
+
+// isNamespaceTerminating reports whether a request was rejected because its namespace is terminating
+func isNamespaceTerminating(err error) bool {
+	return apierrors.HasStatusCause(err, corev1.NamespaceTerminatingCause)
+}
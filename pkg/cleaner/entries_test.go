@@ -0,0 +1,490 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	clienttesting "k8s.io/client-go/testing"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner/cleanertest"
+)
+
+func TestWithoutFinalizers(t *testing.T) {
+	tests := []struct {
+		name            string
+		finalizers      []string
+		remove          []string
+		expected        []string
+		expectedChanged bool
+	}{
+		{
+			name:            "finalizer removed",
+			finalizers:      []string{"kubernetes", "example.com/cleanup", "other.io/protect"},
+			remove:          []string{"example.com/cleanup"},
+			expected:        []string{"kubernetes", "other.io/protect"},
+			expectedChanged: true,
+		},
+		{
+			name:            "all finalizers removed",
+			finalizers:      []string{"example.com/cleanup"},
+			remove:          []string{"example.com/cleanup", "other.io/protect"},
+			expected:        []string{},
+			expectedChanged: true,
+		},
+		{
+			name:            "finalizer absent",
+			finalizers:      []string{"kubernetes"},
+			remove:          []string{"example.com/cleanup"},
+			expected:        []string{"kubernetes"},
+			expectedChanged: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finalizers, changed := withoutFinalizers(tt.finalizers, tt.remove)
+			if !reflect.DeepEqual(finalizers, tt.expected) {
+				t.Errorf("expected finalizers %v, got %v", tt.expected, finalizers)
+			}
+			if changed != tt.expectedChanged {
+				t.Errorf("expected changed %v, got %v", tt.expectedChanged, changed)
+			}
+		})
+	}
+}
+
+func TestMetadataRemovalPatch(t *testing.T) {
+	labels := map[string]string{"istio-injection": "enabled", "app": "web"}
+	annotations := map[string]string{"example.com/owner": "product"}
+
+	tests := []struct {
+		name              string
+		removeLabels      []string
+		removeAnnotations []string
+		expected          string
+		expectedOk        bool
+	}{
+		{
+			name:              "labels and annotations",
+			removeLabels:      []string{"istio-injection"},
+			removeAnnotations: []string{"example.com/owner"},
+			expected:          `{"metadata":{"annotations":{"example.com/owner":null},"labels":{"istio-injection":null}}}`,
+			expectedOk:        true,
+		},
+		{
+			name:         "absent keys skipped",
+			removeLabels: []string{"istio-injection", "absent"},
+			expected:     `{"metadata":{"labels":{"istio-injection":null}}}`,
+			expectedOk:   true,
+		},
+		{
+			name:              "nothing to remove",
+			removeLabels:      []string{"absent"},
+			removeAnnotations: []string{"absent"},
+			expectedOk:        false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, ok := metadataRemovalPatch(labels, annotations, tt.removeLabels, tt.removeAnnotations)
+			if ok != tt.expectedOk {
+				t.Fatalf("expected ok %v, got %v", tt.expectedOk, ok)
+			}
+			if string(patch) != tt.expected {
+				t.Errorf("expected patch %s, got %s", tt.expected, patch)
+			}
+		})
+	}
+}
+
+func TestProcessEntries(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	configMap := func(namespace string) runtime.Object {
+		return &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: namespace},
+		}
+	}
+	namespaces := []string{"a", "b", "c", "d", "e"}
+
+	tests := []struct {
+		name            string
+		concurrency     int
+		failFast        bool
+		expectedDeleted []string
+		expectedError   bool
+	}{
+		{
+			name:            "sequential",
+			concurrency:     1,
+			failFast:        true,
+			expectedDeleted: []string{"a", "b", "c", "d", "e"},
+		},
+		{
+			name:            "concurrent",
+			concurrency:     3,
+			expectedDeleted: []string{"a", "b", "c", "d", "e"},
+		},
+		{
+			name:            "fail fast on required entry",
+			concurrency:     1,
+			failFast:        true,
+			expectedDeleted: []string{"a", "b"},
+			expectedError:   true,
+		},
+		{
+			name:            "continue after required entry",
+			concurrency:     1,
+			expectedDeleted: []string{"a", "b", "d", "e"},
+			expectedError:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var objs []runtime.Object
+			var entries []DeleteObj
+			for _, ns := range namespaces {
+				objs = append(objs, configMap(ns))
+				entries = append(entries, DeleteObj{GroupVersionResource: gvr, Namespace: ns, MustDelete: ns == "c"})
+			}
+			client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), objs...)
+			if tt.expectedError {
+				client.PrependReactor("delete", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+					if action.GetNamespace() == "c" {
+						return true, nil, apierrors.NewForbidden(gvr.GroupResource(), "cm", errors.New("denied"))
+					}
+					return false, nil, nil
+				})
+			}
+
+			c := New(Options{MetadataClient: client, EntryConcurrency: tt.concurrency, FailFast: tt.failFast})
+			_, err := c.CleanupResources(context.Background(), entries)
+			if err != nil && !tt.expectedError {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if err == nil && tt.expectedError {
+				t.Fatalf("expected error, got nil")
+			}
+			var mustDeleteErr *MustDeleteError
+			if tt.expectedError && (!errors.As(err, &mustDeleteErr) || !errors.Is(err, ErrForbidden)) {
+				t.Errorf("expected %v and %v, got %v", ErrMustDeleteFailed, ErrForbidden, err)
+			}
+			if mustDeleteErr != nil && mustDeleteErr.Entry.Namespace != "c" {
+				t.Errorf("expected failed entry in namespace c, got %q", mustDeleteErr.Entry.Namespace)
+			}
+
+			var deleted []string
+			for _, ns := range namespaces {
+				_, err := client.Resource(gvr).Namespace(ns).Get(context.Background(), "cm", metav1.GetOptions{})
+				if apierrors.IsNotFound(err) {
+					deleted = append(deleted, ns)
+				}
+			}
+			if !reflect.DeepEqual(deleted, tt.expectedDeleted) {
+				t.Errorf("expected %v deleted, got %v", tt.expectedDeleted, deleted)
+			}
+		})
+	}
+}
+
+func TestProcessEntriesFailedResources(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	names := []string{"a", "b", "c"}
+	var objs []runtime.Object
+	for _, name := range names {
+		objs = append(objs, &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		})
+	}
+	client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), objs...)
+	client.PrependReactor("delete", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if action.(clienttesting.DeleteAction).GetName() == "b" {
+			return true, nil, apierrors.NewForbidden(gvr.GroupResource(), "b", errors.New("denied"))
+		}
+		return false, nil, nil
+	})
+	entries := []DeleteObj{{GroupVersionResource: gvr, Namespace: "default", MustDelete: true}}
+
+	_, err := New(Options{MetadataClient: client}).CleanupResources(context.Background(), entries)
+	var mustDeleteErr *MustDeleteError
+	if !errors.As(err, &mustDeleteErr) {
+		t.Fatalf("expected %v, got %v", ErrMustDeleteFailed, err)
+	}
+	expected := []types.NamespacedName{{Namespace: "default", Name: "b"}}
+	if !reflect.DeepEqual(mustDeleteErr.Resources, expected) {
+		t.Errorf("expected failed resources %v, got %v", expected, mustDeleteErr.Resources)
+	}
+
+	var deleted []string
+	for _, name := range names {
+		_, err := client.Resource(gvr).Namespace("default").Get(context.Background(), name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			deleted = append(deleted, name)
+		}
+	}
+	if expected := []string{"a", "c"}; !reflect.DeepEqual(deleted, expected) {
+		t.Errorf("expected %v deleted, got %v", expected, deleted)
+	}
+}
+
+func TestValidateHighRisk(t *testing.T) {
+	namespaces := schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+	crds := schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+
+	tests := []struct {
+		name          string
+		entry         DeleteObj
+		expectedError bool
+	}{
+		{
+			name:          "every namespace",
+			entry:         DeleteObj{GroupVersionResource: namespaces},
+			expectedError: true,
+		},
+		{
+			name:          "every crd",
+			entry:         DeleteObj{GroupVersionResource: crds},
+			expectedError: true,
+		},
+		{
+			name:  "every namespace confirmed",
+			entry: DeleteObj{GroupVersionResource: namespaces, ConfirmHighRisk: true},
+		},
+		{
+			name:  "selected namespaces",
+			entry: DeleteObj{GroupVersionResource: namespaces, LabelSelector: "app=example"},
+		},
+		{
+			name:  "named namespace",
+			entry: DeleteObj{GroupVersionResource: namespaces, Name: "example"},
+		},
+		{
+			name:  "finalizer removal",
+			entry: DeleteObj{GroupVersionResource: crds, Action: ActionRemoveFinalizers, Finalizers: []string{"example.com/finalizer"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.entry.Validate()
+			if err != nil && !tt.expectedError {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if err == nil && tt.expectedError {
+				t.Fatalf("expected error, got nil")
+			}
+			if err != nil && (!errors.Is(err, ErrHighRiskUnconfirmed) || !errors.Is(err, ErrConfigInvalid)) {
+				t.Errorf("expected %v, got %v", ErrHighRiskUnconfirmed, err)
+			}
+		})
+	}
+}
+
+func TestHighRiskThreshold(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	var objs []runtime.Object
+	for _, name := range []string{"a", "b"} {
+		objs = append(objs, &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		})
+	}
+
+	tests := []struct {
+		name            string
+		confirmed       bool
+		expectedDeletes int
+		expectedError   bool
+	}{
+		{
+			name:          "unconfirmed",
+			expectedError: true,
+		},
+		{
+			name:            "confirmed",
+			confirmed:       true,
+			expectedDeletes: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), objs...)
+			entry := DeleteObj{GroupVersionResource: gvr, Namespace: "default", ConfirmHighRisk: tt.confirmed}
+
+			err := New(Options{MetadataClient: client, HighRiskThreshold: 1}).processEntry(context.Background(), entry, nil)
+			if err != nil && !tt.expectedError {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if tt.expectedError && !errors.Is(err, ErrHighRiskUnconfirmed) {
+				t.Fatalf("expected %v, got %v", ErrHighRiskUnconfirmed, err)
+			}
+			if deletes := countVerb(client.Actions(), "delete"); deletes != tt.expectedDeletes {
+				t.Errorf("expected %d deletes, got %d", tt.expectedDeletes, deletes)
+			}
+		})
+	}
+}
+
+func TestProcessEntrySkipsVerification(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	named := DeleteObj{GroupVersionResource: gvr, Name: "gone", Namespace: "default"}
+	deleteAll := DeleteObj{GroupVersionResource: gvr, Namespace: "default"}
+	notFound := apierrors.NewNotFound(gvr.GroupResource(), "deleted-concurrently")
+	terminating := &apierrors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    403,
+		Reason:  metav1.StatusReasonForbidden,
+		Details: &metav1.StatusDetails{Causes: []metav1.StatusCause{{Type: corev1.NamespaceTerminatingCause}}},
+	}}
+
+	tests := []struct {
+		name          string
+		entry         DeleteObj
+		err           error
+		expectedLists int
+	}{
+		{name: "named not found", entry: named, err: notFound, expectedLists: 0},
+		{name: "delete-all not found", entry: deleteAll, err: notFound, expectedLists: 1},
+		{name: "named in terminating namespace", entry: named, err: terminating, expectedLists: 0},
+		{name: "delete-all in terminating namespace", entry: deleteAll, err: terminating, expectedLists: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), &metav1.PartialObjectMetadata{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+				ObjectMeta: metav1.ObjectMeta{Name: "deleted-concurrently", Namespace: "default"},
+			})
+			client.PrependReactor("delete", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				return true, nil, tt.err
+			})
+			c := New(Options{MetadataClient: client, DeletionTimeout: time.Second})
+
+			if err := c.processEntry(context.Background(), tt.entry, c.waiter); err != nil && !apierrors.IsNotFound(err) {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			// the only list is the delete-all entry's own, not a verification
+			if lists := countVerb(client.Actions(), "list"); lists != tt.expectedLists {
+				t.Errorf("expected %d lists, got %d", tt.expectedLists, lists)
+			}
+			if watches := countVerb(client.Actions(), "watch"); watches != 0 {
+				t.Errorf("expected 0 watches, got %d", watches)
+			}
+		})
+	}
+}
+
+func TestProcessEntriesWait(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+	tests := []struct {
+		name          string
+		wait          bool
+		expectedError error
+	}{
+		{name: "without wait"},
+		{name: "wait", wait: true, expectedError: ErrDeletionTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), &metav1.PartialObjectMetadata{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+				ObjectMeta: metav1.ObjectMeta{Name: "stuck", Namespace: "default", Finalizers: []string{"example.com/finalizer"}},
+			})
+			// the finalizer is never removed, so the resource remains
+			client.PrependReactor("delete", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				return true, nil, nil
+			})
+			fakeClock := testingclock.NewFakeClock(time.Now())
+			if tt.wait {
+				go cleanertest.StepWhenWaiting(fakeClock, defaultWaitTimeout)
+			}
+
+			entries := []DeleteObj{{GroupVersionResource: gvr, Name: "stuck", Namespace: "default", Wait: tt.wait}}
+			result, err := New(Options{MetadataClient: client, Clock: fakeClock}).CleanupResources(context.Background(), entries)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !errors.Is(result.Entries[0].Err, tt.expectedError) {
+				t.Errorf("expected entry error %v, got %v", tt.expectedError, result.Entries[0].Err)
+			}
+		})
+	}
+}
+
+func TestSkipArgoCDHooks(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	objs := []runtime.Object{
+		&metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "default"},
+		},
+		&metav1.PartialObjectMetadata{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: "presync", Namespace: "default", Annotations: map[string]string{
+				ArgoCDHookDeletePolicyAnnotation: "HookSucceeded",
+			}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		entryName string
+		skip      bool
+		remaining []string
+	}{
+		{name: "disabled", remaining: nil},
+		{name: "enabled", skip: true, remaining: []string{"presync"}},
+		{name: "named disabled", entryName: "presync", remaining: []string{"settings"}},
+		{name: "named enabled", entryName: "presync", skip: true, remaining: []string{"presync", "settings"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), objs...)
+			entry := DeleteObj{GroupVersionResource: gvr, Name: tt.entryName, Namespace: "default"}
+			if err := New(Options{MetadataClient: client, SkipArgoCDHooks: tt.skip}).processEntry(context.Background(), entry, nil); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			list, err := client.Resource(gvr).Namespace("default").List(context.Background(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var remaining []string
+			for _, item := range list.Items {
+				remaining = append(remaining, item.Name)
+			}
+			slices.Sort(remaining)
+			if !reflect.DeepEqual(remaining, tt.remaining) {
+				t.Errorf("expected remaining %v, got %v", tt.remaining, remaining)
+			}
+		})
+	}
+}
+
+func countVerb(actions []clienttesting.Action, verb string) int {
+	n := 0
+	for _, a := range actions {
+		if a.GetVerb() == verb {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,93 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Failure classes of a cleanup, to be matched with errors.Is
+var (
+	// ErrConfigInvalid is returned for a malformed file, resource or rule config
+	ErrConfigInvalid = errors.New("invalid cleanup config")
+	// ErrDeletionTimeout is returned when deleted resources remain after the deletion timeout
+	ErrDeletionTimeout = errors.New("timed out waiting for deletion")
+	// ErrForbidden is returned when spectro-cleanup lacks the RBAC permissions for an entry's action
+	ErrForbidden = errors.New("forbidden")
+	// ErrRecreated is returned when deleted resources reappear, e.g., when a still running controller recreates them
+	ErrRecreated = errors.New("resources recreated after deletion")
+	// ErrMustDeleteFailed is returned when the action of a MustDelete resource config entry fails
+	ErrMustDeleteFailed = errors.New("failed to clean up required resource")
+	// ErrHighRiskUnconfirmed is returned for a high-risk resource config entry without confirmHighRisk
+	ErrHighRiskUnconfirmed = errors.New("high-risk entry not confirmed")
+	// ErrVetoed is returned when a resource's deletion is vetoed by Hooks.BeforeResourceDelete
+	ErrVetoed = errors.New("deletion vetoed")
+)
+
+// MustDeleteError is the failure of a MustDelete resource config entry. It matches both
+// ErrMustDeleteFailed and the underlying error.
+type MustDeleteError struct {
+	Entry DeleteObj
+	// Resources are those of the resources matched by the entry that failed to be cleaned up. It is
+	// empty if the failure is not specific to individual resources, e.g., if listing them failed.
+	Resources []types.NamespacedName
+	Err       error
+}
+
+func (e *MustDeleteError) Error() string {
+	if len(e.Resources) > 0 {
+		return fmt.Sprintf("%s %s %s/%s: %d resources failed %v: %v", ErrMustDeleteFailed, e.Entry.GroupVersionResource,
+			e.Entry.Namespace, e.Entry.Name, len(e.Resources), e.Resources, e.Err)
+	}
+	return fmt.Sprintf("%s %s %s/%s: %v", ErrMustDeleteFailed, e.Entry.GroupVersionResource, e.Entry.Namespace, e.Entry.Name, e.Err)
+}
+
+func (e *MustDeleteError) Unwrap() []error {
+	return []error{ErrMustDeleteFailed, e.Err}
+}
+
+// ResourcesError is a failure affecting some of the resources matched by a resource config entry
+type ResourcesError struct {
+	Resources []types.NamespacedName
+	Err       error
+}
+
+func (e *ResourcesError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ResourcesError) Unwrap() error {
+	return e.Err
+}
+
+// failedResources returns the resources of every ResourcesError within err
+func failedResources(err error) []types.NamespacedName {
+	switch e := err.(type) {
+	case *ResourcesError:
+		return e.Resources
+	case interface{ Unwrap() []error }:
+		var resources []types.NamespacedName
+		for _, err := range e.Unwrap() {
+			resources = append(resources, failedResources(err)...)
+		}
+		return resources
+	case interface{ Unwrap() error }:
+		return failedResources(e.Unwrap())
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// EventType is the kind of progress an Event reports
+type EventType string
+
+// Progress events of a cleanup
+const (
+	// EventEntryStarted is emitted when a resource config entry starts being processed
+	EventEntryStarted EventType = "EntryStarted"
+	// EventEntryCompleted is emitted once a resource config entry has been processed, with its error, if any
+	EventEntryCompleted EventType = "EntryCompleted"
+	// EventResourcesMatched is emitted once the resources a resource config entry acts on are matched, with their count
+	EventResourcesMatched EventType = "ResourcesMatched"
+	// EventResourceDeleted is emitted when a resource is deleted
+	EventResourceDeleted EventType = "ResourceDeleted"
+	// EventResourceUpdated is emitted when a resource's finalizers, labels or annotations are removed
+	EventResourceUpdated EventType = "ResourceUpdated"
+	// EventResourceFailed is emitted when an entry's action fails for a resource, including when its deletion is vetoed
+	EventResourceFailed EventType = "ResourceFailed"
+	// EventResourceSkipped is emitted when a resource is not deleted by design, i.e., as its deletion
+	// is denied by the Policy option, or it is outside the tenant of the TenantLabels option
+	EventResourceSkipped EventType = "ResourceSkipped"
+	// EventFileDeleted is emitted when a file is deleted
+	EventFileDeleted EventType = "FileDeleted"
+	// EventFileSkipped is emitted when a file is not deleted by design, e.g., as its content does not
+	// match the expected content, or its deletion is vetoed
+	EventFileSkipped EventType = "FileSkipped"
+	// EventFileFailed is emitted when a file fails to be deleted
+	EventFileFailed EventType = "FileFailed"
+)
+
+// Event reports the progress of a cleanup
+type Event struct {
+	Type EventType
+	Time time.Time
+
+	// Entry is the resource config entry of entry and resource events
+	Entry *DeleteObj
+
+	// GroupVersionResource and Resource identify the resource of resource events
+	GroupVersionResource schema.GroupVersionResource
+	Resource             types.NamespacedName
+
+	// Path is the file of file events
+	Path string
+
+	// Reason explains why a file or resource was skipped
+	Reason string
+
+	// Count is the number of resources of matched events
+	Count int
+
+	// Snapshot is the state of the resource before its deletion, of the deleted and failed events of
+	// MustDelete entries
+	Snapshot *ResourceSnapshot
+
+	// Err is the failure of failed events, and of completed entries that failed
+	Err error
+}
+
+// EventSink receives the progress events of a cleanup. Emit may be called concurrently if
+// EntryConcurrency is greater than 1, and must not block.
+type EventSink interface {
+	Emit(event Event)
+}
+
+// EventSinkFunc adapts a function to an EventSink
+type EventSinkFunc func(event Event)
+
+// Emit implements EventSink
+func (f EventSinkFunc) Emit(event Event) {
+	f(event)
+}
+
+// emit sends an event to the EventSink option, if set
+func (c *Cleaner) emit(event Event) {
+	if c.opts.EventSink == nil {
+		return
+	}
+	event.Time = c.opts.Clock.Now()
+	c.opts.EventSink.Emit(event)
+}
+
+// resourceEvent returns an event concerning one of the resources matched by an entry
+func resourceEvent(eventType EventType, obj DeleteObj, resource types.NamespacedName, err error) Event {
+	return Event{Type: eventType, Entry: &obj, GroupVersionResource: obj.GroupVersionResource, Resource: resource, Err: err}
+}
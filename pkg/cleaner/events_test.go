@@ -0,0 +1,88 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner/cleanertest"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// eventRecorder returns an EventSink recording the type and subject of each event
+func eventRecorder(events *[]string) EventSink {
+	var mu sync.Mutex
+	return EventSinkFunc(func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		if e.Time.IsZero() {
+			*events = append(*events, "missing time")
+		}
+		subject := e.Resource.Name + e.Path
+		if e.Err != nil {
+			subject += " error"
+		}
+		*events = append(*events, string(e.Type)+" "+subject)
+	})
+}
+
+func TestResourceEvents(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	var objs []runtime.Object
+	for _, name := range []string{"a", "b"} {
+		objs = append(objs, &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		})
+	}
+	client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), objs...)
+	client.PrependReactor("delete", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if action.(clienttesting.DeleteAction).GetName() == "b" {
+			return true, nil, apierrors.NewForbidden(gvr.GroupResource(), "b", errors.New("denied"))
+		}
+		return false, nil, nil
+	})
+
+	var events []string
+	entries := []DeleteObj{{GroupVersionResource: gvr, Namespace: "default"}}
+	_, _ = New(Options{MetadataClient: client, EventSink: eventRecorder(&events)}).CleanupResources(context.Background(), entries)
+
+	expected := []string{"EntryStarted ", "ResourcesMatched ", "ResourceDeleted a", "ResourceFailed b error", "EntryCompleted  error"}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected events %q, got %q", expected, events)
+	}
+}
+
+func TestFileEvents(t *testing.T) {
+	fsys := cleanertest.MapFS{MapFS: fstest.MapFS{
+		"etc/cni/net.d/00-multus.conf":     {Data: []byte(`{"type": "multus"}`)},
+		"etc/cni/net.d/10-calico.conflist": {Data: []byte(`{"type": "calico"}`)},
+	}}
+	files := []FileEntry{
+		{Path: "/etc/cni/net.d/00-multus.conf"},
+		{Path: "/etc/cni/net.d/10-calico.conflist", ExpectedContent: "multus"},
+		{Path: "/etc/cni/net.d/99-missing.conf"},
+	}
+
+	var events []string
+	if _, err := New(Options{FS: fsys, EventSink: eventRecorder(&events)}).CleanupFiles(context.Background(), files); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []string{
+		"FileDeleted /etc/cni/net.d/00-multus.conf",
+		"FileSkipped /etc/cni/net.d/10-calico.conflist",
+		"FileFailed /etc/cni/net.d/99-missing.conf error",
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected events %q, got %q", expected, events)
+	}
+}
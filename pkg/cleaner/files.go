@@ -0,0 +1,335 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/go-logr/logr"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/internal/command"
+)
+
+// FileEntry is a file to be deleted from the node. In the file cleanup config,
+// an entry may either be a plain path string or an object.
+type FileEntry struct {
+	Path string `json:"path"`
+
+	// Glob treats Path as a glob pattern, with the syntax of filepath.Match, replacing the entry with
+	// an entry per matching file. Otherwise, Path is literal, even if it contains special characters.
+	Glob bool `json:"glob,omitempty"`
+
+	// Preset, if set, selects a preset whose file entries replace this one. See Presets.
+	Preset string `json:"preset,omitempty"`
+
+	// PruneEmptyParents removes each parent directory left empty after the file
+	// is deleted, walking upwards until PruneBoundary is reached. The boundary
+	// directory itself is never removed.
+	PruneEmptyParents bool   `json:"pruneEmptyParents,omitempty"`
+	PruneBoundary     string `json:"pruneBoundary,omitempty"`
+
+	// PostDeleteCommands are run in order once the file has been deleted, e.g., to restart
+	// a service so that the removal takes effect. Each command is an argv list.
+	PostDeleteCommands [][]string `json:"postDeleteCommands,omitempty"`
+
+	// ExpectedContent and ExpectedSHA256 guard against deleting a file that another component
+	// has since replaced with its own. When set, the file is only deleted if it contains the
+	// substring and/or its hex-encoded sha256 digest matches.
+	ExpectedContent string `json:"expectedContent,omitempty"`
+	ExpectedSHA256  string `json:"expectedSha256,omitempty"`
+}
+
+// UnmarshalJSON allows a FileEntry to be specified as a plain path string
+func (f *FileEntry) UnmarshalJSON(data []byte) error {
+	var path string
+	if err := json.Unmarshal(data, &path); err == nil {
+		*f = FileEntry{Path: path}
+		return nil
+	}
+	type fileEntry FileEntry
+	return json.Unmarshal(data, (*fileEntry)(f))
+}
+
+// applyHostRoot prefixes the entry's host paths with the directory the host's root
+// filesystem is mounted at, allowing configs to reference real host paths
+func (f *FileEntry) applyHostRoot(root string) {
+	if root == "" {
+		return
+	}
+	f.Path = filepath.Join(root, f.Path)
+	if f.PruneBoundary != "" {
+		f.PruneBoundary = filepath.Join(root, f.PruneBoundary)
+	}
+}
+
+// matchesExpectedContent reports whether the file's content satisfies the entry's content guards.
+// Reading the file is abandoned once ctx is done.
+func (f *FileEntry) matchesExpectedContent(ctx context.Context, fsys FS) (bool, error) {
+	if f.ExpectedContent == "" && f.ExpectedSHA256 == "" {
+		return true, nil
+	}
+	file, err := fsys.Open(f.Path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(contextReader{ctx: ctx, r: file})
+	if err != nil {
+		return false, err
+	}
+	if f.ExpectedContent != "" && !bytes.Contains(data, []byte(f.ExpectedContent)) {
+		return false, nil
+	}
+	if f.ExpectedSHA256 != "" {
+		sum := sha256.Sum256(data)
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), f.ExpectedSHA256) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// CleanupFiles deletes files from the node, returning the outcome of each file. Entries the process
+// lacks the permissions to delete, e.g., when running as non-root, are skipped. Files are deleted until
+// ctx is done, interrupting any in-flight content check, archival, parent pruning or post-deletion
+// command, after which the archive is flushed and ctx's error is returned.
+func (c *Cleaner) CleanupFiles(ctx context.Context, files []FileEntry) (*Result, error) {
+	start := c.opts.Clock.Now()
+	result := &Result{}
+	err := c.recording(result.record).cleanupFiles(ctx, files)
+	result.Duration = c.opts.Clock.Since(start)
+	return result, err
+}
+
+// cleanupFiles deletes files from the node. See CleanupFiles.
+func (c *Cleaner) cleanupFiles(ctx context.Context, files []FileEntry) error {
+	files = c.resolveFiles(files)
+	checkCapabilities(c.log, c.opts.Unmount, c.opts.ClearImmutable)
+
+	// optionally retain a copy of every deleted file for auditing
+	var archive *fileArchive
+	if c.opts.ArchiveDir != "" {
+		var err error
+		archive, err = newFileArchive(c.opts.ArchiveDir, c.opts.FS, c.opts.Clock.Now())
+		if err != nil {
+			return err
+		}
+		c.log.Info("Archiving deleted files", "path", archive.path)
+		defer func() {
+			if err := archive.Close(); err != nil {
+				c.log.Error(err, "failed to close file archive", "path", archive.path)
+			}
+		}()
+	}
+
+	for _, file := range files {
+		// stop early when interrupted, ensuring the archive is flushed
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		matches, err := file.matchesExpectedContent(ctx, c.opts.FS)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			c.log.Error(err, "file content check failed, skipping deletion", "path", file.Path)
+			c.emit(Event{Type: EventFileFailed, Path: file.Path, Err: err})
+			continue
+		}
+		if !matches {
+			c.log.Info("WARNING: file content does not match expected content, skipping deletion", "path", file.Path)
+			c.emit(Event{Type: EventFileSkipped, Path: file.Path, Reason: "content does not match expected content"})
+			continue
+		}
+
+		if err := c.opts.Hooks.BeforeFileDelete(ctx, file); err != nil {
+			c.log.Info("WARNING: file deletion vetoed, skipping", "path", file.Path, "reason", err.Error())
+			c.emit(Event{Type: EventFileSkipped, Path: file.Path, Reason: "deletion vetoed: " + err.Error()})
+			continue
+		}
+
+		if archive != nil {
+			if err := archive.add(ctx, file.Path); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				c.log.Error(err, "file archival failed, skipping deletion", "path", file.Path)
+				c.emit(Event{Type: EventFileFailed, Path: file.Path, Err: err})
+				continue
+			}
+		}
+
+		if c.opts.Unmount {
+			if err := unmountIfMounted(c.log, file.Path); err != nil {
+				c.log.Error(err, "unmount failed", "path", file.Path)
+				c.emit(Event{Type: EventFileFailed, Path: file.Path, Err: err})
+				continue
+			}
+		}
+
+		if c.opts.ClearImmutable {
+			cleared, err := clearImmutable(file.Path)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				c.log.Error(err, "failed to clear immutable attributes", "path", file.Path)
+			} else if cleared {
+				c.log.Info("Cleared immutable attributes", "path", file.Path)
+			}
+		}
+
+		c.log.Info("Deleting file", "path", file.Path)
+		if err := c.opts.FS.Remove(file.Path); err != nil {
+			if errors.Is(err, syscall.EBUSY) && !c.opts.Unmount {
+				c.log.Info("WARNING: path may be a mount point, set Options.Unmount to unmount it before removal", "path", file.Path)
+			}
+			c.log.Error(err, "file deletion failed")
+			c.emit(Event{Type: EventFileFailed, Path: file.Path, Err: err})
+			continue
+		}
+		c.log.Info("File deletion successful")
+		c.emit(Event{Type: EventFileDeleted, Path: file.Path})
+
+		if file.PruneEmptyParents {
+			pruneEmptyParents(ctx, c.log, c.opts.FS, file.Path, file.PruneBoundary)
+		}
+
+		for _, cmd := range file.PostDeleteCommands {
+			if err := command.Run(ctx, c.opts.CommandTimeout, cmd); err != nil {
+				c.log.Error(err, "post-deletion command failed", "path", file.Path, "command", cmd)
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// resolveFiles returns the file entries to delete, prefixed with the host root, with glob patterns
+// expanded, and without those the process lacks the permissions to delete
+func (c *Cleaner) resolveFiles(files []FileEntry) []FileEntry {
+	files = slices.Clone(files)
+	for i := range files {
+		files[i].applyHostRoot(c.opts.HostRoot)
+	}
+	files = expandGlobs(c.log, c.opts.FS, files)
+	if _, ok := c.opts.FS.(OSFS); ok {
+		files = c.removableFiles(files)
+	}
+	return files
+}
+
+// expandGlobs replaces each file entry whose path is a glob pattern, i.e., that sets Glob, with an entry
+// per matching file. Patterns matching no files are dropped.
+func expandGlobs(log logr.Logger, fsys FS, files []FileEntry) []FileEntry {
+	expanded := make([]FileEntry, 0, len(files))
+	for _, file := range files {
+		if !file.Glob {
+			expanded = append(expanded, file)
+			continue
+		}
+		matches, err := fsys.Glob(file.Path)
+		if err != nil {
+			log.Error(err, "invalid file path pattern, skipping", "path", file.Path)
+			continue
+		}
+		if len(matches) == 0 {
+			log.Info("No files match path pattern", "path", file.Path)
+		}
+		for _, match := range matches {
+			entry := file
+			entry.Path, entry.Glob = match, false
+			expanded = append(expanded, entry)
+		}
+	}
+	return expanded
+}
+
+// removableFiles reports the file entries the process lacks the permissions to delete, e.g., when
+// running as non-root, and returns the remaining entries so that their cleanup can proceed
+func (c *Cleaner) removableFiles(files []FileEntry) []FileEntry {
+	removable := make([]FileEntry, 0, len(files))
+	for _, file := range files {
+		if err := checkRemovable(file.Path); err != nil {
+			c.log.Info("WARNING: insufficient permissions to delete file, skipping", "path", file.Path, "reason", err.Error())
+			c.emit(Event{Type: EventFileSkipped, Path: file.Path, Reason: "insufficient permissions: " + err.Error()})
+			continue
+		}
+		removable = append(removable, file)
+	}
+	return removable
+}
+
+// unmountIfMounted unmounts path if it is a mount point, e.g., a bind-mounted socket
+func unmountIfMounted(log logr.Logger, path string) error {
+	mounted, err := isMountPoint(path)
+	if err != nil || !mounted {
+		return err
+	}
+	log.Info("Unmounting path", "path", path)
+	return unmount(path)
+}
+
+// pruneEmptyParents removes the empty parent directories of a deleted file, stopping
+// at the first non-empty directory, once the boundary directory is reached or once ctx is done
+func pruneEmptyParents(ctx context.Context, log logr.Logger, fsys FS, path, boundary string) {
+	if boundary == "" {
+		log.Info("WARNING: pruneEmptyParents requires a pruneBoundary. Skipping.", "path", path)
+		return
+	}
+	boundary = filepath.Clean(boundary)
+
+	dir := filepath.Dir(filepath.Clean(path))
+	for isSubPath(dir, boundary) && ctx.Err() == nil {
+		if err := fsys.Remove(dir); err != nil {
+			// a non-empty directory ends the walk, anything else is worth logging
+			if !errors.Is(err, syscall.ENOTEMPTY) && !errors.Is(err, syscall.EEXIST) {
+				log.Error(err, "empty directory removal failed", "path", dir)
+			}
+			return
+		}
+		log.Info("Removed empty directory", "path", dir)
+		dir = filepath.Dir(dir)
+	}
+}
+
+// isSubPath reports whether path is strictly nested beneath dir
+func isSubPath(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// contextReader is an io.Reader that fails once ctx is done, allowing large file reads to be interrupted
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
@@ -0,0 +1,319 @@
+package cleaner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner/cleanertest"
+)
+
+func TestFileEntryUnmarshal(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        string
+		expected      []FileEntry
+		expectedError bool
+	}{
+		{
+			name:     "plain paths",
+			config:   `["/host/etc/cni/net.d/00-multus.conf", "/host/opt/cni/bin/multus"]`,
+			expected: []FileEntry{{Path: "/host/etc/cni/net.d/00-multus.conf"}, {Path: "/host/opt/cni/bin/multus"}},
+		},
+		{
+			name:   "mixed paths and objects",
+			config: `["/host/opt/cni/bin/multus", {"path": "/host/etc/cni/net.d/multus.d/multus.kubeconfig", "pruneEmptyParents": true, "pruneBoundary": "/host/etc/cni/net.d"}]`,
+			expected: []FileEntry{
+				{Path: "/host/opt/cni/bin/multus"},
+				{Path: "/host/etc/cni/net.d/multus.d/multus.kubeconfig", PruneEmptyParents: true, PruneBoundary: "/host/etc/cni/net.d"},
+			},
+		},
+		{
+			name:          "invalid entry",
+			config:        `[1]`,
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := []FileEntry{}
+			err := json.Unmarshal([]byte(tt.config), &entries)
+			if err != nil && !tt.expectedError {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if err == nil && tt.expectedError {
+				t.Fatalf("expected error, got nil")
+			}
+			if !tt.expectedError && !reflect.DeepEqual(entries, tt.expected) {
+				t.Errorf("expected entries %+v, got %+v", tt.expected, entries)
+			}
+		})
+	}
+}
+
+func TestPruneEmptyParents(t *testing.T) {
+	tests := []struct {
+		name        string
+		boundary    string
+		siblingDir  string
+		expectedDir string
+	}{
+		{
+			name:        "prune up to boundary",
+			boundary:    "net.d",
+			expectedDir: "net.d",
+		},
+		{
+			name:        "stop at non-empty directory",
+			boundary:    "net.d",
+			siblingDir:  "net.d/multus.d/other",
+			expectedDir: "net.d/multus.d/other",
+		},
+		{
+			name:        "no boundary",
+			expectedDir: "net.d/multus.d/nested",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dir := filepath.Join(root, "net.d", "multus.d", "nested")
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if tt.siblingDir != "" {
+				if err := os.MkdirAll(filepath.Join(root, tt.siblingDir), 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			boundary := ""
+			if tt.boundary != "" {
+				boundary = filepath.Join(root, tt.boundary)
+			}
+
+			pruneEmptyParents(context.Background(), logr.Discard(), OSFS{}, filepath.Join(dir, "multus.kubeconfig"), boundary)
+
+			if _, err := os.Stat(filepath.Join(root, tt.expectedDir)); err != nil {
+				t.Errorf("expected %s to exist, got %v", tt.expectedDir, err)
+			}
+			if tt.boundary != "" && tt.siblingDir == "" {
+				if _, err := os.Stat(filepath.Join(root, "net.d", "multus.d")); !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("expected multus.d to be pruned, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchesExpectedContent(t *testing.T) {
+	content := `{"cniVersion": "0.3.1", "name": "multus-cni-network", "type": "multus"}`
+	path := filepath.Join(t.TempDir(), "00-multus.conf")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		entry         FileEntry
+		expected      bool
+		expectedError bool
+	}{
+		{
+			name:     "no guards",
+			entry:    FileEntry{Path: path},
+			expected: true,
+		},
+		{
+			name:     "matching substring",
+			entry:    FileEntry{Path: path, ExpectedContent: `"type": "multus"`},
+			expected: true,
+		},
+		{
+			name:     "non-matching substring",
+			entry:    FileEntry{Path: path, ExpectedContent: `"type": "calico"`},
+			expected: false,
+		},
+		{
+			name:     "matching sha256",
+			entry:    FileEntry{Path: path, ExpectedSHA256: "79483CCC0A610795B9FAEB6D4B7D07ED706D9D3DC6D4002210A0898DE475E6F1"},
+			expected: true,
+		},
+		{
+			name:     "non-matching sha256",
+			entry:    FileEntry{Path: path, ExpectedSHA256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+			expected: false,
+		},
+		{
+			name:          "missing file",
+			entry:         FileEntry{Path: path + ".missing", ExpectedContent: "multus"},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := tt.entry.matchesExpectedContent(context.Background(), OSFS{})
+			if err != nil && !tt.expectedError {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if err == nil && tt.expectedError {
+				t.Fatalf("expected error, got nil")
+			}
+			if matches != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, matches)
+			}
+		})
+	}
+}
+
+func TestCleanupFilesContextDone(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "00-multus.conf"), filepath.Join(dir, "multus")}
+	files := []FileEntry{}
+	for _, path := range paths {
+		if err := os.WriteFile(path, []byte("multus"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, FileEntry{Path: path})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := New(Options{}).CleanupFiles(ctx, files)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s to be retained, got %v", path, err)
+		}
+	}
+}
+
+func TestCleanupFilesFS(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []FileEntry
+		expected []string
+	}{
+		{
+			name:     "plain paths",
+			files:    []FileEntry{{Path: "/etc/cni/net.d/00-multus.conf"}, {Path: "/opt/cni/bin/multus"}},
+			expected: []string{"etc/cni/net.d/10-calico.conflist", "etc/cni/net.d/multus.d/multus.kubeconfig"},
+		},
+		{
+			name:     "glob pattern",
+			files:    []FileEntry{{Path: "/etc/cni/net.d/*multus*", Glob: true}, {Path: "/etc/cni/net.d/*/*.kubeconfig", Glob: true}},
+			expected: []string{"etc/cni/net.d/10-calico.conflist", "opt/cni/bin/multus"},
+		},
+		{
+			name:     "glob pattern with content guard",
+			files:    []FileEntry{{Path: "/etc/cni/net.d/*.conf*", Glob: true, ExpectedContent: "multus"}},
+			expected: []string{"etc/cni/net.d/10-calico.conflist", "etc/cni/net.d/multus.d/multus.kubeconfig", "opt/cni/bin/multus"},
+		},
+		{
+			name:     "glob pattern without matches",
+			files:    []FileEntry{{Path: "/etc/cni/net.d/*.json", Glob: true}},
+			expected: []string{"etc/cni/net.d/00-multus.conf", "etc/cni/net.d/10-calico.conflist", "etc/cni/net.d/multus.d/multus.kubeconfig", "opt/cni/bin/multus"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := cleanertest.MapFS{MapFS: fstest.MapFS{
+				"etc/cni/net.d/00-multus.conf":             {Data: []byte(`{"type": "multus"}`)},
+				"etc/cni/net.d/10-calico.conflist":         {Data: []byte(`{"type": "calico"}`)},
+				"etc/cni/net.d/multus.d/multus.kubeconfig": {Data: []byte("apiVersion: v1")},
+				"opt/cni/bin/multus":                       {Data: []byte("multus")},
+			}}
+
+			if _, err := New(Options{FS: fsys}).CleanupFiles(context.Background(), tt.files); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			remaining := []string{}
+			for name := range fsys.MapFS {
+				remaining = append(remaining, name)
+			}
+			slices.Sort(remaining)
+			if !reflect.DeepEqual(remaining, tt.expected) {
+				t.Errorf("expected remaining files %v, got %v", tt.expected, remaining)
+			}
+		})
+	}
+}
+
+func TestCleanupFilesLiteralPaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     FileEntry
+		expected []string
+	}{
+		{
+			name:     "backslash",
+			file:     FileEntry{Path: `/etc/cni/net.d/a\b.conf`},
+			expected: []string{"etc/cni/net.d/[x].conf", "etc/cni/net.d/x.conf"},
+		},
+		{
+			name:     "brackets",
+			file:     FileEntry{Path: "/etc/cni/net.d/[x].conf"},
+			expected: []string{`etc/cni/net.d/a\b.conf`, "etc/cni/net.d/x.conf"},
+		},
+		{
+			name:     "brackets as glob pattern",
+			file:     FileEntry{Path: "/etc/cni/net.d/[x].conf", Glob: true},
+			expected: []string{"etc/cni/net.d/[x].conf", `etc/cni/net.d/a\b.conf`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := cleanertest.MapFS{MapFS: fstest.MapFS{
+				`etc/cni/net.d/a\b.conf`: {},
+				"etc/cni/net.d/[x].conf": {},
+				"etc/cni/net.d/x.conf":   {},
+			}}
+
+			if _, err := New(Options{FS: fsys}).CleanupFiles(context.Background(), []FileEntry{tt.file}); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			remaining := []string{}
+			for name := range fsys.MapFS {
+				remaining = append(remaining, name)
+			}
+			slices.Sort(remaining)
+			if !reflect.DeepEqual(remaining, tt.expected) {
+				t.Errorf("expected remaining files %v, got %v", tt.expected, remaining)
+			}
+		})
+	}
+}
+
+func TestCleanupFilesLogger(t *testing.T) {
+	fsys := cleanertest.MapFS{MapFS: fstest.MapFS{"etc/cni/net.d/00-multus.conf": {}}}
+	var logs []string
+	logger := funcr.New(func(prefix, args string) {
+		logs = append(logs, args)
+	}, funcr.Options{})
+
+	if _, err := New(Options{FS: fsys, Logger: logger}).CleanupFiles(context.Background(), []FileEntry{{Path: "/etc/cni/net.d/00-multus.conf"}}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !slices.ContainsFunc(logs, func(l string) bool { return strings.Contains(l, `"msg"="Deleting file"`) }) {
+		t.Errorf("expected file deletion to be logged to the injected logger, got %q", logs)
+	}
+}
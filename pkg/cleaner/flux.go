@@ -0,0 +1,117 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// fluxOwner identifies the Flux object managing resources by the labels it sets on them
+type fluxOwner struct {
+	// resource is the Flux object's resource, without a version, which is resolved by the RESTMapper
+	resource       schema.GroupResource
+	nameLabel      string
+	namespaceLabel string
+}
+
+// fluxOwners are the Flux objects that reconcile resources, recreating them once deleted
+var fluxOwners = []fluxOwner{
+	{
+		resource:       schema.GroupResource{Group: "kustomize.toolkit.fluxcd.io", Resource: "kustomizations"},
+		nameLabel:      "kustomize.toolkit.fluxcd.io/name",
+		namespaceLabel: "kustomize.toolkit.fluxcd.io/namespace",
+	},
+	{
+		resource:       schema.GroupResource{Group: "helm.toolkit.fluxcd.io", Resource: "helmreleases"},
+		nameLabel:      "helm.toolkit.fluxcd.io/name",
+		namespaceLabel: "helm.toolkit.fluxcd.io/namespace",
+	},
+}
+
+// fluxSuspensions records the Flux objects suspended by a Cleaner, so that each is patched once.
+// It is safe for concurrent use.
+type fluxSuspensions struct {
+	mu        sync.Mutex
+	suspended map[string]bool
+}
+
+// suspendFluxOwners suspends the Flux Kustomization or HelmRelease managing a resource, if any, by
+// setting its spec.suspend, so that Flux doesn't recreate the resource once deleted. Flux objects
+// that are already gone are ignored. The object is read via the Client option, which is required.
+func (c *Cleaner) suspendFluxOwners(ctx context.Context, r metav1.PartialObjectMetadata) error {
+	client := c.opts.Client
+	if client == nil {
+		return fmt.Errorf("%w: suspending Flux requires a client", ErrConfigInvalid)
+	}
+	for _, owner := range fluxOwners {
+		name := r.Labels[owner.nameLabel]
+		if name == "" {
+			continue
+		}
+		namespace := r.Labels[owner.namespaceLabel]
+		if namespace == "" {
+			namespace = r.Namespace
+		}
+		key := owner.resource.String() + "/" + namespace + "/" + name
+		c.flux.mu.Lock()
+		suspended := c.flux.suspended[key]
+		c.flux.mu.Unlock()
+		if suspended {
+			continue
+		}
+
+		if err := c.suspendFluxObject(ctx, client, owner.resource, types.NamespacedName{Namespace: namespace, Name: name}); err != nil {
+			return err
+		}
+		c.flux.mu.Lock()
+		c.flux.suspended[key] = true
+		c.flux.mu.Unlock()
+	}
+	return nil
+}
+
+// suspendFluxObject sets spec.suspend of a Flux object, in the preferred version of its resource.
+// Objects whose resource isn't served, i.e., Flux was uninstalled, needn't be suspended.
+func (c *Cleaner) suspendFluxObject(ctx context.Context, client ctrlclient.Client, resource schema.GroupResource, key types.NamespacedName) error {
+	gvk, err := client.RESTMapper().KindFor(resource.WithVersion(""))
+	if meta.IsNoMatchError(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	u.SetName(key.Name)
+	u.SetNamespace(key.Namespace)
+
+	c.log.Info("Suspending Flux object", "name", key.Name, "namespace", key.Namespace, "kind", gvk.Kind)
+	err = c.retry(ctx, func(ctx context.Context) error {
+		return client.Patch(ctx, u, ctrlclient.RawPatch(types.MergePatchType, []byte(`{"spec":{"suspend":true}}`)))
+	})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
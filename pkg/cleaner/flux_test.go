@@ -0,0 +1,100 @@
+package cleaner
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestSuspendFlux(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	kustomizationGVK := schema.GroupVersionKind{Group: "kustomize.toolkit.fluxcd.io", Version: "v1", Kind: "Kustomization"}
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{kustomizationGVK.GroupVersion()})
+	mapper.Add(kustomizationGVK, meta.RESTScopeNamespace)
+	kustomization := &unstructured.Unstructured{}
+	kustomization.SetGroupVersionKind(kustomizationGVK)
+	kustomization.SetName("apps")
+	kustomization.SetNamespace("flux-system")
+
+	fluxLabels := map[string]string{
+		"kustomize.toolkit.fluxcd.io/name":      "apps",
+		"kustomize.toolkit.fluxcd.io/namespace": "flux-system",
+	}
+	var objs []runtime.Object
+	for _, name := range []string{"a", "b"} {
+		objs = append(objs, &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: fluxLabels},
+		})
+	}
+	objs = append(objs, &metav1.PartialObjectMetadata{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: "released", Namespace: "default", Labels: map[string]string{
+			"helm.toolkit.fluxcd.io/name": "uninstalled",
+		}},
+	})
+
+	tests := []struct {
+		name    string
+		entries []DeleteObj
+	}{
+		{
+			name:    "delete all",
+			entries: []DeleteObj{{GroupVersionResource: gvr, Namespace: "default"}},
+		},
+		{
+			name: "named",
+			entries: []DeleteObj{
+				{GroupVersionResource: gvr, Name: "a", Namespace: "default"},
+				{GroupVersionResource: gvr, Name: "b", Namespace: "default"},
+				{GroupVersionResource: gvr, Name: "released", Namespace: "default"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patches := 0
+			client := fake.NewClientBuilder().WithRESTMapper(mapper).WithObjects(kustomization.DeepCopy()).WithInterceptorFuncs(interceptor.Funcs{
+				Patch: func(ctx context.Context, client ctrlclient.WithWatch, obj ctrlclient.Object, patch ctrlclient.Patch, opts ...ctrlclient.PatchOption) error {
+					patches++
+					return client.Patch(ctx, obj, patch, opts...)
+				},
+			}).Build()
+			metadataClient := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), objs...)
+
+			if _, err := New(Options{Client: client, MetadataClient: metadataClient, SuspendFlux: true}).CleanupResources(context.Background(), tt.entries); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			suspended := &unstructured.Unstructured{}
+			suspended.SetGroupVersionKind(kustomizationGVK)
+			if err := client.Get(context.Background(), types.NamespacedName{Namespace: "flux-system", Name: "apps"}, suspended); err != nil {
+				t.Fatal(err)
+			}
+			if suspend, _, _ := unstructured.NestedBool(suspended.Object, "spec", "suspend"); !suspend {
+				t.Errorf("expected Kustomization to be suspended, got %v", suspended.Object["spec"])
+			}
+			if patches != 1 {
+				t.Errorf("expected 1 patch, got %d", patches)
+			}
+			list, err := metadataClient.Resource(gvr).Namespace("default").List(context.Background(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list.Items) != 0 {
+				t.Errorf("expected every ConfigMap to be deleted, got %d remaining", len(list.Items))
+			}
+		})
+	}
+}
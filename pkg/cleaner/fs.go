@@ -0,0 +1,68 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FS is the filesystem file entries are deleted from. Alternative implementations allow file
+// cleanup to be unit tested, or to operate on another backend, e.g., a mounted image.
+type FS interface {
+	// Lstat describes the named file. If it is a symbolic link, the link itself is described.
+	Lstat(name string) (fs.FileInfo, error)
+
+	// Readlink returns the destination of the named symbolic link
+	Readlink(name string) (string, error)
+
+	// Open opens the named file for reading
+	Open(name string) (fs.File, error)
+
+	// Remove removes the named file or empty directory
+	Remove(name string) error
+
+	// Glob returns the names of all files matching pattern, with the syntax of filepath.Match
+	Glob(pattern string) ([]string, error)
+}
+
+// OSFS is the FS of the local filesystem, and the default FS of a Cleaner
+type OSFS struct{}
+
+// Lstat implements FS
+func (OSFS) Lstat(name string) (fs.FileInfo, error) {
+	return os.Lstat(name)
+}
+
+// Readlink implements FS
+func (OSFS) Readlink(name string) (string, error) {
+	return os.Readlink(name)
+}
+
+// Open implements FS
+func (OSFS) Open(name string) (fs.File, error) {
+	return os.Open(name) // #nosec G304
+}
+
+// Remove implements FS
+func (OSFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// Glob implements FS
+func (OSFS) Glob(pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
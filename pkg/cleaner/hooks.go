@@ -0,0 +1,59 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Hooks are called by a Cleaner around its deletions, allowing embedding code to, e.g., audit or
+// back up the files and resources deleted, or to veto their deletion. Embed NopHooks to implement
+// a subset of the hooks. Hooks may be called concurrently if EntryConcurrency is greater than 1.
+type Hooks interface {
+	// BeforeResourceDelete is called before a resource matched by an entry with the delete action
+	// is deleted. Returning an error vetoes the deletion, failing it with ErrVetoed.
+	BeforeResourceDelete(ctx context.Context, entry DeleteObj, resource types.NamespacedName) error
+
+	// AfterResourceDelete is called once a resource's deletion has been attempted, with its error
+	AfterResourceDelete(ctx context.Context, entry DeleteObj, resource types.NamespacedName, err error)
+
+	// BeforeFileDelete is called before a file is archived and deleted. Returning an error vetoes
+	// the deletion, skipping the file.
+	BeforeFileDelete(ctx context.Context, file FileEntry) error
+
+	// OnEntryComplete is called once a resource entry has been processed, with its error
+	OnEntryComplete(ctx context.Context, entry DeleteObj, err error)
+}
+
+// NopHooks implements Hooks without doing anything, and is the default Hooks of a Cleaner
+type NopHooks struct{}
+
+// BeforeResourceDelete implements Hooks
+func (NopHooks) BeforeResourceDelete(context.Context, DeleteObj, types.NamespacedName) error {
+	return nil
+}
+
+// AfterResourceDelete implements Hooks
+func (NopHooks) AfterResourceDelete(context.Context, DeleteObj, types.NamespacedName, error) {}
+
+// BeforeFileDelete implements Hooks
+func (NopHooks) BeforeFileDelete(context.Context, FileEntry) error {
+	return nil
+}
+
+// OnEntryComplete implements Hooks
+func (NopHooks) OnEntryComplete(context.Context, DeleteObj, error) {}
@@ -0,0 +1,102 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	metadatafake "k8s.io/client-go/metadata/fake"
+)
+
+// recordingHooks records the resources deleted and the entries completed, vetoing the deletion of the resource named veto
+type recordingHooks struct {
+	NopHooks
+	veto      string
+	deleted   []string
+	completed []error
+}
+
+func (h *recordingHooks) BeforeResourceDelete(_ context.Context, _ DeleteObj, resource types.NamespacedName) error {
+	if resource.Name == h.veto {
+		return errors.New("backup failed")
+	}
+	return nil
+}
+
+func (h *recordingHooks) AfterResourceDelete(_ context.Context, _ DeleteObj, resource types.NamespacedName, err error) {
+	if err == nil {
+		h.deleted = append(h.deleted, resource.Name)
+	}
+}
+
+func (h *recordingHooks) OnEntryComplete(_ context.Context, _ DeleteObj, err error) {
+	h.completed = append(h.completed, err)
+}
+
+func TestHooks(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	tests := []struct {
+		name            string
+		entry           DeleteObj
+		veto            string
+		expectedDeleted []string
+		expectedVetoed  bool
+	}{
+		{
+			name:            "named resource",
+			entry:           DeleteObj{GroupVersionResource: gvr, Name: "a", Namespace: "default"},
+			expectedDeleted: []string{"a"},
+		},
+		{
+			name:           "named resource vetoed",
+			entry:          DeleteObj{GroupVersionResource: gvr, Name: "a", Namespace: "default"},
+			veto:           "a",
+			expectedVetoed: true,
+		},
+		{
+			name:            "delete-all with a resource vetoed",
+			entry:           DeleteObj{GroupVersionResource: gvr, Namespace: "default"},
+			veto:            "b",
+			expectedDeleted: []string{"a", "c"},
+			expectedVetoed:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var objs []runtime.Object
+			for _, name := range []string{"a", "b", "c"} {
+				objs = append(objs, &metav1.PartialObjectMetadata{
+					TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				})
+			}
+			client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), objs...)
+			hooks := &recordingHooks{veto: tt.veto}
+			entry := tt.entry
+			entry.MustDelete = true
+
+			_, err := New(Options{MetadataClient: client, Hooks: hooks}).CleanupResources(context.Background(), []DeleteObj{entry})
+			if errors.Is(err, ErrVetoed) != tt.expectedVetoed {
+				t.Errorf("expected vetoed %v, got %v", tt.expectedVetoed, err)
+			}
+			if !reflect.DeepEqual(hooks.deleted, tt.expectedDeleted) {
+				t.Errorf("expected deleted %v, got %v", tt.expectedDeleted, hooks.deleted)
+			}
+			if len(hooks.completed) != 1 || errors.Is(hooks.completed[0], ErrVetoed) != tt.expectedVetoed {
+				t.Errorf("expected one completed entry, got %v", hooks.completed)
+			}
+			if tt.veto != "" {
+				if _, err := client.Resource(gvr).Namespace("default").Get(context.Background(), tt.veto, metav1.GetOptions{}); apierrors.IsNotFound(err) {
+					t.Errorf("expected %s to be retained, got %v", tt.veto, err)
+				}
+			}
+		})
+	}
+}
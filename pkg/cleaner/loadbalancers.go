@@ -0,0 +1,47 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"context"
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// LoadBalancerCleanupFinalizer is set on Services of type LoadBalancer by the cloud controller
+// manager until it has released the Service's cloud load balancer
+const LoadBalancerCleanupFinalizer = "service.kubernetes.io/load-balancer-cleanup"
+
+var servicesGR = schema.GroupResource{Resource: "services"}
+
+// waitForLoadBalancers blocks until an entry's deleted Services holding a cloud load balancer are
+// gone, i.e., until the cloud controller manager has released their load balancers, so that tearing
+// down the network afterwards, e.g., a VPC or subnets, doesn't race it
+func (c *Cleaner) waitForLoadBalancers(ctx context.Context, obj DeleteObj, deleted []metav1.PartialObjectMetadata) error {
+	holding := slices.DeleteFunc(slices.Clone(deleted), func(d metav1.PartialObjectMetadata) bool {
+		return !slices.Contains(d.Finalizers, LoadBalancerCleanupFinalizer)
+	})
+	if len(holding) == 0 {
+		return nil
+	}
+	c.log.Info("Waiting for cloud load balancers to be released", "services", len(holding), "name", obj.Name, "namespace", obj.Namespace)
+	if err := c.lbWaiter.waitForDeletion(ctx, obj, holding); err != nil {
+		c.log.Error(err, "cloud load balancers not released", "name", obj.Name, "namespace", obj.Namespace)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	clienttesting "k8s.io/client-go/testing"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner/cleanertest"
+)
+
+func TestWaitForLoadBalancers(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "services"}
+	scheme := runtime.NewScheme()
+	scheme.AddKnownTypeWithName(schema.GroupVersionKind{Version: "v1", Kind: "Service"}, &metav1.PartialObjectMetadata{})
+	scheme.AddKnownTypeWithName(schema.GroupVersionKind{Version: "v1", Kind: "ServiceList"}, &metav1.PartialObjectMetadataList{})
+	service := func(name string, finalizers ...string) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Finalizers: finalizers},
+		}
+	}
+	entry := DeleteObj{GroupVersionResource: gvr, Namespace: "default"}
+
+	tests := []struct {
+		name              string
+		released          bool
+		final             bool
+		expectedError     error
+		expectedResources []types.NamespacedName
+	}{
+		{
+			name:     "released",
+			released: true,
+		},
+		{
+			name:              "not released",
+			expectedError:     ErrDeletionTimeout,
+			expectedResources: []types.NamespacedName{{Namespace: "default", Name: "ingress"}},
+		},
+		{
+			name:  "final entry never blocks",
+			final: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := metadatafake.NewSimpleMetadataClient(scheme, service("ingress", LoadBalancerCleanupFinalizer), service("internal"))
+			if !tt.released {
+				// the cloud controller manager never removes the finalizer, so the Service remains
+				client.PrependReactor("delete", "services", func(action clienttesting.Action) (bool, runtime.Object, error) {
+					return action.(clienttesting.DeleteAction).GetName() == "ingress", nil, nil
+				})
+			}
+			fakeClock := testingclock.NewFakeClock(time.Now())
+			c := New(Options{MetadataClient: client, Clock: fakeClock, LoadBalancerTimeout: time.Hour})
+
+			var err error
+			if tt.final {
+				_, err = c.CleanupFinalResource(context.Background(), entry)
+			} else {
+				if !tt.released {
+					go cleanertest.StepWhenWaiting(fakeClock, time.Hour)
+				}
+				err = c.processEntry(context.Background(), entry, nil)
+			}
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, got %v", tt.expectedError, err)
+			}
+			if resources := failedResources(err); !reflect.DeepEqual(resources, tt.expectedResources) {
+				t.Errorf("expected failed resources %v, got %v", tt.expectedResources, resources)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Infrastructure-as-code controllers, which reconcile the resources they manage from their own
+// state, and would recreate, or fight over, resources deleted behind their backs
+const (
+	controllerCrossplane          = "crossplane"
+	controllerTerraformController = "terraform-controller"
+)
+
+// crossplaneLabels and crossplaneAnnotations mark resources composed, claimed or managed by Crossplane
+var (
+	crossplaneLabels      = []string{"crossplane.io/composite", "crossplane.io/claim-name"}
+	crossplaneAnnotations = []string{"crossplane.io/external-name"}
+)
+
+// managingControllers are the controllers owning the resources they manage, by API group
+var managingControllers = map[string]string{
+	"pkg.crossplane.io":       controllerCrossplane,
+	"infra.contrib.fluxcd.io": controllerTerraformController,
+	"terraform.core.oam.dev":  controllerTerraformController,
+}
+
+// managingController returns the infrastructure-as-code controller managing a resource, as identified
+// by its ownership markers, or "" if there is none
+func managingController(r metav1.PartialObjectMetadata) string {
+	for _, key := range crossplaneLabels {
+		if _, ok := r.Labels[key]; ok {
+			return controllerCrossplane
+		}
+	}
+	for _, key := range crossplaneAnnotations {
+		if _, ok := r.Annotations[key]; ok {
+			return controllerCrossplane
+		}
+	}
+	for _, ref := range r.OwnerReferences {
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			continue
+		}
+		if controller, ok := managingControllers[gv.Group]; ok {
+			return controller
+		}
+	}
+	return ""
+}
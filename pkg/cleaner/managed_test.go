@@ -0,0 +1,108 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestManagingController(t *testing.T) {
+	tests := []struct {
+		name     string
+		meta     metav1.ObjectMeta
+		expected string
+	}{
+		{
+			name: "unmanaged",
+			meta: metav1.ObjectMeta{Labels: map[string]string{"app": "widget"}},
+		},
+		{
+			name:     "crossplane composite label",
+			meta:     metav1.ObjectMeta{Labels: map[string]string{"crossplane.io/composite": "db-x7k2"}},
+			expected: controllerCrossplane,
+		},
+		{
+			name:     "crossplane external name",
+			meta:     metav1.ObjectMeta{Annotations: map[string]string{"crossplane.io/external-name": "vpc-0a1b"}},
+			expected: controllerCrossplane,
+		},
+		{
+			name:     "owned by crossplane provider",
+			meta:     metav1.ObjectMeta{OwnerReferences: []metav1.OwnerReference{{APIVersion: "pkg.crossplane.io/v1", Kind: "ProviderRevision"}}},
+			expected: controllerCrossplane,
+		},
+		{
+			name:     "owned by terraform",
+			meta:     metav1.ObjectMeta{OwnerReferences: []metav1.OwnerReference{{APIVersion: "infra.contrib.fluxcd.io/v1alpha2", Kind: "Terraform"}}},
+			expected: controllerTerraformController,
+		},
+		{
+			name: "owned by deployment",
+			meta: metav1.ObjectMeta{OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if controller := managingController(metav1.PartialObjectMetadata{ObjectMeta: tt.meta}); controller != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, controller)
+			}
+		})
+	}
+}
+
+func TestForceManaged(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+	tests := []struct {
+		name            string
+		entryName       string
+		force           bool
+		forbidGet       bool
+		expectedGets    int
+		expectedDeletes int
+	}{
+		{name: "skipped by default", expectedDeletes: 1},
+		{name: "forced", force: true, expectedDeletes: 2},
+		{name: "named skipped by default", entryName: "composed", expectedGets: 1},
+		{name: "named forced", entryName: "composed", force: true, expectedDeletes: 1},
+		{name: "named get forbidden", entryName: "composed", forbidGet: true, expectedGets: 1, expectedDeletes: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(),
+				&metav1.PartialObjectMetadata{
+					TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+					ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "default"},
+				},
+				&metav1.PartialObjectMetadata{
+					TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+					ObjectMeta: metav1.ObjectMeta{Name: "composed", Namespace: "default", Labels: map[string]string{"crossplane.io/composite": "db-x7k2"}},
+				},
+			)
+			if tt.forbidGet {
+				client.PrependReactor("get", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+					return true, nil, apierrors.NewForbidden(gvr.GroupResource(), tt.entryName, errors.New("denied"))
+				})
+			}
+			entry := DeleteObj{GroupVersionResource: gvr, Name: tt.entryName, Namespace: "default"}
+			if err := New(Options{MetadataClient: client, ForceManaged: tt.force}).processEntry(context.Background(), entry, nil); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if gets := countVerb(client.Actions(), "get"); gets != tt.expectedGets {
+				t.Errorf("expected %d gets, got %d", tt.expectedGets, gets)
+			}
+			if deletes := countVerb(client.Actions(), "delete"); deletes != tt.expectedDeletes {
+				t.Errorf("expected %d deletes, got %d", tt.expectedDeletes, deletes)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// objectKey identifies an object of the manifests
+type objectKey struct {
+	gvr schema.GroupVersionResource
+	types.NamespacedName
+}
+
+// manifestExtensions are the extensions of the manifest files read from a directory or tarball
+var manifestExtensions = []string{".yaml", ".yml", ".json"}
+
+// ExpandManifests replaces each resource entry setting Manifests with an entry per object of the
+// manifests at its path, e.g., the rendered manifests of a Helm chart as previously applied, so that
+// uninstall configs needn't be maintained in parallel with install manifests. Objects are deleted in
+// reverse order, so that what was applied first, e.g., namespaces and CRDs, is deleted last. Kinds are
+// mapped to resources by mapper, falling back to the preferred version if the object's version isn't
+// served; objects whose kind isn't served at all, e.g., as its CRD is already gone, are skipped. Named namespaced objects without a namespace are left for the caller to default.
+func ExpandManifests(entries []DeleteObj, mapper meta.RESTMapper) ([]DeleteObj, error) {
+	expanded := make([]DeleteObj, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Manifests == "" {
+			expanded = append(expanded, entry)
+			continue
+		}
+		objs, err := ReadManifests(entry.Manifests)
+		if err != nil {
+			return nil, err
+		}
+		var manifestEntries []DeleteObj
+		seen := map[objectKey]bool{}
+		for _, obj := range objs {
+			gvk := obj.GroupVersionKind()
+			mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+			if meta.IsNoMatchError(err) {
+				// the version may no longer be served, e.g., a removed beta version
+				mapping, err = mapper.RESTMapping(gvk.GroupKind())
+			}
+			if meta.IsNoMatchError(err) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			e := entry
+			e.Manifests = ""
+			e.GroupVersionResource, e.Name = mapping.Resource, obj.GetName()
+			if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+				e.Namespace = obj.GetNamespace()
+			} else {
+				e.Namespace = ""
+			}
+			key := objectKey{gvr: e.GroupVersionResource, NamespacedName: types.NamespacedName{Namespace: e.Namespace, Name: e.Name}}
+			if !seen[key] {
+				seen[key] = true
+				manifestEntries = append(manifestEntries, e)
+			}
+		}
+		slices.Reverse(manifestEntries)
+		expanded = append(expanded, manifestEntries...)
+	}
+	return expanded, nil
+}
+
+// ReadManifests returns the objects of the YAML or JSON manifests at path: a manifest file, a directory
+// of manifest files, read recursively in lexical order as kubectl apply does, or a tarball of manifest
+// files, gzipped if its name ends with .gz or .tgz. Lists are replaced by their items.
+func ReadManifests(path string) ([]*unstructured.Unstructured, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: manifests: %w", ErrConfigInvalid, err)
+	}
+	var objs []*unstructured.Unstructured
+	read := func(name string, r io.Reader) error {
+		decoded, err := decodeManifests(r)
+		if err != nil {
+			return fmt.Errorf("%w: manifest %s: %w", ErrConfigInvalid, name, err)
+		}
+		objs = append(objs, decoded...)
+		return nil
+	}
+
+	switch {
+	case info.IsDir():
+		err = filepath.WalkDir(path, func(name string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !slices.Contains(manifestExtensions, filepath.Ext(name)) {
+				return err
+			}
+			f, err := os.Open(name) // #nosec G304
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			return read(name, f)
+		})
+	case strings.HasSuffix(path, ".tar"), strings.HasSuffix(path, ".tar.gz"), strings.HasSuffix(path, ".tgz"):
+		err = readManifestTarball(path, read)
+	default:
+		var f *os.File
+		f, err = os.Open(path) // #nosec G304
+		if err != nil {
+			break
+		}
+		defer f.Close()
+		err = read(path, f)
+	}
+	return objs, err
+}
+
+// readManifestTarball calls read with each manifest file of a tarball, in the order they were archived
+func readManifestTarball(path string, read func(name string, r io.Reader) error) error {
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if !strings.HasSuffix(path, ".tar") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("%w: manifests %s: %w", ErrConfigInvalid, path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%w: manifests %s: %w", ErrConfigInvalid, path, err)
+		}
+		if hdr.Typeflag != tar.TypeReg || !slices.Contains(manifestExtensions, filepath.Ext(hdr.Name)) {
+			continue
+		}
+		if err := read(hdr.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// decodeManifests returns the objects of a stream of YAML documents or JSON objects, skipping empty documents
+func decodeManifests(r io.Reader) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+	decoder := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); errors.Is(err, io.EOF) {
+			return objs, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		if obj.IsList() {
+			list, err := obj.ToList()
+			if err != nil {
+				return nil, err
+			}
+			for i := range list.Items {
+				objs = append(objs, &list.Items[i])
+			}
+			continue
+		}
+		if obj.GetKind() == "" || obj.GetName() == "" {
+			return nil, fmt.Errorf("object without a kind or name: %v", obj.Object)
+		}
+		objs = append(objs, obj)
+	}
+}
@@ -0,0 +1,130 @@
+package cleaner
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	namespaceManifest = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: widgets
+`
+	workloadManifests = `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: widget-config
+  namespace: widgets
+---
+# rendered from a disabled template
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: widget-operator
+    namespace: widgets
+- apiVersion: example.com/v1
+  kind: Widget
+  metadata:
+    name: uninstalled
+    namespace: widgets
+`
+	duplicateManifest = `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "widget-config", "namespace": "widgets"}}`
+)
+
+func TestExpandManifests(t *testing.T) {
+	dir := t.TempDir()
+	manifestDir := filepath.Join(dir, "manifests")
+	files := map[string]string{
+		"00-namespace.yaml":     namespaceManifest,
+		"10-workloads.yaml":     workloadManifests,
+		"20-duplicate.json":     duplicateManifest,
+		"README.md":             "not a manifest",
+		"nested/30-ignored.txt": "not a manifest either",
+	}
+	for name, content := range files {
+		path := filepath.Join(manifestDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tarballPath := filepath.Join(dir, "manifests.tgz")
+	f, err := os.Create(tarballPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, name := range []string{"00-namespace.yaml", "10-workloads.yaml", "README.md"} {
+		content := files[name]
+		if err := tw.WriteHeader(&tar.Header{Name: "chart/" + name, Mode: 0o600, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []interface{ Close() error }{tw, gz, f} {
+		if err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, meta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+
+	expected := []DeleteObj{
+		{GroupVersionResource: deployments, Name: "widget-operator", Namespace: "widgets", MustDelete: true},
+		{GroupVersionResource: configMapsGVR, Name: "widget-config", Namespace: "widgets", MustDelete: true},
+		{GroupVersionResource: namespacesGVR, Name: "widgets", MustDelete: true},
+		{GroupVersionResource: jobsGVR, Name: "spectro-cleanup", Namespace: "widgets"},
+	}
+	for _, path := range []string{manifestDir, tarballPath} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			entries := []DeleteObj{
+				{Manifests: path, MustDelete: true},
+				{GroupVersionResource: jobsGVR, Name: "spectro-cleanup", Namespace: "widgets"},
+			}
+			expanded, err := ExpandManifests(entries, mapper)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(expanded, expected) {
+				t.Errorf("expected %v, got %v", expected, expanded)
+			}
+		})
+	}
+}
+
+func TestReadManifestsInvalid(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(path, []byte("apiVersion: v1\nkind: ConfigMap\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{path, filepath.Join(dir, "missing")} {
+		if _, err := ReadManifests(path); !errors.Is(err, ErrConfigInvalid) {
+			t.Errorf("expected %v for %s, got %v", ErrConfigInvalid, path, err)
+		}
+	}
+}
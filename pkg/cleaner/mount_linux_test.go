@@ -0,0 +1,23 @@
+package cleaner
+
+import "testing"
+
+func TestUnescapeMountPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/var/run/docker.sock", expected: "/var/run/docker.sock"},
+		{path: `/var/lib/kubelet/pods/my\040volume`, expected: "/var/lib/kubelet/pods/my volume"},
+		{path: `/mnt/tab\011and\134slash`, expected: "/mnt/tab\tand\\slash"},
+		{path: `/mnt/trailing\04`, expected: `/mnt/trailing\04`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := unescapeMountPath(tt.path); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
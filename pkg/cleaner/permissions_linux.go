@@ -0,0 +1,107 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/sys/unix"
+)
+
+// hasCapability reports whether the effective capability set of the process includes c
+func hasCapability(c int) (bool, error) {
+	hdr := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	var data [2]unix.CapUserData
+	if err := unix.Capget(&hdr, &data[0]); err != nil {
+		return false, err
+	}
+	return data[c/32].Effective&(1<<(c%32)) != 0, nil
+}
+
+// checkCapabilities warns about enabled file cleanup options the process lacks the capabilities for,
+// e.g., when running as non-root under the restricted Pod Security Standard
+func checkCapabilities(log logr.Logger, unmountEnabled, clearImmutableEnabled bool) {
+	for _, required := range []struct {
+		enabled    bool
+		capability int
+		name       string
+		option     string
+	}{
+		{unmountEnabled, unix.CAP_SYS_ADMIN, "CAP_SYS_ADMIN", "Options.Unmount"},
+		{clearImmutableEnabled, unix.CAP_LINUX_IMMUTABLE, "CAP_LINUX_IMMUTABLE", "Options.ClearImmutable"},
+	} {
+		if !required.enabled {
+			continue
+		}
+		ok, err := hasCapability(required.capability)
+		if err != nil {
+			log.Error(err, "capability detection failed", "capability", required.name)
+			continue
+		}
+		if !ok {
+			log.Info("WARNING: missing capability, file cleanup with "+required.option+" set will fail", "capability", required.name)
+		}
+	}
+}
+
+// checkRemovable returns an error describing why the process lacks the permissions to delete path.
+// Paths that don't exist are considered removable, as there is nothing to delete.
+func checkRemovable(path string) error {
+	dir := filepath.Dir(filepath.Clean(path))
+	// AT_EACCESS checks the effective IDs and capabilities, e.g., CAP_DAC_OVERRIDE, as unlink would
+	err := unix.Faccessat(unix.AT_FDCWD, dir, unix.W_OK|unix.X_OK, unix.AT_EACCESS)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil
+	case errors.Is(err, unix.EACCES):
+		return fmt.Errorf("write access to %s or CAP_DAC_OVERRIDE required", dir)
+	case errors.Is(err, unix.EROFS):
+		return fmt.Errorf("%s is on a read-only filesystem", dir)
+	case err != nil:
+		return err
+	}
+
+	// in sticky directories, e.g., /tmp, only the owner of a file or of the directory may delete it
+	var dirStat, fileStat unix.Stat_t
+	if err := unix.Stat(dir, &dirStat); err != nil {
+		return err
+	}
+	if dirStat.Mode&unix.S_ISVTX == 0 {
+		return nil
+	}
+	if err := unix.Lstat(path, &fileStat); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	uid := uint32(os.Geteuid()) // #nosec G115
+	if fileStat.Uid == uid || dirStat.Uid == uid {
+		return nil
+	}
+	fowner, err := hasCapability(unix.CAP_FOWNER)
+	if err != nil {
+		return err
+	}
+	if !fowner {
+		return fmt.Errorf("%s is owned by another user in sticky directory %s, ownership or CAP_FOWNER required", path, dir)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckRemovable(t *testing.T) {
+	dir := t.TempDir()
+	readOnly := filepath.Join(dir, "read-only")
+	if err := os.Mkdir(readOnly, 0o500); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		path          string
+		requiresUser  bool
+		expectedError bool
+	}{
+		{
+			name: "writable directory",
+			path: filepath.Join(dir, "file"),
+		},
+		{
+			name: "missing directory",
+			path: filepath.Join(dir, "missing", "file"),
+		},
+		{
+			name:          "read-only directory",
+			path:          filepath.Join(readOnly, "file"),
+			requiresUser:  true,
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.requiresUser && os.Geteuid() == 0 {
+				t.Skip("permissions are not enforced for root")
+			}
+			err := checkRemovable(tt.path)
+			if err != nil && !tt.expectedError {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if err == nil && tt.expectedError {
+				t.Fatalf("expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !linux
+
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import "github.com/go-logr/logr"
+
+// checkRemovable always succeeds, as permission detection is only supported on linux
+func checkRemovable(_ string) error {
+	return nil
+}
+
+// checkCapabilities is a no-op, as capabilities are only supported on linux
+func checkCapabilities(_ logr.Logger, _, _ bool) {}
@@ -0,0 +1,107 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Config is the files and resources a cleanup acts on, i.e., the contents of the file and resource
+// config files
+type Config struct {
+	Files     []FileEntry
+	Resources []DeleteObj
+}
+
+// Plan is the fully resolved set of files and resources a cleanup would act on
+type Plan struct {
+	// Files are the files that would be deleted, after the host root is applied, glob patterns are
+	// expanded, and files that can't be deleted or don't match their expected content are excluded
+	Files []FileEntry
+
+	// Resources are the resources matched by each resource config entry, in order
+	Resources []PlannedEntry
+}
+
+// PlannedEntry is the resources a resource config entry would apply its action to
+type PlannedEntry struct {
+	Entry     DeleteObj
+	Resources []types.NamespacedName
+
+	// Err is why the entry's resources couldn't be resolved, e.g., listing them is forbidden,
+	// or the entry is high-risk but not confirmed
+	Err error
+}
+
+// Plan resolves the files and resources a cleanup of cfg would act on, without modifying them or
+// emitting events. Resources are listed and their selectors evaluated as they would be by
+// CleanupResources; an entry naming a resource that is already gone plans no resources.
+// Failures to resolve an entry are recorded in the plan, and only ctx's error is returned.
+func (c *Cleaner) Plan(ctx context.Context, cfg Config) (*Plan, error) {
+	planner := *c
+	planner.opts.EventSink = nil
+
+	plan := &Plan{}
+	for _, file := range planner.resolveFiles(cfg.Files) {
+		matches, err := file.matchesExpectedContent(ctx, planner.opts.FS)
+		if err != nil || !matches {
+			continue
+		}
+		plan.Files = append(plan.Files, file)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, obj := range cfg.Resources {
+		entry := PlannedEntry{Entry: obj}
+		resources, err := planner.planEntry(ctx, obj)
+		if err != nil {
+			entry.Err = err
+		}
+		for _, r := range resources {
+			entry.Resources = append(entry.Resources, types.NamespacedName{Namespace: r.Namespace, Name: r.Name})
+		}
+		plan.Resources = append(plan.Resources, entry)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// planEntry returns the resources a resource config entry would apply its action to
+func (c *Cleaner) planEntry(ctx context.Context, obj DeleteObj) ([]metav1.PartialObjectMetadata, error) {
+	if obj.AllResources || obj.Manifests != "" {
+		return nil, errUnexpanded(obj)
+	}
+	switch obj.Action {
+	case ActionRemoveFinalizers, ActionRemoveMetadata:
+		resources, err := c.matchingResources(ctx, obj)
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return resources, err
+	}
+	strategy, err := c.strategy(obj)
+	if err != nil {
+		return nil, err
+	}
+	return c.planDeletion(ctx, obj, strategy)
+}
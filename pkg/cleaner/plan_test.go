@@ -0,0 +1,79 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner/cleanertest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	metadatafake "k8s.io/client-go/metadata/fake"
+)
+
+func TestPlan(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	var objs []runtime.Object
+	for _, name := range []string{"a", "b"} {
+		objs = append(objs, &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": name}},
+		})
+	}
+	client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), objs...)
+	fsys := cleanertest.MapFS{MapFS: fstest.MapFS{
+		"etc/cni/net.d/00-multus.conf":     {Data: []byte(`{"type": "multus"}`)},
+		"etc/cni/net.d/10-calico.conflist": {Data: []byte(`{"type": "calico"}`)},
+	}}
+	cfg := Config{
+		Files: []FileEntry{{Path: "/etc/cni/net.d/*", Glob: true, ExpectedContent: "multus"}},
+		Resources: []DeleteObj{
+			{GroupVersionResource: gvr, Namespace: "default"},
+			{GroupVersionResource: gvr, Namespace: "default", LabelSelector: "app=b", Action: ActionRemoveMetadata, Labels: []string{"app"}},
+			// already gone
+			{GroupVersionResource: gvr, Name: "c", Namespace: "default"},
+			{GroupVersionResource: gvr, Namespace: "default", Strategy: "unknown"},
+		},
+	}
+
+	plan, err := New(Options{MetadataClient: client, FS: fsys}).Plan(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if expected := []FileEntry{{Path: "/etc/cni/net.d/00-multus.conf", ExpectedContent: "multus"}}; !reflect.DeepEqual(plan.Files, expected) {
+		t.Errorf("expected files %v, got %v", expected, plan.Files)
+	}
+	expected := [][]types.NamespacedName{
+		{{Namespace: "default", Name: "a"}, {Namespace: "default", Name: "b"}},
+		{{Namespace: "default", Name: "b"}},
+		nil,
+		nil,
+	}
+	if len(plan.Resources) != len(expected) {
+		t.Fatalf("expected %d planned entries, got %d", len(expected), len(plan.Resources))
+	}
+	for i, entry := range plan.Resources {
+		if !reflect.DeepEqual(entry.Resources, expected[i]) {
+			t.Errorf("expected entry %d resources %v, got %v", i, expected[i], entry.Resources)
+		}
+	}
+	if err := plan.Resources[3].Err; !errors.Is(err, ErrConfigInvalid) {
+		t.Errorf("expected %v, got %v", ErrConfigInvalid, err)
+	}
+
+	if len(fsys.MapFS) != 2 {
+		t.Errorf("expected no files deleted, got %d remaining", len(fsys.MapFS))
+	}
+	list, err := client.Resource(gvr).Namespace("default").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 2 {
+		t.Errorf("expected no resources deleted, got %d remaining", len(list.Items))
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Policy evaluates whether a planned deletion, or removal of finalizers or metadata, is permitted, allowing
+// security teams to enforce guardrails on what may be deleted. It may be called concurrently if
+// EntryConcurrency is greater than 1.
+type Policy interface {
+	Evaluate(ctx context.Context, input PolicyInput) (PolicyDecision, error)
+}
+
+// PolicyInput describes a planned deletion, or removal of finalizers or metadata, to a Policy
+type PolicyInput struct {
+	// Action is the entry's action: delete, removeFinalizers or removeMetadata
+	Action      string            `json:"action"`
+	Group       string            `json:"group"`
+	Version     string            `json:"version"`
+	Resource    string            `json:"resource"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Name        string            `json:"name"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// PolicyDecision is the verdict of a Policy. Denied deletions are skipped, and the reason reported.
+type PolicyDecision struct {
+	Allowed bool   `json:"allow"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// NewPolicyInput returns the PolicyInput of an action on an object, e.g., for evaluating a Policy
+// before deletions outside the resource config
+func NewPolicyInput(action string, gvr schema.GroupVersionResource, obj metav1.Object) PolicyInput {
+	return PolicyInput{
+		Action: action, Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource,
+		Namespace: obj.GetNamespace(), Name: obj.GetName(), Labels: obj.GetLabels(), Annotations: obj.GetAnnotations(),
+	}
+}
+
+// checkPolicy evaluates the Policy option, if set, before an entry's action is applied to a resource.
+// A denied action is reported as skipped, and false returned. Failing to evaluate the policy is an error.
+func (c *Cleaner) checkPolicy(ctx context.Context, obj DeleteObj, r metav1.PartialObjectMetadata) (bool, error) {
+	if c.opts.Policy == nil {
+		return true, nil
+	}
+	action := obj.Action
+	if action == "" {
+		action = ActionDelete
+	}
+	gvrStr := obj.GroupVersionResource.String()
+	decision, err := c.opts.Policy.Evaluate(ctx, NewPolicyInput(action, obj.GroupVersionResource, &r))
+	if err != nil {
+		c.log.Error(err, "failed to evaluate policy, skipping", "action", action, "name", r.Name, "namespace", r.Namespace, "gvr", gvrStr)
+		return false, err
+	}
+	if !decision.Allowed {
+		c.log.Info("WARNING: denied by policy, skipping", "action", action, "name", r.Name, "namespace", r.Namespace, "gvr", gvrStr, "reason", decision.Reason)
+		event := resourceEvent(EventResourceSkipped, obj, types.NamespacedName{Namespace: r.Namespace, Name: r.Name}, nil)
+		event.Reason = "denied by policy"
+		if decision.Reason != "" {
+			event.Reason += ": " + decision.Reason
+		}
+		c.emit(event)
+		return false, nil
+	}
+	return true, nil
+}
+
+// OPAPolicy evaluates deletions with an Open Policy Agent decision, queried via OPA's Data API
+type OPAPolicy struct {
+	// URL is the decision's Data API endpoint, e.g., http://opa:8181/v1/data/spectro_cleanup/delete.
+	// The decision is either a boolean, or an object with an allow boolean and a reason string.
+	// Undefined decisions deny the deletion.
+	URL string
+
+	// Client queries OPA. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Evaluate implements Policy
+func (p *OPAPolicy) Evaluate(ctx context.Context, input PolicyInput) (PolicyDecision, error) {
+	body, err := json.Marshal(struct {
+		Input PolicyInput `json:"input"`
+	}{Input: input})
+	if err != nil {
+		return PolicyDecision{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return PolicyDecision{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return PolicyDecision{}, fmt.Errorf("failed to query OPA: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return PolicyDecision{}, fmt.Errorf("failed to query OPA: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	var decision struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decision); err != nil {
+		return PolicyDecision{}, fmt.Errorf("invalid OPA response: %w", err)
+	}
+	return parseOPAResult(decision.Result)
+}
+
+// parseOPAResult parses the result of an OPA decision, which is either a boolean, or an object with
+// an allow boolean and a reason string
+func parseOPAResult(result json.RawMessage) (PolicyDecision, error) {
+	if len(result) == 0 {
+		return PolicyDecision{Reason: "policy decision undefined"}, nil
+	}
+	var allowed bool
+	if err := json.Unmarshal(result, &allowed); err == nil {
+		return PolicyDecision{Allowed: allowed}, nil
+	}
+	decision := PolicyDecision{}
+	if err := json.Unmarshal(result, &decision); err != nil {
+		return PolicyDecision{}, fmt.Errorf("invalid OPA decision %s: %w", result, err)
+	}
+	return decision, nil
+}
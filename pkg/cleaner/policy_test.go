@@ -0,0 +1,213 @@
+package cleaner
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	metadatafake "k8s.io/client-go/metadata/fake"
+)
+
+func TestOPAPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		response    string
+		expected    PolicyDecision
+		expectedErr bool
+	}{
+		{
+			name:     "boolean allow",
+			status:   http.StatusOK,
+			response: `{"result": true}`,
+			expected: PolicyDecision{Allowed: true},
+		},
+		{
+			name:     "boolean deny",
+			status:   http.StatusOK,
+			response: `{"result": false}`,
+			expected: PolicyDecision{},
+		},
+		{
+			name:     "object deny with reason",
+			status:   http.StatusOK,
+			response: `{"result": {"allow": false, "reason": "namespace is protected"}}`,
+			expected: PolicyDecision{Reason: "namespace is protected"},
+		},
+		{
+			name:     "undefined decision",
+			status:   http.StatusOK,
+			response: `{}`,
+			expected: PolicyDecision{Reason: "policy decision undefined"},
+		},
+		{
+			name:        "invalid decision",
+			status:      http.StatusOK,
+			response:    `{"result": "yes"}`,
+			expectedErr: true,
+		},
+		{
+			name:        "server error",
+			status:      http.StatusInternalServerError,
+			response:    `{"code": "internal_error"}`,
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input PolicyInput
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					Input PolicyInput `json:"input"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("expected an input document, got %v", err)
+				}
+				input = body.Input
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			expectedInput := PolicyInput{Action: ActionDelete, Version: "v1", Resource: "configmaps", Namespace: "default", Name: "a", Labels: map[string]string{"app": "a"}}
+			decision, err := (&OPAPolicy{URL: server.URL}).Evaluate(context.Background(), expectedInput)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			if decision != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, decision)
+			}
+			if !reflect.DeepEqual(input, expectedInput) {
+				t.Errorf("expected input %+v, got %+v", expectedInput, input)
+			}
+		})
+	}
+}
+
+// namePolicy denies the deletion of the resource named deny
+type namePolicy struct {
+	deny string
+}
+
+func (p namePolicy) Evaluate(_ context.Context, input PolicyInput) (PolicyDecision, error) {
+	if input.Name == p.deny {
+		return PolicyDecision{Reason: "protected"}, nil
+	}
+	return PolicyDecision{Allowed: true}, nil
+}
+
+func TestPolicySkipsDenied(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	var objs []runtime.Object
+	for _, name := range []string{"a", "b", "c"} {
+		objs = append(objs, &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		})
+	}
+	client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), objs...)
+	var reasons []string
+	sink := EventSinkFunc(func(event Event) {
+		if event.Type == EventResourceSkipped {
+			reasons = append(reasons, event.Reason)
+		}
+	})
+
+	entry := DeleteObj{GroupVersionResource: gvr, Namespace: "default", MustDelete: true}
+	c := New(Options{MetadataClient: client, Policy: namePolicy{deny: "b"}, EventSink: sink})
+	result, err := c.CleanupResources(context.Background(), []DeleteObj{entry})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	er := result.Entries[0]
+	if er.Status != StatusSucceeded {
+		t.Errorf("expected status %s, got %s", StatusSucceeded, er.Status)
+	}
+	expectedDeleted := []types.NamespacedName{{Namespace: "default", Name: "a"}, {Namespace: "default", Name: "c"}}
+	if !reflect.DeepEqual(er.Deleted, expectedDeleted) {
+		t.Errorf("expected deleted %v, got %v", expectedDeleted, er.Deleted)
+	}
+	expectedSkipped := []types.NamespacedName{{Namespace: "default", Name: "b"}}
+	if !reflect.DeepEqual(er.Skipped, expectedSkipped) {
+		t.Errorf("expected skipped %v, got %v", expectedSkipped, er.Skipped)
+	}
+	if !reflect.DeepEqual(reasons, []string{"denied by policy: protected"}) {
+		t.Errorf("expected reason %q, got %v", "denied by policy: protected", reasons)
+	}
+	if _, err := client.Resource(gvr).Namespace("default").Get(context.Background(), "b", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected b to be retained, got %v", err)
+	}
+}
+
+// recordingPolicy records its inputs, denying every action
+type recordingPolicy struct {
+	inputs *[]PolicyInput
+}
+
+func (p recordingPolicy) Evaluate(_ context.Context, input PolicyInput) (PolicyDecision, error) {
+	*p.inputs = append(*p.inputs, input)
+	return PolicyDecision{}, nil
+}
+
+func TestPolicyInputs(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	labels := map[string]string{"app": "a"}
+	annotations := map[string]string{"owner": "team-a"}
+
+	tests := []struct {
+		name  string
+		entry DeleteObj
+	}{
+		{
+			name:  "named delete",
+			entry: DeleteObj{GroupVersionResource: gvr, Name: "a", Namespace: "default"},
+		},
+		{
+			name:  "named finalizer removal",
+			entry: DeleteObj{GroupVersionResource: gvr, Name: "a", Namespace: "default", Action: ActionRemoveFinalizers, Finalizers: []string{"example.com/finalizer"}},
+		},
+		{
+			name:  "metadata removal",
+			entry: DeleteObj{GroupVersionResource: gvr, Namespace: "default", Action: ActionRemoveMetadata, Labels: []string{"app"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), &metav1.PartialObjectMetadata{
+				TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+				ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default", Labels: labels, Annotations: annotations,
+					Finalizers: []string{"example.com/finalizer"}},
+			})
+			var inputs []PolicyInput
+			c := New(Options{MetadataClient: client, Policy: recordingPolicy{inputs: &inputs}})
+			if _, err := c.CleanupResources(context.Background(), []DeleteObj{tt.entry}); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			action := tt.entry.Action
+			if action == "" {
+				action = ActionDelete
+			}
+			expectedInputs := []PolicyInput{{Action: action, Version: "v1", Resource: "configmaps", Namespace: "default", Name: "a",
+				Labels: labels, Annotations: annotations}}
+			if !reflect.DeepEqual(inputs, expectedInputs) {
+				t.Errorf("expected inputs %+v, got %+v", expectedInputs, inputs)
+			}
+			for _, verb := range []string{"delete", "patch"} {
+				if n := countVerb(client.Actions(), verb); n != 0 {
+					t.Errorf("expected no %s of a denied resource, got %d", verb, n)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Built-in presets, selected by the preset field of a file or resource config entry
+const (
+	// PresetMultus removes the Multus meta-plugin
+	PresetMultus = "multus"
+	// PresetCilium removes the Cilium CNI
+	PresetCilium = "cilium"
+	// PresetCalico removes the Calico CNI
+	PresetCalico = "calico"
+	// PresetWhereabouts removes the Whereabouts IPAM plugin
+	PresetWhereabouts = "whereabouts"
+	// PresetCertManager removes cert-manager's certificates and their Secrets
+	PresetCertManager = "cert-manager"
+)
+
+// Preset is a named set of file and resource config entries, e.g., those removing a CNI from a
+// node and cluster. A config entry selecting the preset is replaced by its entries.
+type Preset struct {
+	Files     []FileEntry
+	Resources []DeleteObj
+}
+
+// Presets map preset names to their entries
+type Presets map[string]Preset
+
+var (
+	daemonSetsGVR          = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "daemonsets"}
+	deploymentsGVR         = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	configMapsGVR          = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	serviceAccountsGVR     = schema.GroupVersionResource{Version: "v1", Resource: "serviceaccounts"}
+	clusterRolesGVR        = schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}
+	clusterRoleBindingsGVR = schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterrolebindings"}
+	crdsGVR                = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+	secretsGVR             = schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+	certificatesGVR        = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}
+	certRequestsGVR        = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "certificaterequests"}
+	ordersGVR              = schema.GroupVersionResource{Group: "acme.cert-manager.io", Version: "v1", Resource: "orders"}
+	challengesGVR          = schema.GroupVersionResource{Group: "acme.cert-manager.io", Version: "v1", Resource: "challenges"}
+)
+
+// DefaultPresets remove the well-known CNIs from a node and cluster, and cert-manager's certificates
+// from a cluster. The CNI presets' file entries are host paths, prefixed with Options.HostRoot like
+// any other, and glob patterns, so that files absent from a node are skipped. Their resource entries
+// delete the CNI's workloads in kube-system, then its RBAC, and finally its CRDs, along with every
+// custom resource.
+var DefaultPresets = Presets{
+	PresetMultus: {
+		Files: []FileEntry{
+			{Path: "/etc/cni/net.d/00-multus.conf*", Glob: true},
+			{Path: "/etc/cni/net.d/multus.d/*", Glob: true, PruneEmptyParents: true, PruneBoundary: "/etc/cni/net.d"},
+			{Path: "/opt/cni/bin/multus*", Glob: true},
+		},
+		Resources: cniResources("kube-multus-ds", nil, "multus", []string{"multus"}, []string{"multus-cni-config"},
+			"network-attachment-definitions.k8s.cni.cncf.io"),
+	},
+	PresetCilium: {
+		Files: []FileEntry{
+			{Path: "/etc/cni/net.d/05-cilium.conf*", Glob: true},
+			{Path: "/opt/cni/bin/cilium-cni*", Glob: true},
+		},
+		Resources: append(
+			cniResources("cilium", []string{"cilium-operator"}, "cilium", []string{"cilium", "cilium-operator"}, []string{"cilium-config"}),
+			// every Cilium CRD carries its schema version label
+			DeleteObj{GroupVersionResource: crdsGVR, LabelSelector: "io.cilium.k8s.crd.schema.version", ConfirmHighRisk: true},
+		),
+	},
+	PresetCalico: {
+		Files: []FileEntry{
+			{Path: "/etc/cni/net.d/10-calico.conf*", Glob: true},
+			{Path: "/etc/cni/net.d/calico-kubeconfig*", Glob: true},
+			{Path: "/opt/cni/bin/calico*", Glob: true},
+			{Path: "/var/lib/calico/*", Glob: true, PruneEmptyParents: true, PruneBoundary: "/var/lib"},
+			{Path: "/var/run/calico/*", Glob: true, PruneEmptyParents: true, PruneBoundary: "/var/run"},
+		},
+		Resources: cniResources("calico-node", []string{"calico-kube-controllers"}, "calico-node",
+			[]string{"calico-node", "calico-kube-controllers"}, []string{"calico-config"},
+			"bgpconfigurations.crd.projectcalico.org",
+			"bgpfilters.crd.projectcalico.org",
+			"bgppeers.crd.projectcalico.org",
+			"blockaffinities.crd.projectcalico.org",
+			"caliconodestatuses.crd.projectcalico.org",
+			"clusterinformations.crd.projectcalico.org",
+			"felixconfigurations.crd.projectcalico.org",
+			"globalnetworkpolicies.crd.projectcalico.org",
+			"globalnetworksets.crd.projectcalico.org",
+			"hostendpoints.crd.projectcalico.org",
+			"ipamblocks.crd.projectcalico.org",
+			"ipamconfigs.crd.projectcalico.org",
+			"ipamhandles.crd.projectcalico.org",
+			"ippools.crd.projectcalico.org",
+			"ipreservations.crd.projectcalico.org",
+			"kubecontrollersconfigurations.crd.projectcalico.org",
+			"networkpolicies.crd.projectcalico.org",
+			"networksets.crd.projectcalico.org",
+		),
+	},
+	PresetWhereabouts: {
+		Files: []FileEntry{
+			{Path: "/etc/cni/net.d/whereabouts.d/*", Glob: true, PruneEmptyParents: true, PruneBoundary: "/etc/cni/net.d"},
+			{Path: "/opt/cni/bin/whereabouts*", Glob: true},
+		},
+		Resources: cniResources("whereabouts", nil, "whereabouts", []string{"whereabouts-cni"}, nil,
+			"ippools.whereabouts.cni.cncf.io",
+			"overlappingrangeipreservations.whereabouts.cni.cncf.io"),
+	},
+	// Certificates are deleted first, as cert-manager reissues a Certificate whose Secret is gone,
+	// followed by what they own, top down, so that no owner remains to recreate what it owns. Each
+	// entry waits for its resources to be gone, and the Secrets cert-manager issued are only deleted
+	// once nothing remains to reissue them.
+	PresetCertManager: {
+		Resources: []DeleteObj{
+			{GroupVersionResource: certificatesGVR, Wait: true, ConfirmHighRisk: true},
+			{GroupVersionResource: certRequestsGVR, Wait: true, ConfirmHighRisk: true},
+			{GroupVersionResource: ordersGVR, Wait: true, ConfirmHighRisk: true},
+			{GroupVersionResource: challengesGVR, Wait: true, ConfirmHighRisk: true},
+			// cert-manager labels the Secrets it issues for its filtered cache
+			{GroupVersionResource: secretsGVR, LabelSelector: "controller.cert-manager.io/fao=true", ConfirmHighRisk: true},
+		},
+	},
+}
+
+// cniResources returns the resource entries deleting a CNI's DaemonSet and Deployments, ConfigMaps
+// and ServiceAccount in kube-system, its ClusterRoles and ClusterRoleBindings of the same names, and
+// the named CRDs
+func cniResources(daemonSet string, deployments []string, serviceAccount string, clusterRoles, configMaps []string, crds ...string) []DeleteObj {
+	entries := []DeleteObj{{GroupVersionResource: daemonSetsGVR, Name: daemonSet, Namespace: metav1.NamespaceSystem}}
+	for _, name := range deployments {
+		entries = append(entries, DeleteObj{GroupVersionResource: deploymentsGVR, Name: name, Namespace: metav1.NamespaceSystem})
+	}
+	for _, name := range configMaps {
+		entries = append(entries, DeleteObj{GroupVersionResource: configMapsGVR, Name: name, Namespace: metav1.NamespaceSystem})
+	}
+	entries = append(entries, DeleteObj{GroupVersionResource: serviceAccountsGVR, Name: serviceAccount, Namespace: metav1.NamespaceSystem})
+	for _, name := range clusterRoles {
+		entries = append(entries,
+			DeleteObj{GroupVersionResource: clusterRoleBindingsGVR, Name: name},
+			DeleteObj{GroupVersionResource: clusterRolesGVR, Name: name},
+		)
+	}
+	for _, name := range crds {
+		entries = append(entries, DeleteObj{GroupVersionResource: crdsGVR, Name: name})
+	}
+	return entries
+}
+
+// ExpandFiles replaces each file entry selecting a preset with the preset's file entries
+func (p Presets) ExpandFiles(entries []FileEntry) ([]FileEntry, error) {
+	expanded := make([]FileEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Preset == "" {
+			expanded = append(expanded, entry)
+			continue
+		}
+		preset, ok := p[entry.Preset]
+		if !ok {
+			return nil, fmt.Errorf("%w: file entry selects unknown preset %q", ErrConfigInvalid, entry.Preset)
+		}
+		if entry.Path != "" {
+			return nil, fmt.Errorf("%w: file entry selecting preset %q may not set a path", ErrConfigInvalid, entry.Preset)
+		}
+		expanded = append(expanded, preset.Files...)
+	}
+	return expanded, nil
+}
+
+// ExpandResources replaces each resource entry selecting a preset with the preset's resource entries
+func (p Presets) ExpandResources(entries []DeleteObj) ([]DeleteObj, error) {
+	expanded := make([]DeleteObj, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Preset == "" {
+			expanded = append(expanded, entry)
+			continue
+		}
+		preset, ok := p[entry.Preset]
+		if !ok {
+			return nil, fmt.Errorf("%w: resource entry selects unknown preset %q", ErrConfigInvalid, entry.Preset)
+		}
+		if entry.GroupVersionResource != (schema.GroupVersionResource{}) || entry.Name != "" {
+			return nil, fmt.Errorf("%w: resource entry selecting preset %q may not set a resource or name", ErrConfigInvalid, entry.Preset)
+		}
+		expanded = append(expanded, preset.Resources...)
+	}
+	return expanded, nil
+}
@@ -0,0 +1,115 @@
+package cleaner
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestPresetsExpandFiles(t *testing.T) {
+	presets := Presets{"widget": {Files: []FileEntry{{Path: "/etc/widget.conf"}, {Path: "/opt/widget"}}}}
+
+	tests := []struct {
+		name          string
+		entries       []FileEntry
+		expected      []FileEntry
+		expectedError error
+	}{
+		{
+			name:     "without presets",
+			entries:  []FileEntry{{Path: "/etc/gadget.conf"}},
+			expected: []FileEntry{{Path: "/etc/gadget.conf"}},
+		},
+		{
+			name:     "preset expanded in place",
+			entries:  []FileEntry{{Path: "/etc/gadget.conf"}, {Preset: "widget"}, {Path: "/opt/gadget"}},
+			expected: []FileEntry{{Path: "/etc/gadget.conf"}, {Path: "/etc/widget.conf"}, {Path: "/opt/widget"}, {Path: "/opt/gadget"}},
+		},
+		{
+			name:          "unknown preset",
+			entries:       []FileEntry{{Preset: "gadget"}},
+			expectedError: ErrConfigInvalid,
+		},
+		{
+			name:          "preset with path",
+			entries:       []FileEntry{{Preset: "widget", Path: "/etc/widget.conf"}},
+			expectedError: ErrConfigInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := presets.ExpandFiles(tt.entries)
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, got %v", tt.expectedError, err)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPresetsExpandResources(t *testing.T) {
+	widgets := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	presets := Presets{"widget": {Resources: []DeleteObj{{GroupVersionResource: widgets, Name: "a"}, {GroupVersionResource: widgets, Name: "b"}}}}
+	final := DeleteObj{GroupVersionResource: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "daemonsets"}, Name: "spectro-cleanup"}
+
+	tests := []struct {
+		name          string
+		entries       []DeleteObj
+		expected      []DeleteObj
+		expectedError error
+	}{
+		{
+			name:     "preset expanded in place",
+			entries:  []DeleteObj{{Preset: "widget"}, final},
+			expected: []DeleteObj{{GroupVersionResource: widgets, Name: "a"}, {GroupVersionResource: widgets, Name: "b"}, final},
+		},
+		{
+			name:          "unknown preset",
+			entries:       []DeleteObj{{Preset: "gadget"}, final},
+			expectedError: ErrConfigInvalid,
+		},
+		{
+			name:          "preset with name",
+			entries:       []DeleteObj{{Preset: "widget", Name: "a"}, final},
+			expectedError: ErrConfigInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := presets.ExpandResources(tt.entries)
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, got %v", tt.expectedError, err)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDefaultPresetsValid(t *testing.T) {
+	for name, preset := range DefaultPresets {
+		t.Run(name, func(t *testing.T) {
+			if len(preset.Files) == 0 && len(preset.Resources) == 0 {
+				t.Errorf("expected file or resource entries, got none")
+			}
+			for _, file := range preset.Files {
+				if _, err := filepath.Match(file.Path, ""); err != nil {
+					t.Errorf("expected valid pattern %s, got %v", file.Path, err)
+				}
+			}
+			for _, entry := range preset.Resources {
+				if err := entry.Validate(); err != nil {
+					t.Errorf("expected valid entry, got %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Status is the outcome of a resource config entry or file entry
+type Status string
+
+// Outcomes of resource config entries and file entries
+const (
+	// StatusSucceeded is the status of an entry whose action succeeded, or of a deleted file
+	StatusSucceeded Status = "Succeeded"
+	// StatusFailed is the status of an entry whose action failed, or of a file that failed to be deleted
+	StatusFailed Status = "Failed"
+	// StatusSkipped is the status of an entry that wasn't processed, e.g., as a checkpoint records it as
+	// completed or the cleanup was stopped first, or of a file that wasn't deleted by design
+	StatusSkipped Status = "Skipped"
+)
+
+// Result is the outcome of a cleanup
+type Result struct {
+	// Entries are the outcomes of the resource config entries, in config order
+	Entries []EntryResult
+
+	// Files are the outcomes of the file entries, after glob patterns are expanded
+	Files []FileResult
+
+	// Duration is how long the cleanup took
+	Duration time.Duration
+}
+
+// EntryResult is the outcome of a resource config entry
+type EntryResult struct {
+	Entry  DeleteObj
+	Status Status
+
+	// Matched is the number of resources the entry acted on, after excluding protected namespaces,
+	// and managed and Argo CD hook resources
+	Matched int
+
+	// Deleted are the resources deleted by the entry's delete action, and Updated those whose finalizers,
+	// labels or annotations were removed. Failed are the resources the entry's action failed for,
+	// and Skipped those whose deletion was denied by the Policy option, or that are outside the tenant.
+	Deleted []types.NamespacedName
+	Updated []types.NamespacedName
+	Failed  []types.NamespacedName
+	Skipped []types.NamespacedName
+
+	// Snapshots are the states of the resources a MustDelete entry deleted or failed to delete,
+	// read immediately before each deletion
+	Snapshots []ResourceSnapshot
+
+	// Duration is how long the entry took to process, including waiting for its deletions
+	Duration time.Duration
+
+	// Err is the failure of a failed entry, whether or not it is MustDelete
+	Err error
+}
+
+// FileResult is the outcome of a file entry
+type FileResult struct {
+	Path   string
+	Status Status
+
+	// Reason explains why a file was skipped
+	Reason string
+
+	// Err is the failure of a file that failed to be deleted
+	Err error
+}
+
+// Count returns the number of resource config entries and files with a status
+func (r *Result) Count(status Status) int {
+	count := 0
+	for _, e := range r.Entries {
+		if e.Status == status {
+			count++
+		}
+	}
+	for _, f := range r.Files {
+		if f.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// GVRStats are the outcomes of the resource config entries of a GroupVersionResource
+type GVRStats struct {
+	schema.GroupVersionResource
+
+	// Entries is the number of processed entries, and Matched, Deleted, Updated, Failed and
+	// Skipped the total numbers of resources recorded by their results
+	Entries int
+	Matched int
+	Deleted int
+	Updated int
+	Failed  int
+	Skipped int
+
+	// Duration is how long the entries took to process, including waiting for their deletions
+	Duration time.Duration
+}
+
+// AverageWait returns the average time taken per matched resource
+func (s GVRStats) AverageWait() time.Duration {
+	if s.Matched == 0 {
+		return 0
+	}
+	return s.Duration / time.Duration(s.Matched)
+}
+
+// Stats aggregates the outcomes of the processed resource config entries per GroupVersionResource,
+// ordered by decreasing Duration, so that the types dominating the cleanup time come first
+func (r *Result) Stats() []GVRStats {
+	var stats []GVRStats
+	index := map[schema.GroupVersionResource]int{}
+	for _, e := range r.Entries {
+		if e.Status == StatusSkipped {
+			continue
+		}
+		gvr := e.Entry.GroupVersionResource
+		i, ok := index[gvr]
+		if !ok {
+			i = len(stats)
+			index[gvr] = i
+			stats = append(stats, GVRStats{GroupVersionResource: gvr})
+		}
+		s := &stats[i]
+		s.Entries++
+		s.Matched += e.Matched
+		s.Deleted += len(e.Deleted)
+		s.Updated += len(e.Updated)
+		s.Failed += len(e.Failed)
+		s.Skipped += len(e.Skipped)
+		s.Duration += e.Duration
+	}
+	slices.SortStableFunc(stats, func(a, b GVRStats) int {
+		return cmp.Compare(b.Duration, a.Duration)
+	})
+	return stats
+}
+
+// record records the outcome of a resource event
+func (r *EntryResult) record(event Event) {
+	if event.Snapshot != nil {
+		r.Snapshots = append(r.Snapshots, *event.Snapshot)
+	}
+	switch event.Type {
+	case EventResourcesMatched:
+		r.Matched = event.Count
+	case EventResourceDeleted:
+		r.Deleted = append(r.Deleted, event.Resource)
+	case EventResourceUpdated:
+		r.Updated = append(r.Updated, event.Resource)
+	case EventResourceFailed:
+		r.Failed = append(r.Failed, event.Resource)
+	case EventResourceSkipped:
+		r.Skipped = append(r.Skipped, event.Resource)
+	}
+}
+
+// record records the outcome of a file event
+func (r *Result) record(event Event) {
+	switch event.Type {
+	case EventFileDeleted:
+		r.Files = append(r.Files, FileResult{Path: event.Path, Status: StatusSucceeded})
+	case EventFileSkipped:
+		r.Files = append(r.Files, FileResult{Path: event.Path, Status: StatusSkipped, Reason: event.Reason})
+	case EventFileFailed:
+		r.Files = append(r.Files, FileResult{Path: event.Path, Status: StatusFailed, Err: event.Err})
+	}
+}
+
+// recording returns a copy of the Cleaner whose events are recorded by record, in addition to being
+// sent to the EventSink option, so that results are derived from the same events consumers receive
+func (c *Cleaner) recording(record func(Event)) *Cleaner {
+	rc := *c
+	sink := c.opts.EventSink
+	rc.opts.EventSink = EventSinkFunc(func(event Event) {
+		record(event)
+		if sink != nil {
+			sink.Emit(event)
+		}
+	})
+	return &rc
+}
@@ -0,0 +1,136 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner/cleanertest"
+)
+
+// skipFirst is a Checkpoint recording the first entry as completed
+type skipFirst struct{}
+
+func (skipFirst) Completed(i int) bool { return i == 0 }
+func (skipFirst) Complete(int)         {}
+
+func TestResourcesResult(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	var objs []runtime.Object
+	for _, name := range []string{"a", "b", "c"} {
+		objs = append(objs, &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": name}},
+		})
+	}
+	client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), objs...)
+	client.PrependReactor("delete", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if action.(clienttesting.DeleteAction).GetName() == "c" {
+			return true, nil, apierrors.NewForbidden(gvr.GroupResource(), "c", errors.New("denied"))
+		}
+		return false, nil, nil
+	})
+
+	entries := []DeleteObj{
+		{GroupVersionResource: gvr, Namespace: "default", Name: "a"},
+		{GroupVersionResource: gvr, Namespace: "default", LabelSelector: "app in (a,b)"},
+		{GroupVersionResource: gvr, Namespace: "default", Name: "c"},
+		{GroupVersionResource: gvr, Namespace: "default", Name: "missing"},
+	}
+	result, err := New(Options{MetadataClient: client, Checkpoint: skipFirst{}}).CleanupResources(context.Background(), entries)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expectedStatuses := []Status{StatusSkipped, StatusSucceeded, StatusFailed, StatusSucceeded}
+	for i, entry := range result.Entries {
+		if entry.Status != expectedStatuses[i] {
+			t.Errorf("expected entry %d status %s, got %s", i, expectedStatuses[i], entry.Status)
+		}
+	}
+	if result.Entries[1].Matched != 2 {
+		t.Errorf("expected 2 matched, got %d", result.Entries[1].Matched)
+	}
+	deleted := []types.NamespacedName{{Namespace: "default", Name: "a"}, {Namespace: "default", Name: "b"}}
+	if !reflect.DeepEqual(result.Entries[1].Deleted, deleted) {
+		t.Errorf("expected deleted %v, got %v", deleted, result.Entries[1].Deleted)
+	}
+	failed := []types.NamespacedName{{Namespace: "default", Name: "c"}}
+	if !reflect.DeepEqual(result.Entries[2].Failed, failed) {
+		t.Errorf("expected failed %v, got %v", failed, result.Entries[2].Failed)
+	}
+	if !errors.Is(result.Entries[2].Err, ErrForbidden) {
+		t.Errorf("expected %v, got %v", ErrForbidden, result.Entries[2].Err)
+	}
+	if result.Count(StatusSucceeded) != 2 || result.Count(StatusFailed) != 1 || result.Count(StatusSkipped) != 1 {
+		t.Errorf("expected 2 succeeded, 1 failed and 1 skipped, got %v", result.Entries)
+	}
+}
+
+func TestResultStats(t *testing.T) {
+	configMaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	secrets := schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+	a, b := types.NamespacedName{Name: "a"}, types.NamespacedName{Name: "b"}
+	result := &Result{Entries: []EntryResult{
+		{Entry: DeleteObj{GroupVersionResource: configMaps}, Status: StatusSucceeded, Matched: 2, Deleted: []types.NamespacedName{a, b}, Duration: 2 * time.Second},
+		{Entry: DeleteObj{GroupVersionResource: secrets}, Status: StatusFailed, Matched: 2, Deleted: []types.NamespacedName{a}, Failed: []types.NamespacedName{b}, Duration: 4 * time.Second},
+		{Entry: DeleteObj{GroupVersionResource: configMaps}, Status: StatusSucceeded, Matched: 2, Skipped: []types.NamespacedName{a, b}, Duration: 4 * time.Second},
+		{Entry: DeleteObj{GroupVersionResource: secrets}, Status: StatusSkipped},
+	}}
+
+	expected := []GVRStats{
+		{GroupVersionResource: configMaps, Entries: 2, Matched: 4, Deleted: 2, Skipped: 2, Duration: 6 * time.Second},
+		{GroupVersionResource: secrets, Entries: 1, Matched: 2, Deleted: 1, Failed: 1, Duration: 4 * time.Second},
+	}
+	stats := result.Stats()
+	if !reflect.DeepEqual(stats, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, stats)
+	}
+	if wait := stats[0].AverageWait(); wait != 1500*time.Millisecond {
+		t.Errorf("expected average wait 1.5s, got %s", wait)
+	}
+	if wait := (GVRStats{}).AverageWait(); wait != 0 {
+		t.Errorf("expected no average wait, got %s", wait)
+	}
+}
+
+func TestFilesResult(t *testing.T) {
+	fsys := cleanertest.MapFS{MapFS: fstest.MapFS{
+		"etc/cni/net.d/00-multus.conf":     {Data: []byte(`{"type": "multus"}`)},
+		"etc/cni/net.d/10-calico.conflist": {Data: []byte(`{"type": "calico"}`)},
+	}}
+	files := []FileEntry{
+		{Path: "/etc/cni/net.d/*.conf", Glob: true},
+		{Path: "/etc/cni/net.d/10-calico.conflist", ExpectedContent: "multus"},
+		{Path: "/etc/cni/net.d/99-missing.conflist"},
+	}
+
+	result, err := New(Options{FS: fsys}).CleanupFiles(context.Background(), files)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []Status{StatusSucceeded, StatusSkipped, StatusFailed}
+	if len(result.Files) != len(expected) {
+		t.Fatalf("expected %d file results, got %v", len(expected), result.Files)
+	}
+	for i, file := range result.Files {
+		if file.Status != expected[i] {
+			t.Errorf("expected %s status %s, got %s", file.Path, expected[i], file.Status)
+		}
+	}
+	if result.Files[0].Path != "/etc/cni/net.d/00-multus.conf" {
+		t.Errorf("expected the expanded glob pattern, got %s", result.Files[0].Path)
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"context"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ResourceSnapshot is the state of a resource immediately before a MustDelete entry deleted it, so
+// that failed uninstalls can be debugged from the cleanup's result alone, without cluster access
+type ResourceSnapshot struct {
+	Resource types.NamespacedName `json:"resource"`
+	Time     time.Time            `json:"time"`
+
+	// Phase and Conditions are the resource's status.phase and status.conditions, if any. They are only
+	// recorded if the Client option is set.
+	Phase      string              `json:"phase,omitempty"`
+	Conditions []SnapshotCondition `json:"conditions,omitempty"`
+
+	Finalizers        []string     `json:"finalizers,omitempty"`
+	DeletionTimestamp *metav1.Time `json:"deletionTimestamp,omitempty"`
+}
+
+// SnapshotCondition is a status condition of a resource snapshot
+type SnapshotCondition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// snapshot returns the state of a resource about to be deleted, reading it via the Client option, if
+// set, or else via the metadata API. Failing to read it never blocks the deletion, and only the
+// resource's key is recorded; a resource that is already gone has no snapshot.
+func (c *Cleaner) snapshot(ctx context.Context, gvr schema.GroupVersionResource, r metav1.PartialObjectMetadata) *ResourceSnapshot {
+	snapshot := &ResourceSnapshot{Resource: types.NamespacedName{Namespace: r.Namespace, Name: r.Name}, Time: c.opts.Clock.Now()}
+	err := c.readSnapshot(ctx, gvr, snapshot)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		c.log.Info("WARNING: failed to snapshot resource before deletion", "name", r.Name, "namespace", r.Namespace,
+			"gvr", gvr.String(), "error", err.Error())
+	}
+	return snapshot
+}
+
+// readSnapshot reads a resource's finalizers, deletionTimestamp and, via the Client option, its status into a snapshot
+func (c *Cleaner) readSnapshot(ctx context.Context, gvr schema.GroupVersionResource, snapshot *ResourceSnapshot) error {
+	if c.opts.Client == nil {
+		m, err := c.opts.MetadataClient.Resource(gvr).Namespace(snapshot.Resource.Namespace).Get(ctx, snapshot.Resource.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		snapshot.Finalizers, snapshot.DeletionTimestamp = m.Finalizers, m.DeletionTimestamp
+		return nil
+	}
+
+	gvk, err := c.opts.Client.RESTMapper().KindFor(gvr)
+	if err != nil {
+		return err
+	}
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	if err := c.opts.Client.Get(ctx, snapshot.Resource, u); err != nil {
+		return err
+	}
+	snapshot.Finalizers, snapshot.DeletionTimestamp = u.GetFinalizers(), u.GetDeletionTimestamp()
+	snapshot.Phase, _, _ = unstructured.NestedString(u.Object, "status", "phase")
+	conditions, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		snapshot.Conditions = append(snapshot.Conditions, SnapshotCondition{
+			Type:    stringField(condition, "type"),
+			Status:  stringField(condition, "status"),
+			Reason:  stringField(condition, "reason"),
+			Message: stringField(condition, "message"),
+		})
+	}
+	return nil
+}
+
+// stringField returns a string field of an unstructured object, or "" if it isn't a string
+func stringField(obj map[string]interface{}, field string) string {
+	s, _ := obj[field].(string)
+	return s
+}
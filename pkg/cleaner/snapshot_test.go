@@ -0,0 +1,87 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	clienttesting "k8s.io/client-go/testing"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestResourceSnapshots(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{corev1.SchemeGroupVersion})
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Pod"), meta.RESTScopeNamespace)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "operator", Namespace: "widgets", Finalizers: []string{"example.com/drain"}},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{
+			{Type: corev1.PodReady, Status: corev1.ConditionFalse, Reason: "ContainersNotReady", Message: "containers with unready status: [operator]"},
+		}},
+	}
+	scheme := runtime.NewScheme()
+	scheme.AddKnownTypeWithName(corev1.SchemeGroupVersion.WithKind("Pod"), &metav1.PartialObjectMetadata{})
+	scheme.AddKnownTypeWithName(corev1.SchemeGroupVersion.WithKind("PodList"), &metav1.PartialObjectMetadataList{})
+	resource := types.NamespacedName{Namespace: "widgets", Name: "operator"}
+
+	tests := []struct {
+		name       string
+		client     ctrlclient.Client
+		mustDelete bool
+		expected   []ResourceSnapshot
+	}{
+		{
+			name:       "with client",
+			client:     fake.NewClientBuilder().WithRESTMapper(mapper).WithObjects(pod).Build(),
+			mustDelete: true,
+			expected: []ResourceSnapshot{{
+				Resource:   resource,
+				Phase:      "Running",
+				Conditions: []SnapshotCondition{{Type: "Ready", Status: "False", Reason: "ContainersNotReady", Message: "containers with unready status: [operator]"}},
+				Finalizers: []string{"example.com/drain"},
+			}},
+		},
+		{
+			name:       "metadata only",
+			mustDelete: true,
+			expected:   []ResourceSnapshot{{Resource: resource, Finalizers: []string{"example.com/drain"}}},
+		},
+		{
+			name: "not must delete",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadataClient := metadatafake.NewSimpleMetadataClient(scheme, &metav1.PartialObjectMetadata{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+				ObjectMeta: metav1.ObjectMeta{Name: "operator", Namespace: "widgets", Finalizers: []string{"example.com/drain"}},
+			})
+			metadataClient.PrependReactor("delete", "pods", func(clienttesting.Action) (bool, runtime.Object, error) {
+				return true, nil, apierrors.NewInternalError(errors.New("etcd timeout"))
+			})
+
+			entries := []DeleteObj{{GroupVersionResource: corev1.SchemeGroupVersion.WithResource("pods"), Name: "operator", Namespace: "widgets", MustDelete: tt.mustDelete}}
+			result, _ := New(Options{Client: tt.client, MetadataClient: metadataClient}).CleanupResources(context.Background(), entries)
+			snapshots := result.Entries[0].Snapshots
+			for i := range snapshots {
+				if snapshots[i].Time.IsZero() {
+					t.Errorf("expected snapshot %d to be timestamped", i)
+				}
+				snapshots[i].Time = tt.expected[i].Time
+			}
+			if !reflect.DeepEqual(snapshots, tt.expected) {
+				t.Errorf("expected snapshots %+v, got %+v", tt.expected, snapshots)
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Built-in deletion strategies, referenced by the Strategy of a resource config entry
+const (
+	// StrategyDirect deletes each resource. This is the default.
+	StrategyDirect = "direct"
+	// StrategyScaleThenDelete scales each resource to zero replicas, waiting for its replicas to be
+	// gone before deleting it, so that workloads shut down gracefully, in order
+	StrategyScaleThenDelete = "scaleThenDelete"
+	// StrategyFinalizerStrip deletes each resource and then strips its finalizers, or the entry's
+	// Finalizers if set, e.g., when the controllers responsible for them have been uninstalled
+	StrategyFinalizerStrip = "finalizerStrip"
+	// StrategyForegroundWithTimeout deletes each resource with foreground propagation, so that its
+	// dependents are deleted first, and waits for it to be gone. If the foregroundDeletion finalizer
+	// hasn't cleared by the entry's timeout, the resource is deleted again with background propagation,
+	// and the foregroundDeletion finalizer, along with the entry's Finalizers, is stripped.
+	StrategyForegroundWithTimeout = "foregroundWithTimeout"
+)
+
+// defaultScaleDownTimeout bounds the wait for a resource's replicas to be gone, and
+// defaultForegroundTimeout the wait for a resource deleted in the foreground to be gone, if neither
+// the entry's TimeoutSeconds nor the DeletionTimeout option is set
+const (
+	defaultScaleDownTimeout  = 2 * time.Minute
+	defaultForegroundTimeout = 2 * time.Minute
+)
+
+// DeletionStrategy deletes the resources matched by a resource config entry with the delete
+// action. Custom strategies are registered by name via Options.Strategies.
+type DeletionStrategy interface {
+	// Plan returns the resources the entry deletes
+	Plan(ctx context.Context, entry DeleteObj) ([]metav1.PartialObjectMetadata, error)
+
+	// Delete deletes one of the planned resources. Returning a NotFound error, or one caused by
+	// the resource's namespace terminating, is equivalent to success.
+	Delete(ctx context.Context, entry DeleteObj, resource metav1.PartialObjectMetadata) error
+
+	// Verify blocks until the deleted resources are gone, returning an error if they aren't. It is
+	// only called if the DeletionTimeout option is set, or the entry sets Wait.
+	Verify(ctx context.Context, entry DeleteObj, deleted []metav1.PartialObjectMetadata) error
+}
+
+// strategy returns the DeletionStrategy of an entry, preferring those registered via Options.Strategies
+func (c *Cleaner) strategy(obj DeleteObj) (DeletionStrategy, error) {
+	if s, ok := c.opts.Strategies[obj.Strategy]; ok {
+		return s, nil
+	}
+	direct := directStrategy{c: c}
+	switch obj.Strategy {
+	case "", StrategyDirect:
+		return direct, nil
+	case StrategyScaleThenDelete:
+		return scaleThenDeleteStrategy{directStrategy: direct}, nil
+	case StrategyFinalizerStrip:
+		return finalizerStripStrategy{directStrategy: direct}, nil
+	case StrategyForegroundWithTimeout:
+		return foregroundWithTimeoutStrategy{directStrategy: direct}, nil
+	}
+	return nil, fmt.Errorf("%w: resource entry %s %s/%s: unknown strategy %q", ErrConfigInvalid,
+		obj.GroupVersionResource, obj.Namespace, obj.Name, obj.Strategy)
+}
+
+// directStrategy deletes each resource via the metadata API
+type directStrategy struct {
+	c *Cleaner
+}
+
+// Plan returns the metadata of every resource matching an entry without a name, or the resource
+// named by the entry. A named resource's metadata is only read if the managed, Argo CD hook or tenant
+// guards need its labels, annotations or owners; a named resource that is already gone is then planned
+// as deleted, i.e., not at all. If reading it is forbidden, it is planned by name alone, with a
+// warning, so its guards don't apply. Named resources outside the tenant are left for the deletion's
+// tenant recheck to skip.
+func (s directStrategy) Plan(ctx context.Context, obj DeleteObj) ([]metav1.PartialObjectMetadata, error) {
+	if obj.Name == "" {
+		return s.c.matchingResources(ctx, obj)
+	}
+	named := []metav1.PartialObjectMetadata{{ObjectMeta: metav1.ObjectMeta{Name: obj.Name, Namespace: obj.Namespace}}}
+	if s.c.opts.ForceManaged && !s.c.opts.SkipArgoCDHooks && s.c.tenant == nil {
+		return named, nil
+	}
+	m, err := s.c.opts.MetadataClient.Resource(obj.GroupVersionResource).Namespace(obj.Namespace).Get(ctx, obj.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if apierrors.IsForbidden(err) {
+		s.c.log.Info("WARNING: not permitted to get resource, deleting it without checking whether it is managed or an Argo CD hook",
+			"name", obj.Name, "namespace", obj.Namespace, "gvr", obj.GroupVersionResource.String())
+		return named, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return []metav1.PartialObjectMetadata{*m}, nil
+}
+
+// Delete deletes a resource, bypassing admission webhooks if enabled. The metadata API is used,
+// rather than the dynamic client, as it negotiates protobuf with the API server for built-in types.
+func (s directStrategy) Delete(ctx context.Context, obj DeleteObj, r metav1.PartialObjectMetadata) error {
+	return s.deleteWith(ctx, obj, r, s.c.opts.PropagationPolicy)
+}
+
+// deleteWith deletes a resource with a propagation policy, bypassing admission webhooks if enabled
+func (s directStrategy) deleteWith(ctx context.Context, obj DeleteObj, r metav1.PartialObjectMetadata, policy metav1.DeletionPropagation) error {
+	return s.c.deleteBypassingWebhooks(ctx, func(ctx context.Context) error {
+		return s.c.opts.MetadataClient.Resource(obj.GroupVersionResource).Namespace(r.Namespace).Delete(
+			ctx, r.Name, metav1.DeleteOptions{PropagationPolicy: &policy},
+		)
+	})
+}
+
+// Verify waits for the deleted resources to be gone
+func (s directStrategy) Verify(ctx context.Context, obj DeleteObj, deleted []metav1.PartialObjectMetadata) error {
+	if s.c.waiter == nil {
+		return nil
+	}
+	if err := s.c.waiter.waitForDeletion(ctx, obj, deleted); err != nil {
+		s.c.log.Error(err, "resource deletion not confirmed", "name", obj.Name, "namespace", obj.Namespace, "gvr", obj.GroupVersionResource.String())
+		return err
+	}
+	return nil
+}
+
+// scaleThenDeleteStrategy scales each resource's spec.replicas to zero before deleting it. The
+// resource is read via the Client option, which is required.
+type scaleThenDeleteStrategy struct {
+	directStrategy
+}
+
+// Delete scales a resource to zero, waits for its status.replicas to reach zero, and deletes it.
+// If its replicas aren't gone by the entry's timeout, it is deleted regardless.
+func (s scaleThenDeleteStrategy) Delete(ctx context.Context, obj DeleteObj, r metav1.PartialObjectMetadata) error {
+	client := s.c.opts.Client
+	if client == nil {
+		return fmt.Errorf("%w: strategy %s requires a client", ErrConfigInvalid, StrategyScaleThenDelete)
+	}
+	gvk, err := client.RESTMapper().KindFor(obj.GroupVersionResource)
+	if err != nil {
+		return err
+	}
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	u.SetName(r.Name)
+	u.SetNamespace(r.Namespace)
+
+	s.c.log.Info("Scaling resource to zero replicas", "name", r.Name, "namespace", r.Namespace, "gvr", obj.GroupVersionResource.String())
+	if err := s.c.retry(ctx, func(ctx context.Context) error {
+		return client.Patch(ctx, u, ctrlclient.RawPatch(types.MergePatchType, []byte(`{"spec":{"replicas":0}}`)))
+	}); err != nil {
+		return err
+	}
+
+	timeout := s.c.entryTimeout(obj, defaultScaleDownTimeout)
+	scaledDown, err := waitForScaleDown(ctx, s.c.opts.Clock, client, u, timeout)
+	if err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if !scaledDown {
+		s.c.log.Info("WARNING: replicas remain after scaling to zero, deleting regardless", "name", r.Name, "namespace", r.Namespace,
+			"gvr", obj.GroupVersionResource.String(), "timeout", timeout.String())
+	}
+	return s.directStrategy.Delete(ctx, obj, r)
+}
+
+// waitForScaleDown polls a scaled down resource until its status.replicas is zero, returning
+// false if the timeout elapses first
+func waitForScaleDown(ctx context.Context, clk clock.WithDelayedExecution, client ctrlclient.Client, u *unstructured.Unstructured,
+	timeout time.Duration) (bool, error) {
+	ctx, cancel := withTimeout(ctx, clk, timeout)
+	defer cancel()
+	for {
+		err := client.Get(ctx, ctrlclient.ObjectKeyFromObject(u), u)
+		if err != nil && ctx.Err() == nil {
+			return false, err
+		}
+		if replicas, _, _ := unstructured.NestedInt64(u.Object, "status", "replicas"); err == nil && replicas == 0 {
+			return true, nil
+		}
+		select {
+		case <-ctx.Done():
+			return false, nil
+		case <-clk.After(deletionPollInterval):
+		}
+	}
+}
+
+// finalizerStripStrategy deletes each resource and then strips its finalizers
+type finalizerStripStrategy struct {
+	directStrategy
+}
+
+// Delete deletes a resource and strips the entry's finalizers from it or, if the entry has none,
+// every finalizer. The patch is conditional on the resourceVersion, so that a resource recreated
+// in the meantime is never modified.
+func (s finalizerStripStrategy) Delete(ctx context.Context, obj DeleteObj, r metav1.PartialObjectMetadata) error {
+	if err := s.directStrategy.Delete(ctx, obj, r); err != nil {
+		return err
+	}
+	return s.stripFinalizers(ctx, obj, r, obj.Finalizers)
+}
+
+// stripFinalizers strips finalizers from a deleted resource or, if none are given, every finalizer.
+// The patch is conditional on the resourceVersion.
+func (s directStrategy) stripFinalizers(ctx context.Context, obj DeleteObj, r metav1.PartialObjectMetadata, remove []string) error {
+	ri := s.c.opts.MetadataClient.Resource(obj.GroupVersionResource).Namespace(r.Namespace)
+	latest, err := ri.Get(ctx, r.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if r.UID != "" && latest.UID != r.UID {
+		return nil
+	}
+	finalizers := []string{}
+	if len(remove) > 0 {
+		finalizers, _ = withoutFinalizers(latest.Finalizers, remove)
+	}
+	if len(finalizers) == len(latest.Finalizers) {
+		return nil
+	}
+	s.c.log.Info("Stripping finalizers", "finalizers", latest.Finalizers, "name", r.Name, "namespace", r.Namespace, "gvr", obj.GroupVersionResource.String())
+	patch, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"finalizers": finalizers, "resourceVersion": latest.ResourceVersion},
+	})
+	err = s.c.retry(ctx, func(ctx context.Context) error {
+		_, err := ri.Patch(ctx, r.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		return err
+	})
+	if apierrors.IsConflict(err) {
+		return fmt.Errorf("resource changed while stripping finalizers: %w", err)
+	}
+	return err
+}
+
+// foregroundWithTimeoutStrategy deletes each resource with foreground propagation, falling back to
+// background propagation and stripping finalizers if the resource isn't gone by the entry's timeout
+type foregroundWithTimeoutStrategy struct {
+	directStrategy
+}
+
+// Delete deletes a resource in the foreground and waits for it to be gone, i.e., for its dependents
+// to be deleted and the foregroundDeletion finalizer to clear. If the entry's timeout elapses first,
+// the resource is deleted again in the background, and the foregroundDeletion finalizer and the
+// entry's Finalizers are stripped, so that the wait is bounded.
+func (s foregroundWithTimeoutStrategy) Delete(ctx context.Context, obj DeleteObj, r metav1.PartialObjectMetadata) error {
+	if err := s.deleteWith(ctx, obj, r, metav1.DeletePropagationForeground); err != nil {
+		return err
+	}
+	timeout := s.c.entryTimeout(obj, defaultForegroundTimeout)
+	waiter := &deletionWaiter{metadataClient: s.c.opts.MetadataClient, log: s.c.log, clock: s.c.opts.Clock, timeout: timeout}
+	named := DeleteObj{GroupVersionResource: obj.GroupVersionResource, Namespace: r.Namespace, Name: r.Name}
+	err := waiter.waitForDeletion(ctx, named, []metav1.PartialObjectMetadata{r})
+	if !errors.Is(err, ErrDeletionTimeout) || ctx.Err() != nil {
+		return err
+	}
+
+	s.c.log.Info("WARNING: foreground deletion didn't complete in time, falling back to background deletion", "name", r.Name,
+		"namespace", r.Namespace, "gvr", obj.GroupVersionResource.String(), "timeout", timeout.String())
+	if err := s.deleteWith(ctx, obj, r, metav1.DeletePropagationBackground); err != nil {
+		return err
+	}
+	err = s.stripFinalizers(ctx, obj, r, append([]string{metav1.FinalizerDeleteDependents}, obj.Finalizers...))
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,258 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	clienttesting "k8s.io/client-go/testing"
+	testingclock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner/cleanertest"
+)
+
+// customStrategy records the resources it is asked to delete without deleting them
+type customStrategy struct {
+	directStrategy
+	deleted []string
+}
+
+func (s *customStrategy) Delete(_ context.Context, _ DeleteObj, r metav1.PartialObjectMetadata) error {
+	s.deleted = append(s.deleted, r.Name)
+	return nil
+}
+
+func TestStrategy(t *testing.T) {
+	custom := &customStrategy{}
+	c := New(Options{Strategies: map[string]DeletionStrategy{"custom": custom}})
+
+	tests := []struct {
+		name          string
+		strategy      string
+		expected      DeletionStrategy
+		expectedError error
+	}{
+		{
+			name:     "default",
+			expected: directStrategy{c: c},
+		},
+		{
+			name:     "built-in",
+			strategy: StrategyFinalizerStrip,
+			expected: finalizerStripStrategy{directStrategy: directStrategy{c: c}},
+		},
+		{
+			name:     "custom",
+			strategy: "custom",
+			expected: custom,
+		},
+		{
+			name:          "unknown",
+			strategy:      "scale-then-delete",
+			expectedError: ErrConfigInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy, err := c.strategy(DeleteObj{Strategy: tt.strategy})
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, got %v", tt.expectedError, err)
+			}
+			if strategy != tt.expected {
+				t.Errorf("expected strategy %#v, got %#v", tt.expected, strategy)
+			}
+		})
+	}
+}
+
+func TestCustomStrategy(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"},
+	})
+	custom := &customStrategy{}
+	c := New(Options{MetadataClient: client, Strategies: map[string]DeletionStrategy{"custom": custom}})
+	custom.c = c
+
+	entries := []DeleteObj{{GroupVersionResource: gvr, Namespace: "default", Strategy: "custom", MustDelete: true}}
+	if _, err := c.CleanupResources(context.Background(), entries); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if expected := []string{"a"}; !reflect.DeepEqual(custom.deleted, expected) {
+		t.Errorf("expected %v deleted by the custom strategy, got %v", expected, custom.deleted)
+	}
+	if _, err := client.Resource(gvr).Namespace("default").Get(context.Background(), "a", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected a to be retained, got %v", err)
+	}
+}
+
+func TestFinalizerStripStrategy(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	tests := []struct {
+		name               string
+		finalizers         []string
+		recreated          bool
+		expectedFinalizers []string
+	}{
+		{
+			name:               "all finalizers",
+			expectedFinalizers: nil,
+		},
+		{
+			name:               "entry finalizers",
+			finalizers:         []string{"example.com/cleanup"},
+			expectedFinalizers: []string{"kubernetes"},
+		},
+		{
+			name:               "recreated",
+			recreated:          true,
+			expectedFinalizers: []string{"kubernetes", "example.com/cleanup"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configMap := func(uid types.UID) *metav1.PartialObjectMetadata {
+				return &metav1.PartialObjectMetadata{
+					TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+					ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default", UID: uid,
+						Finalizers: []string{"kubernetes", "example.com/cleanup"}},
+				}
+			}
+			client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), configMap("deleted"))
+			// resources with finalizers are only marked as deleting, and a named resource recreated in
+			// the meantime, i.e., with another UID than the one planned, must not be stripped
+			client.PrependReactor("delete", "configmaps", func(clienttesting.Action) (bool, runtime.Object, error) {
+				if tt.recreated {
+					return true, nil, client.Tracker().Update(gvr, configMap("recreated"), "default")
+				}
+				return true, nil, nil
+			})
+
+			entries := []DeleteObj{{GroupVersionResource: gvr, Name: "a", Namespace: "default", Strategy: StrategyFinalizerStrip, Finalizers: tt.finalizers}}
+			if _, err := New(Options{MetadataClient: client}).CleanupResources(context.Background(), entries); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			m, err := client.Resource(gvr).Namespace("default").Get(context.Background(), "a", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(m.Finalizers) != len(tt.expectedFinalizers) || (len(m.Finalizers) > 0 && !reflect.DeepEqual(m.Finalizers, tt.expectedFinalizers)) {
+				t.Errorf("expected finalizers %v, got %v", tt.expectedFinalizers, m.Finalizers)
+			}
+		})
+	}
+}
+
+func TestForegroundWithTimeoutStrategy(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	tests := []struct {
+		name               string
+		stuck              bool
+		expectedDeletes    int
+		expectedFinalizers []string
+	}{
+		{
+			name:            "foreground deletion completes",
+			expectedDeletes: 1,
+		},
+		{
+			name:               "foreground deletion times out",
+			stuck:              true,
+			expectedDeletes:    2,
+			expectedFinalizers: []string{"kubernetes"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), &metav1.PartialObjectMetadata{
+				TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+				ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default",
+					Finalizers: []string{metav1.FinalizerDeleteDependents, "example.com/cleanup", "kubernetes"}},
+			})
+			// a stuck resource is only marked as deleting
+			client.PrependReactor("delete", "configmaps", func(clienttesting.Action) (bool, runtime.Object, error) {
+				return tt.stuck, nil, nil
+			})
+			fakeClock := testingclock.NewFakeClock(time.Now())
+			if tt.stuck {
+				go cleanertest.StepWhenWaiting(fakeClock, defaultForegroundTimeout)
+			}
+
+			entries := []DeleteObj{{GroupVersionResource: gvr, Name: "a", Namespace: "default", Strategy: StrategyForegroundWithTimeout,
+				Finalizers: []string{"example.com/cleanup"}, MustDelete: true}}
+			if _, err := New(Options{MetadataClient: client, Clock: fakeClock}).CleanupResources(context.Background(), entries); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			// the fallback deletes the resource again, in the background
+			if deletes := countVerb(client.Actions(), "delete"); deletes != tt.expectedDeletes {
+				t.Errorf("expected %d deletes, got %d", tt.expectedDeletes, deletes)
+			}
+			m, err := client.Resource(gvr).Namespace("default").Get(context.Background(), "a", metav1.GetOptions{})
+			if !tt.stuck {
+				if !apierrors.IsNotFound(err) {
+					t.Errorf("expected a to be deleted, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(m.Finalizers, tt.expectedFinalizers) {
+				t.Errorf("expected finalizers %v, got %v", tt.expectedFinalizers, m.Finalizers)
+			}
+		})
+	}
+}
+
+func TestScaleThenDeleteStrategy(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	gvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{gvk.GroupVersion()})
+	mapper.Add(gvk, meta.RESTScopeNamespace)
+	replicas := int32(3)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "operator", Namespace: "default"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+	}
+	client := fake.NewClientBuilder().WithRESTMapper(mapper).WithObjects(deployment).Build()
+
+	scheme := runtime.NewScheme()
+	scheme.AddKnownTypeWithName(gvk, &metav1.PartialObjectMetadata{})
+	scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind("DeploymentList"), &metav1.PartialObjectMetadataList{})
+	metadataClient := metadatafake.NewSimpleMetadataClient(scheme, &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "operator", Namespace: "default"},
+	})
+
+	entries := []DeleteObj{{GroupVersionResource: gvr, Name: "operator", Namespace: "default", Strategy: StrategyScaleThenDelete}}
+	if _, err := New(Options{Client: client, MetadataClient: metadataClient}).CleanupResources(context.Background(), entries); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	scaled := &appsv1.Deployment{}
+	if err := client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "operator"}, scaled); err != nil {
+		t.Fatal(err)
+	}
+	if scaled.Spec.Replicas == nil || *scaled.Spec.Replicas != 0 {
+		t.Errorf("expected 0 replicas, got %v", scaled.Spec.Replicas)
+	}
+	if _, err := metadataClient.Resource(gvr).Namespace("default").Get(context.Background(), "operator", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected operator to be deleted, got %v", err)
+	}
+}
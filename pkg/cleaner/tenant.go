@@ -0,0 +1,50 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// tenantSelector returns an entry's label selector restricted to the TenantLabels option, if set, so
+// that the API server only lists resources within the tenant
+func (c *Cleaner) tenantSelector(selector string) string {
+	if c.tenant == nil {
+		return selector
+	}
+	if selector == "" {
+		return c.tenant.String()
+	}
+	return selector + "," + c.tenant.String()
+}
+
+// withinTenant reports whether a resource carries the TenantLabels option, if set
+func (c *Cleaner) withinTenant(r metav1.PartialObjectMetadata) bool {
+	return c.tenant == nil || c.tenant.Matches(labels.Set(r.Labels))
+}
+
+// recheckTenant re-reads a resource immediately before its deletion, reporting whether it still
+// carries the TenantLabels option, so that neither a named entry nor a resource relabeled since it
+// was listed crosses the tenant boundary
+func (c *Cleaner) recheckTenant(ctx context.Context, obj DeleteObj, r metav1.PartialObjectMetadata) (bool, error) {
+	latest, err := c.opts.MetadataClient.Resource(obj.GroupVersionResource).Namespace(r.Namespace).Get(ctx, r.Name, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	return c.withinTenant(*latest), nil
+}
@@ -0,0 +1,69 @@
+package cleaner
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	metadatafake "k8s.io/client-go/metadata/fake"
+)
+
+func TestTenantLabels(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	tests := []struct {
+		name            string
+		entry           DeleteObj
+		expectedDeleted []types.NamespacedName
+		expectedSkipped []types.NamespacedName
+	}{
+		{
+			name:            "delete-all lists within the tenant",
+			entry:           DeleteObj{GroupVersionResource: gvr, Namespace: "default"},
+			expectedDeleted: []types.NamespacedName{{Namespace: "default", Name: "a"}, {Namespace: "default", Name: "c"}},
+		},
+		{
+			name:            "label selector is restricted to the tenant",
+			entry:           DeleteObj{GroupVersionResource: gvr, Namespace: "default", LabelSelector: "app=c"},
+			expectedDeleted: []types.NamespacedName{{Namespace: "default", Name: "c"}},
+		},
+		{
+			name:            "named resource within the tenant",
+			entry:           DeleteObj{GroupVersionResource: gvr, Namespace: "default", Name: "a"},
+			expectedDeleted: []types.NamespacedName{{Namespace: "default", Name: "a"}},
+		},
+		{
+			name:            "named resource outside the tenant",
+			entry:           DeleteObj{GroupVersionResource: gvr, Namespace: "default", Name: "b"},
+			expectedSkipped: []types.NamespacedName{{Namespace: "default", Name: "b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var objs []runtime.Object
+			for name, tenant := range map[string]string{"a": "blue", "b": "green", "c": "blue"} {
+				objs = append(objs, &metav1.PartialObjectMetadata{
+					TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"tenant": tenant, "app": name}},
+				})
+			}
+			client := metadatafake.NewSimpleMetadataClient(newMetadataScheme(), objs...)
+
+			c := New(Options{MetadataClient: client, TenantLabels: map[string]string{"tenant": "blue"}})
+			result, err := c.CleanupResources(context.Background(), []DeleteObj{tt.entry})
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if er := result.Entries[0]; !reflect.DeepEqual(er.Deleted, tt.expectedDeleted) || !reflect.DeepEqual(er.Skipped, tt.expectedSkipped) {
+				t.Errorf("expected deleted %v and skipped %v, got %v and %v", tt.expectedDeleted, tt.expectedSkipped, er.Deleted, er.Skipped)
+			}
+			if _, err := client.Resource(gvr).Namespace("default").Get(context.Background(), "b", metav1.GetOptions{}); err != nil {
+				t.Errorf("expected b to be retained, got %v", err)
+			}
+		})
+	}
+}
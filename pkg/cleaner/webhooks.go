@@ -0,0 +1,101 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// webhookCallFailure matches the error returned by the API server when it fails to call an admission webhook
+var webhookCallFailure = regexp.MustCompile(`failed calling webhook "([^"]+)"`)
+
+// failingWebhook returns the name of the admission webhook that rejected a request because it could
+// not be called, e.g., because its backend was uninstalled while its failurePolicy is Fail
+func failingWebhook(err error) (string, bool) {
+	if !apierrors.IsInternalError(err) {
+		return "", false
+	}
+	m := webhookCallFailure.FindStringSubmatch(err.Error())
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// deleteBypassingWebhooks performs a deletion. If an admission webhook that can't be called rejects it,
+// and bypassing webhooks is enabled, the webhook's configuration is deleted, and the deletion retried.
+// Such webhooks deadlock uninstalls that delete their backends first.
+func (c *Cleaner) deleteBypassingWebhooks(ctx context.Context, fn func(ctx context.Context) error) error {
+	err := c.retry(ctx, fn)
+	webhook, ok := failingWebhook(err)
+	if !ok {
+		return err
+	}
+	if !c.opts.BypassWebhooks || c.opts.Client == nil {
+		c.log.Info("WARNING: deletion blocked by an admission webhook that can't be called, set Options.BypassWebhooks to delete its configuration", "webhook", webhook)
+		return err
+	}
+	if bypassErr := c.deleteWebhookConfiguration(ctx, webhook); bypassErr != nil {
+		return errors.Join(err, bypassErr)
+	}
+	return c.retry(ctx, fn)
+}
+
+// deleteWebhookConfiguration deletes the Validating/MutatingWebhookConfigurations of the named webhook
+func (c *Cleaner) deleteWebhookConfiguration(ctx context.Context, webhook string) error {
+	var configs []ctrlclient.Object
+	validating := &admissionregistrationv1.ValidatingWebhookConfigurationList{}
+	if err := c.opts.Client.List(ctx, validating); err != nil {
+		return err
+	}
+	for i := range validating.Items {
+		for _, w := range validating.Items[i].Webhooks {
+			if w.Name == webhook {
+				configs = append(configs, &validating.Items[i])
+				break
+			}
+		}
+	}
+	mutating := &admissionregistrationv1.MutatingWebhookConfigurationList{}
+	if err := c.opts.Client.List(ctx, mutating); err != nil {
+		return err
+	}
+	for i := range mutating.Items {
+		for _, w := range mutating.Items[i].Webhooks {
+			if w.Name == webhook {
+				configs = append(configs, &mutating.Items[i])
+				break
+			}
+		}
+	}
+	if len(configs) == 0 {
+		return fmt.Errorf("no webhook configuration found for webhook %q", webhook)
+	}
+
+	for _, cfg := range configs {
+		c.log.Info("Deleting webhook configuration blocking deletion", "webhook", webhook, "webhookConfiguration", cfg.GetName())
+		if err := c.retry(ctx, func(ctx context.Context) error { return c.opts.Client.Delete(ctx, cfg) }); ctrlclient.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDeleteBypassingWebhooks(t *testing.T) {
+	webhookErr := apierrors.NewInternalError(errors.New(`failed calling webhook "validate.example.com": failed to call webhook: ` +
+		`Post "https://example-webhook.system.svc:443/validate?timeout=10s": no endpoints available for service "example-webhook"`))
+	cfg := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "example"},
+		Webhooks:   []admissionregistrationv1.ValidatingWebhook{{Name: "validate.example.com"}},
+	}
+
+	tests := []struct {
+		name             string
+		bypass           bool
+		errs             []error
+		expectedError    bool
+		expectedAttempts int
+	}{
+		{
+			name:             "not blocked",
+			bypass:           true,
+			errs:             []error{nil},
+			expectedAttempts: 1,
+		},
+		{
+			name:             "blocked and bypassed",
+			bypass:           true,
+			errs:             []error{webhookErr, nil},
+			expectedAttempts: 2,
+		},
+		{
+			name:             "blocked without bypass",
+			errs:             []error{webhookErr},
+			expectedError:    true,
+			expectedAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewClientBuilder().WithObjects(cfg.DeepCopy()).Build()
+			c := New(Options{Client: client, BypassWebhooks: tt.bypass, Backoff: wait.Backoff{Steps: 1}})
+
+			attempts := 0
+			err := c.deleteBypassingWebhooks(context.Background(), func(context.Context) error {
+				err := tt.errs[attempts]
+				attempts++
+				return err
+			})
+			if err != nil && !tt.expectedError {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if err == nil && tt.expectedError {
+				t.Fatalf("expected error, got nil")
+			}
+			if attempts != tt.expectedAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.expectedAttempts, attempts)
+			}
+
+			getErr := client.Get(context.Background(), types.NamespacedName{Name: "example"}, &admissionregistrationv1.ValidatingWebhookConfiguration{})
+			deleted := apierrors.IsNotFound(getErr)
+			if expected := tt.expectedAttempts > 1; deleted != expected {
+				t.Errorf("expected webhook configuration deleted %v, got %v", expected, deleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/internal/command"
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+const (
+	PluginsToRun = "pluginsToRun"
+
+	// pluginPrefix prefixes the name of each plugin executable, e.g., spectro-cleanup-aws-enis
+	pluginPrefix = "spectro-cleanup-"
+)
+
+// ErrPluginFailed is returned when a plugin entry with mustSucceed fails
+var ErrPluginFailed = errors.New("cleanup plugin failed")
+
+// PluginEntry is a custom cleanup step implemented by an external executable, e.g., to call a cloud
+// provider's API or clean up a database. The executable is discovered in the plugin directory.
+type PluginEntry struct {
+	// Plugin is the name of the plugin. Its executable is spectro-cleanup-<plugin>.
+	Plugin string `json:"plugin"`
+
+	// Config is passed to the plugin as is
+	Config json.RawMessage `json:"config,omitempty"`
+
+	// TimeoutSeconds overrides CLEANUP_COMMAND_TIMEOUT_SECONDS for the plugin
+	TimeoutSeconds int64 `json:"timeoutSeconds,omitempty"`
+
+	// MustSucceed aborts the cleanup with an error if the plugin fails, or is not found
+	MustSucceed bool `json:"mustSucceed,omitempty"`
+
+	// Network declares that the plugin calls services other than the API server, e.g., a cloud
+	// provider's API. Such plugins are rejected in offline mode.
+	Network bool `json:"network,omitempty"`
+}
+
+// pluginStep is the JSON description of a step written to a plugin's standard input
+type pluginStep struct {
+	Plugin string          `json:"plugin"`
+	Config json.RawMessage `json:"config,omitempty"`
+}
+
+// readPluginConfig loads the plugin entries specified in the plugin config file
+func readPluginConfig() []PluginEntry {
+	plugins := []PluginEntry{}
+	bytes := readConfig(pluginConfigPath, PluginsToRun)
+	if bytes == nil {
+		return plugins
+	}
+	if err := json.Unmarshal(bytes, &plugins); err != nil {
+		panic(fmt.Errorf("%w: %w", cleaner.ErrConfigInvalid, err))
+	}
+	for _, p := range plugins {
+		if p.Plugin == "" || p.Plugin != filepath.Base(p.Plugin) || p.Plugin == ".." {
+			panic(fmt.Errorf("%w: invalid plugin name %q", cleaner.ErrConfigInvalid, p.Plugin))
+		}
+	}
+	return plugins
+}
+
+// pluginPath returns the path of a plugin's executable in the plugin directory
+func pluginPath(name string) (string, error) {
+	path := filepath.Join(pluginDir, pluginPrefix+name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+		return "", fmt.Errorf("plugin %s is not an executable file", path)
+	}
+	return path, nil
+}
+
+// runPlugins runs each plugin entry in order, writing its step description to its standard input.
+// Failures are logged, and those of mustSucceed entries returned, after which no further plugins are run.
+func runPlugins(ctx context.Context) error {
+	for _, p := range readPluginConfig() {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err := runPlugin(ctx, p); err != nil {
+			log.Error(err, "cleanup plugin failed", "plugin", p.Plugin)
+			if p.MustSucceed {
+				return fmt.Errorf("%w: %s: %w", ErrPluginFailed, p.Plugin, err)
+			}
+		}
+	}
+	return nil
+}
+
+// runPlugin runs a single plugin entry
+func runPlugin(ctx context.Context, p PluginEntry) error {
+	path, err := pluginPath(p.Plugin)
+	if err != nil {
+		return err
+	}
+	input, err := json.Marshal(pluginStep{Plugin: p.Plugin, Config: p.Config})
+	if err != nil {
+		return err
+	}
+	timeout := cmdTimeout
+	if p.TimeoutSeconds > 0 {
+		timeout = time.Duration(p.TimeoutSeconds) * time.Second
+	}
+	log.Info("Running cleanup plugin", "plugin", p.Plugin, "path", path)
+	return command.RunInput(ctx, timeout, []string{path}, input)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunPlugins(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        string
+		expectedInput string
+		expectedError bool
+	}{
+		{
+			name:          "plugin receives step on stdin",
+			config:        `[{"plugin": "record", "config": {"region": "us-east-1"}}]`,
+			expectedInput: `{"plugin":"record","config":{"region":"us-east-1"}}`,
+		},
+		{
+			name:   "missing plugin",
+			config: `[{"plugin": "missing"}]`,
+		},
+		{
+			name:          "missing required plugin",
+			config:        `[{"plugin": "missing", "mustSucceed": true}, {"plugin": "record"}]`,
+			expectedError: true,
+		},
+		{
+			name:          "required plugin fails",
+			config:        `[{"plugin": "fail", "mustSucceed": true}]`,
+			expectedError: true,
+		},
+		{
+			name:   "non-executable plugin",
+			config: `[{"plugin": "noexec"}]`,
+		},
+	}
+
+	defaultDir, defaultPath := pluginDir, pluginConfigPath
+	defer func() { pluginDir, pluginConfigPath = defaultDir, defaultPath }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pluginDir = t.TempDir()
+			output := filepath.Join(t.TempDir(), "input.json")
+			for name, script := range map[string]string{
+				"record": "#!/bin/sh\ncat > " + output + "\n",
+				"fail":   "#!/bin/sh\nexit 1\n",
+				"noexec": "#!/bin/sh\ntrue\n",
+			} {
+				mode := os.FileMode(0o700)
+				if name == "noexec" {
+					mode = 0o600
+				}
+				if err := os.WriteFile(filepath.Join(pluginDir, pluginPrefix+name), []byte(script), mode); err != nil {
+					t.Fatal(err)
+				}
+			}
+			pluginConfigPath = filepath.Join(t.TempDir(), "plugin-config.json")
+			if err := os.WriteFile(pluginConfigPath, []byte(tt.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			err := runPlugins(context.Background())
+			if errors.Is(err, ErrPluginFailed) != tt.expectedError {
+				t.Fatalf("expected error %v, got %v", tt.expectedError, err)
+			}
+
+			input, err := os.ReadFile(output)
+			if tt.expectedInput == "" {
+				if err == nil {
+					t.Errorf("expected plugin not to run, got input %s", input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(input) != tt.expectedInput {
+				t.Errorf("expected input %s, got %s", tt.expectedInput, input)
+			}
+		})
+	}
+}
+
+func TestReadPluginConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        string
+		expectedPanic bool
+	}{
+		{
+			name:   "valid plugin",
+			config: `[{"plugin": "aws-enis"}]`,
+		},
+		{
+			name:          "missing plugin name",
+			config:        `[{"config": {}}]`,
+			expectedPanic: true,
+		},
+		{
+			name:          "plugin path",
+			config:        `[{"plugin": "../../bin/sh"}]`,
+			expectedPanic: true,
+		},
+	}
+
+	defaultPath := pluginConfigPath
+	defer func() { pluginConfigPath = defaultPath }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pluginConfigPath = filepath.Join(t.TempDir(), "plugin-config.json")
+			if err := os.WriteFile(pluginConfigPath, []byte(tt.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				if r := recover(); (r != nil) != tt.expectedPanic {
+					t.Errorf("expected panic %v, got %v", tt.expectedPanic, r)
+				}
+			}()
+			readPluginConfig()
+		})
+	}
+}
@@ -0,0 +1,60 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// policyAllows evaluates the configured deletion policy, if any, before a deletion outside the resource
+// config, e.g., by a rule or a builtin sweep. Deletions are skipped if they are denied, or if the policy
+// cannot be evaluated.
+func policyAllows(ctx context.Context, gvr schema.GroupVersionResource, obj metav1.Object) bool {
+	policy := deletionPolicy()
+	if policy == nil {
+		return true
+	}
+	decision, err := policy.Evaluate(ctx, cleaner.NewPolicyInput(cleaner.ActionDelete, gvr, obj))
+	if err != nil {
+		log.Error(err, "failed to evaluate policy, skipping deletion", "name", obj.GetName(), "namespace", obj.GetNamespace(), "gvr", gvr.String())
+		return false
+	}
+	if !decision.Allowed {
+		log.Info("WARNING: resource deletion denied by policy, skipping", "name", obj.GetName(), "namespace", obj.GetNamespace(),
+			"gvr", gvr.String(), "reason", decision.Reason)
+		return false
+	}
+	return true
+}
+
+// policyAllowsObject is policyAllows for a typed object, whose resource is derived from the client's scheme
+func policyAllowsObject(ctx context.Context, client ctrlclient.Client, obj ctrlclient.Object) bool {
+	if policyOPAURL == "" {
+		return true
+	}
+	gvk, err := client.GroupVersionKindFor(obj)
+	if err != nil {
+		log.Error(err, "failed to get object kind, skipping deletion", "name", obj.GetName(), "namespace", obj.GetNamespace())
+		return false
+	}
+	gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+	return policyAllows(ctx, gvr, obj)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPolicyAllowsOrphanDeletion(t *testing.T) {
+	defaultPolicyOPAURL, defaultDeleteOrphans := policyOPAURL, deleteOrphans
+	defer func() { policyOPAURL, deleteOrphans = defaultPolicyOPAURL, defaultDeleteOrphans }()
+	deleteOrphans = true
+
+	tests := []struct {
+		name             string
+		status           int
+		response         string
+		disabled         bool
+		expectedRetained bool
+	}{
+		{
+			name:     "no policy",
+			disabled: true,
+		},
+		{
+			name:     "allowed",
+			status:   http.StatusOK,
+			response: `{"result": true}`,
+		},
+		{
+			name:             "denied",
+			status:           http.StatusOK,
+			response:         `{"result": {"allow": false, "reason": "namespace is protected"}}`,
+			expectedRetained: true,
+		},
+		{
+			name:             "evaluation failure",
+			status:           http.StatusInternalServerError,
+			response:         `{"code": "internal_error"}`,
+			expectedRetained: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+			policyOPAURL = server.URL
+			if tt.disabled {
+				policyOPAURL = ""
+			}
+
+			cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "orphan", Namespace: "default"}}
+			client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cm).Build()
+			handleOrphan(context.Background(), client, cm, "configMap")
+
+			err := client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "orphan"}, &corev1.ConfigMap{})
+			if retained := err == nil; retained != tt.expectedRetained {
+				t.Errorf("expected retained %v, got %v (%v)", tt.expectedRetained, retained, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+// preflight verifies, before anything is deleted, that the API server is reachable and serves the
+// resources of every resource config entry, and that no admission webhook, policy or missing RBAC
+// permission would block the deletions of mustDelete entries, so that a misconfigured entry fails the
+// cleanup up front rather than midway through
+func preflight(ctx context.Context, dc discovery.DiscoveryInterface, c *cleaner.Cleaner, objs []cleaner.DeleteObj) error {
+	version, err := dc.ServerVersion()
+	if err != nil {
+		return fmt.Errorf("preflight: API server unreachable: %w", err)
+	}
+	log.Info("Preflight: API server reachable", "version", version.GitVersion)
+
+	scopes := discoverScopes(dc)
+	if scopes == nil {
+		return errors.New("preflight: resource discovery failed")
+	}
+	objs = scopes.resolve(objs)
+	if err := scopes.verify(objs); err != nil {
+		return err
+	}
+	if err := c.DryRun(ctx, objs); err != nil {
+		return fmt.Errorf("preflight: mustDelete deletions would be blocked: %w", err)
+	}
+	log.Info("Preflight: dry-run deletions of mustDelete entries succeeded")
+	return nil
+}
+
+// verify returns an error listing every resolved resource config entry whose resource isn't served
+func (s *resourceScopes) verify(objs []cleaner.DeleteObj) error {
+	var missing []schema.GroupVersionResource
+	for _, obj := range objs {
+		if _, ok := s.namespaced[obj.GroupVersionResource]; !ok {
+			missing = append(missing, obj.GroupVersionResource)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("preflight: resources not served by the cluster, or whose API group failed discovery: %v", missing)
+	}
+	log.Info("Preflight: all resource config entries are served", "entries", len(objs))
+	return nil
+}
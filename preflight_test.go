@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+func TestResourceScopesVerify(t *testing.T) {
+	configMaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	widgets := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	scopes := &resourceScopes{namespaced: map[schema.GroupVersionResource]bool{configMaps: true}}
+
+	tests := []struct {
+		name          string
+		objs          []cleaner.DeleteObj
+		expectedError bool
+	}{
+		{name: "no entries"},
+		{name: "served", objs: []cleaner.DeleteObj{{GroupVersionResource: configMaps, Name: "a"}}},
+		{
+			name:          "not served",
+			objs:          []cleaner.DeleteObj{{GroupVersionResource: configMaps, Name: "a"}, {GroupVersionResource: widgets, Name: "b"}},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := scopes.verify(tt.objs)
+			if err != nil && !tt.expectedError {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if err == nil && tt.expectedError {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
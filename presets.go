@@ -0,0 +1,102 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Built-in rule presets, enabled via CLEANUP_PRESETS
+const (
+	// PresetCompletedWorkloads deletes Succeeded/Failed pods and completed Jobs
+	PresetCompletedWorkloads = "completed-workloads"
+	// PresetEvictedPods deletes pods left behind by evictions and graceful node shutdowns
+	PresetEvictedPods = "evicted-pods"
+	// PresetStaleLeases deletes Leases left behind by uninstalled controllers, i.e., that haven't been
+	// renewed for a while and whose holder is gone
+	PresetStaleLeases = "stale-leases"
+)
+
+// knownPresets lists every supported preset
+var knownPresets = []string{PresetCompletedWorkloads, PresetEvictedPods, PresetStaleLeases}
+
+var (
+	podsGVR   = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	jobsGVR   = schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"}
+	leasesGVR = schema.GroupVersionResource{Group: "coordination.k8s.io", Version: "v1", Resource: "leases"}
+)
+
+// presetRules returns the rules for each enabled preset. Every preset rule only matches objects
+// older than presetMinAgeSeconds, or, for Jobs, that finished at least presetMinAgeSeconds ago.
+func presetRules() []Rule {
+	rules := []Rule{}
+	for _, preset := range presets {
+		switch preset {
+		case PresetCompletedWorkloads:
+			rules = append(rules,
+				Rule{GroupVersionResource: podsGVR, FieldSelector: "status.phase=Succeeded", MinAgeSeconds: presetMinAgeSeconds},
+				Rule{GroupVersionResource: podsGVR, FieldSelector: "status.phase=Failed", MinAgeSeconds: presetMinAgeSeconds},
+				// Jobs are aged from when they finished rather than from their creation, so that
+				// long-running Jobs are kept for as long as short ones once they're done
+				Rule{
+					GroupVersionResource: jobsGVR,
+					Conditions:           []RuleCondition{{Path: "status.completionTime", OlderThanSeconds: presetMinAgeSeconds}},
+				},
+				// failed Jobs have no completionTime
+				Rule{
+					GroupVersionResource: jobsGVR,
+					Conditions: []RuleCondition{
+						{Path: "status.conditions[type=Failed].status", Values: []string{"True"}},
+						{Path: "status.conditions[type=Failed].lastTransitionTime", OlderThanSeconds: presetMinAgeSeconds},
+					},
+				},
+			)
+		case PresetEvictedPods:
+			// graceful node shutdown sets the reason to Shutdown, or Terminated as of K8s v1.22
+			rules = append(rules, Rule{
+				GroupVersionResource: podsGVR,
+				FieldSelector:        "status.phase=Failed",
+				Conditions:           []RuleCondition{{Path: "status.reason", Values: []string{"Evicted", "Shutdown", "Terminated"}}},
+				MinAgeSeconds:        presetMinAgeSeconds,
+			})
+		case PresetStaleLeases:
+			// an active holder renews its Lease every few seconds, so one that has not been renewed for
+			// staleLeaseSeconds, or never was, is stale. A stale Lease may still belong to a paused holder,
+			// or one scaled to zero, so it is only deleted once its holder is gone too. Node heartbeat
+			// Leases are garbage collected with their Node.
+			rules = append(rules,
+				Rule{
+					GroupVersionResource: leasesGVR,
+					FieldSelector:        "metadata.namespace!=kube-node-lease",
+					Conditions:           []RuleCondition{{Path: "spec.renewTime", OlderThanSeconds: staleLeaseSeconds}},
+					MinAgeSeconds:        presetMinAgeSeconds,
+					OwnerGone:            true,
+				},
+				Rule{
+					GroupVersionResource: leasesGVR,
+					FieldSelector:        "metadata.namespace!=kube-node-lease",
+					Conditions:           []RuleCondition{{Path: "spec.renewTime", Absent: true}},
+					MinAgeSeconds:        max(presetMinAgeSeconds, staleLeaseSeconds),
+					OwnerGone:            true,
+				},
+			)
+		default:
+			panic(fmt.Errorf("unknown preset %q", preset))
+		}
+	}
+	return rules
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestPresetCompletedJobs(t *testing.T) {
+	now := time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)
+
+	defer func(p []string, s int64) { presets, presetMinAgeSeconds = p, s }(presets, presetMinAgeSeconds)
+	presets, presetMinAgeSeconds = []string{PresetCompletedWorkloads}, 3600
+
+	tests := []struct {
+		name     string
+		status   map[string]interface{}
+		expected bool
+	}{
+		{
+			name:     "running",
+			status:   map[string]interface{}{"active": int64(1)},
+			expected: false,
+		},
+		{
+			name:     "completed long ago",
+			status:   map[string]interface{}{"completionTime": "2024-01-10T10:00:00Z"},
+			expected: true,
+		},
+		{
+			name:     "completed recently",
+			status:   map[string]interface{}{"completionTime": "2024-01-10T11:30:00Z"},
+			expected: false,
+		},
+		{
+			name: "failed long ago",
+			status: map[string]interface{}{"conditions": []interface{}{
+				map[string]interface{}{"type": "Failed", "status": "True", "lastTransitionTime": "2024-01-10T10:00:00Z"},
+			}},
+			expected: true,
+		},
+		{
+			name: "failed recently",
+			status: map[string]interface{}{"conditions": []interface{}{
+				map[string]interface{}{"type": "Failed", "status": "True", "lastTransitionTime": "2024-01-10T11:30:00Z"},
+			}},
+			expected: false,
+		},
+		{
+			name: "failure pending",
+			status: map[string]interface{}{"conditions": []interface{}{
+				map[string]interface{}{"type": "FailureTarget", "status": "True", "lastTransitionTime": "2024-01-10T10:00:00Z"},
+			}},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// a Job created long ago, so that only its status decides
+			job := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "batch/v1",
+				"kind":       "Job",
+				"metadata": map[string]interface{}{
+					"name":              "migrate",
+					"namespace":         "default",
+					"creationTimestamp": "2024-01-01T00:00:00Z",
+				},
+				"status": tt.status,
+			}}
+
+			matches := false
+			for _, rule := range presetRules() {
+				if rule.GroupVersionResource == jobsGVR && rule.matches(job, now) {
+					matches = true
+				}
+			}
+			if matches != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, matches)
+			}
+		})
+	}
+}
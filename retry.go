@@ -0,0 +1,103 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/flowcontrol"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/internal/retry"
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+var (
+	// retryBackoff is the backoff between attempts of an API call failing with a transient error
+	retryBackoff = wait.Backoff{Steps: 5, Duration: 1 * time.Second, Factor: 2.0, Cap: 30 * time.Second}
+
+	// mutationLimiter throttles every destructive API call, if set
+	mutationLimiter flowcontrol.RateLimiter
+
+	// retryRules classify further errors as transient, e.g., those of a proxy in front of the API server
+	retryRules retry.Rules
+)
+
+// retryMutation performs a destructive API call, throttled by the mutation rate limiter if enabled,
+// retrying transient failures with retryBackoff
+func retryMutation(ctx context.Context, fn func(ctx context.Context) error) error {
+	return retry.Mutation(ctx, retry.Policy{Backoff: retryBackoff, Limiter: mutationLimiter, Retryable: retryRules.Retryable}, fn)
+}
+
+// cleanupWithRetries deletes the resources of a one-shot cleanup, retrying up to runRetryAttempts times,
+// runRetryInterval apart, while mustDelete entries fail. The Cleaner's checkpoint records the entries
+// each attempt completed, so that retries only process the entries that failed or weren't reached,
+// rather than restarting from zero as a Job's backoff policy would. The returned result combines
+// the outcomes of every attempt, and mustDelete entries whose last outcome is a failure fail the cleanup,
+// even if the last attempt didn't reach them.
+func cleanupWithRetries(ctx context.Context, c *cleaner.Cleaner, objs []cleaner.DeleteObj) (*cleaner.Result, error) {
+	result, err := c.CleanupResources(ctx, objs)
+	for attempt := 1; err != nil && attempt <= runRetryAttempts && ctx.Err() == nil; attempt++ {
+		logResult("resources", result)
+		log.Error(err, "required resource cleanup failed, retrying the failed entries", "attempt", attempt,
+			"maxAttempts", runRetryAttempts, "delay", runRetryInterval.String())
+		select {
+		case <-ctx.Done():
+			return result, err
+		case <-time.After(runRetryInterval):
+		}
+		var retried *cleaner.Result
+		retried, err = c.CleanupResources(ctx, objs)
+		result = mergeResults(result, retried)
+		if err == nil {
+			err = mustDeleteFailures(result)
+		}
+	}
+	return result, err
+}
+
+// mergeResults returns the result of a retry, in which the entries skipped, e.g., because earlier
+// attempts completed them or the retry stopped before reaching them, keep their earlier outcomes
+func mergeResults(prev, next *cleaner.Result) *cleaner.Result {
+	if next == nil {
+		return prev
+	}
+	if prev == nil || len(prev.Entries) != len(next.Entries) {
+		return next
+	}
+	for i, e := range next.Entries {
+		if e.Status == cleaner.StatusSkipped && prev.Entries[i].Status != cleaner.StatusSkipped {
+			next.Entries[i] = prev.Entries[i]
+		}
+	}
+	next.Duration += prev.Duration
+	return next
+}
+
+// mustDeleteFailures returns the failures of the mustDelete entries whose last outcome is a failure
+func mustDeleteFailures(result *cleaner.Result) error {
+	if result == nil {
+		return nil
+	}
+	var errs []error
+	for _, e := range result.Entries {
+		if e.Entry.MustDelete && e.Status == cleaner.StatusFailed {
+			errs = append(errs, &cleaner.MustDeleteError{Entry: e.Entry, Resources: e.Failed, Err: e.Err})
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+func TestCleanupWithRetries(t *testing.T) {
+	defaultAttempts, defaultInterval := runRetryAttempts, runRetryInterval
+	defer func() {
+		runRetryAttempts, runRetryInterval = defaultAttempts, defaultInterval
+	}()
+	runRetryInterval = 0
+
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	objs := []cleaner.DeleteObj{
+		{GroupVersionResource: gvr, Name: "a", Namespace: "default", MustDelete: true},
+		{GroupVersionResource: gvr, Name: "b", Namespace: "default", MustDelete: true},
+	}
+
+	tests := []struct {
+		name     string
+		attempts int
+		failures int
+
+		expectedErr     bool
+		expectedDeletes map[string]int
+	}{
+		{
+			name:            "succeeds on retry",
+			attempts:        2,
+			failures:        1,
+			expectedDeletes: map[string]int{"a": 1, "b": 2},
+		},
+		{
+			name:            "attempts exhausted",
+			attempts:        1,
+			failures:        2,
+			expectedErr:     true,
+			expectedDeletes: map[string]int{"a": 1, "b": 2},
+		},
+		{
+			name:            "no retries",
+			failures:        1,
+			expectedErr:     true,
+			expectedDeletes: map[string]int{"a": 1, "b": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runRetryAttempts = tt.attempts
+
+			metadataScheme := runtime.NewScheme()
+			metadataScheme.AddKnownTypeWithName(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, &metav1.PartialObjectMetadata{})
+			metadataScheme.AddKnownTypeWithName(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMapList"}, &metav1.PartialObjectMetadata{})
+			var cms []runtime.Object
+			for _, name := range []string{"a", "b"} {
+				cms = append(cms, &metav1.PartialObjectMetadata{
+					TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID("uid-" + name)},
+				})
+			}
+			metadataClient := metadatafake.NewSimpleMetadataClient(metadataScheme, cms...)
+			deletes := map[string]int{}
+			failures := tt.failures
+			metadataClient.PrependReactor("delete", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				name := action.(clienttesting.DeleteAction).GetName()
+				deletes[name]++
+				if name == "b" && failures > 0 {
+					failures--
+					return true, nil, context.DeadlineExceeded
+				}
+				return false, nil, nil
+			})
+
+			c := cleaner.New(cleaner.Options{MetadataClient: metadataClient, FailFast: true, Checkpoint: &checkpoint{}})
+			result, err := cleanupWithRetries(context.Background(), c, objs)
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			for name, expected := range tt.expectedDeletes {
+				if deletes[name] != expected {
+					t.Errorf("expected %d deletes of %s, got %d", expected, name, deletes[name])
+				}
+			}
+			if result.Entries[0].Status != cleaner.StatusSucceeded {
+				t.Errorf("expected first entry to keep its outcome, got %s", result.Entries[0].Status)
+			}
+		})
+	}
+}
+
+func TestCleanupWithRetriesKeepsFailures(t *testing.T) {
+	defaultAttempts, defaultInterval := runRetryAttempts, runRetryInterval
+	defer func() {
+		runRetryAttempts, runRetryInterval = defaultAttempts, defaultInterval
+	}()
+	runRetryAttempts, runRetryInterval = 1, 0
+
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	objs := []cleaner.DeleteObj{
+		{GroupVersionResource: gvr, Name: "a", Namespace: "default"},
+		{GroupVersionResource: gvr, Name: "b", Namespace: "default", MustDelete: true},
+	}
+	metadataScheme := runtime.NewScheme()
+	metadataScheme.AddKnownTypeWithName(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, &metav1.PartialObjectMetadata{})
+	metadataScheme.AddKnownTypeWithName(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMapList"}, &metav1.PartialObjectMetadata{})
+	var cms []runtime.Object
+	for _, name := range []string{"a", "b"} {
+		cms = append(cms, &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID("uid-" + name)},
+		})
+	}
+	metadataClient := metadatafake.NewSimpleMetadataClient(metadataScheme, cms...)
+
+	// both entries fail, and the retry is stopped while retrying a, so that it never reaches b
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	deletes := map[string]int{}
+	metadataClient.PrependReactor("delete", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		name := action.(clienttesting.DeleteAction).GetName()
+		deletes[name]++
+		if name == "a" && deletes[name] > 1 {
+			cancel()
+		}
+		return true, nil, context.DeadlineExceeded
+	})
+
+	c := cleaner.New(cleaner.Options{MetadataClient: metadataClient, Checkpoint: &checkpoint{}})
+	result, err := cleanupWithRetries(ctx, c, objs)
+	if !errors.As(err, new(*cleaner.MustDeleteError)) {
+		t.Errorf("expected a mustDelete failure, got %v", err)
+	}
+	if deletes["b"] != 1 {
+		t.Errorf("expected 1 delete of b, got %d", deletes["b"])
+	}
+	if result.Entries[1].Status != cleaner.StatusFailed {
+		t.Errorf("expected b to keep its failure, got %s", result.Entries[1].Status)
+	}
+}
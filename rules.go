@@ -0,0 +1,397 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+const (
+	RulesToApply = "rulesToApply"
+
+	// ExpiresAtAnnotation is an RFC 3339 timestamp after which a resource may be deleted
+	ExpiresAtAnnotation = "cleanup.spectrocloud.com/expires-at"
+	// TTLAnnotation is a duration, e.g., "24h", after the resource's creation at which it may be deleted
+	TTLAnnotation = "cleanup.spectrocloud.com/ttl"
+
+	// watchResyncPeriod is how often informers re-evaluate every cached object, which is
+	// what eventually deletes objects that only match a rule once they reach its minimum age
+	watchResyncPeriod = 1 * time.Minute
+)
+
+var namespacesGVR = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+
+// Rule selects K8s resources to be continuously cleaned up, e.g., Evicted pods or completed Jobs
+type Rule struct {
+	schema.GroupVersionResource
+
+	// Namespace restricts the rule to a single namespace. All namespaces are matched if empty.
+	Namespace string `json:"namespace,omitempty"`
+
+	// NamePattern restricts the rule to objects whose name matches a glob, e.g., "e2e-*"
+	NamePattern string `json:"namePattern,omitempty"`
+
+	// LabelSelector and FieldSelector are evaluated server side, e.g., "status.phase=Failed"
+	LabelSelector string `json:"labelSelector,omitempty"`
+	FieldSelector string `json:"fieldSelector,omitempty"`
+
+	// Conditions are evaluated client side and must all match
+	Conditions []RuleCondition `json:"conditions,omitempty"`
+
+	// MinAgeSeconds excludes objects created less than this many seconds ago
+	MinAgeSeconds int64 `json:"minAgeSeconds,omitempty"`
+
+	// Expired restricts the rule to objects whose expires-at or ttl annotation has elapsed
+	Expired bool `json:"expired,omitempty"`
+
+	// OwnerGone restricts the rule to objects whose owner no longer exists. See ownerGone.
+	OwnerGone bool `json:"ownerGone,omitempty"`
+}
+
+// RuleCondition matches objects whose field at the dot-separated Path, e.g., "status.reason",
+// equals any of Values. If Values is empty, the field need only be present. A path segment may select
+// the element of a list with a field of a given value, e.g., "status.conditions[type=Failed].status".
+type RuleCondition struct {
+	Path   string   `json:"path"`
+	Values []string `json:"values,omitempty"`
+
+	// Absent instead requires the field to be missing, e.g., a Lease's spec.renewTime if it was never renewed
+	Absent bool `json:"absent,omitempty"`
+
+	// OlderThanSeconds additionally requires the field to be an RFC 3339 timestamp,
+	// e.g., a Lease's spec.renewTime, at least this many seconds in the past
+	OlderThanSeconds int64 `json:"olderThanSeconds,omitempty"`
+}
+
+// String returns a human readable description of the rule, for logging
+func (r Rule) String() string {
+	s := r.GroupVersionResource.String()
+	if r.Namespace != "" {
+		s += fmt.Sprintf(", namespace=%s", r.Namespace)
+	}
+	if r.LabelSelector != "" {
+		s += fmt.Sprintf(", labelSelector=%s", r.LabelSelector)
+	}
+	if r.FieldSelector != "" {
+		s += fmt.Sprintf(", fieldSelector=%s", r.FieldSelector)
+	}
+	if r.NamePattern != "" {
+		s += fmt.Sprintf(", namePattern=%s", r.NamePattern)
+	}
+	return s
+}
+
+// matches reports whether an object satisfies the rule's client side conditions and minimum age
+func (r Rule) matches(obj *unstructured.Unstructured, now time.Time) bool {
+	if r.GroupVersionResource == namespacesGVR && isProtectedNamespace(obj.GetName()) {
+		return false
+	}
+	if r.NamePattern != "" {
+		if ok, _ := path.Match(r.NamePattern, obj.GetName()); !ok {
+			return false
+		}
+	}
+	if r.MinAgeSeconds > 0 {
+		age := now.Sub(obj.GetCreationTimestamp().Time)
+		if age < time.Duration(r.MinAgeSeconds)*time.Second {
+			return false
+		}
+	}
+	for _, c := range r.Conditions {
+		if !c.matches(obj, now) {
+			return false
+		}
+	}
+	if r.Expired && !isExpired(obj, now) {
+		return false
+	}
+	return true
+}
+
+// isExpired reports whether an object's expires-at or ttl annotation has elapsed. Objects
+// without either annotation, or with an invalid value, never expire.
+func isExpired(obj *unstructured.Unstructured, now time.Time) bool {
+	annotations := obj.GetAnnotations()
+	if expiresAt, ok := annotations[ExpiresAtAnnotation]; ok {
+		t, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			log.Info("WARNING: invalid expiry annotation", "annotation", ExpiresAtAnnotation, "value", expiresAt,
+				"name", obj.GetName(), "namespace", obj.GetNamespace())
+			return false
+		}
+		return !now.Before(t)
+	}
+	if ttl, ok := annotations[TTLAnnotation]; ok {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			log.Info("WARNING: invalid expiry annotation", "annotation", TTLAnnotation, "value", ttl,
+				"name", obj.GetName(), "namespace", obj.GetNamespace())
+			return false
+		}
+		return !now.Before(obj.GetCreationTimestamp().Add(d))
+	}
+	return false
+}
+
+// ownerGone reports whether an object's owner no longer exists. Objects with ownerReferences are left
+// to the garbage collector, which deletes them once their owners are gone. A Lease without any is owned
+// by the Pod named by its spec.holderIdentity, which client-go's leader election sets to the holder's
+// hostname, i.e., its Pod's name, optionally followed by an underscore and a unique suffix. The Pod is
+// looked up in the Lease's namespace. If no owner can be determined, e.g., for a Lease whose holder
+// released it, the owner is assumed to still exist, and the object is kept.
+func ownerGone(ctx context.Context, dynamic dynamic.Interface, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) bool {
+	if len(obj.GetOwnerReferences()) > 0 || gvr.GroupResource() != leasesGVR.GroupResource() {
+		return false
+	}
+	holder, _, _ := unstructured.NestedString(obj.Object, "spec", "holderIdentity")
+	pod, _, _ := strings.Cut(holder, "_")
+	if pod == "" || len(validation.IsDNS1123Subdomain(pod)) > 0 {
+		return false
+	}
+	_, err := dynamic.Resource(podsGVR).Namespace(obj.GetNamespace()).Get(ctx, pod, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return true
+	} else if err != nil {
+		log.Error(err, "failed to get Lease holder, keeping Lease", "name", obj.GetName(), "namespace", obj.GetNamespace(), "pod", pod)
+	}
+	return false
+}
+
+func (c RuleCondition) matches(obj *unstructured.Unstructured, now time.Time) bool {
+	val, found, err := nestedField(obj.Object, c.Path)
+	if c.Absent {
+		return err == nil && !found
+	}
+	if err != nil || !found {
+		return false
+	}
+	str := fmt.Sprint(val)
+	if c.OlderThanSeconds > 0 {
+		t, err := time.Parse(time.RFC3339, str)
+		if err != nil || now.Sub(t) < time.Duration(c.OlderThanSeconds)*time.Second {
+			return false
+		}
+	}
+	if len(c.Values) == 0 {
+		return true
+	}
+	for _, v := range c.Values {
+		if str == v {
+			return true
+		}
+	}
+	return false
+}
+
+// nestedField returns the field at a dot-separated path, whose segments may select the element of a
+// list with a field of a given value, e.g., "conditions[type=Failed]"
+func nestedField(obj map[string]interface{}, path string) (interface{}, bool, error) {
+	var val interface{} = obj
+	for _, segment := range strings.Split(path, ".") {
+		name, selector, _ := strings.Cut(strings.TrimSuffix(segment, "]"), "[")
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			return nil, false, fmt.Errorf("%s is not an object", name)
+		}
+		if val, ok = m[name]; !ok {
+			return nil, false, nil
+		}
+		if selector == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(selector, "=")
+		list, ok := val.([]interface{})
+		if !ok {
+			return nil, false, fmt.Errorf("%s is not a list", name)
+		}
+		val = nil
+		for _, item := range list {
+			if item, ok := item.(map[string]interface{}); ok && fmt.Sprint(item[key]) == value {
+				val = item
+				break
+			}
+		}
+		if val == nil {
+			return nil, false, nil
+		}
+	}
+	return val, true, nil
+}
+
+// isProtectedNamespace reports whether a namespace must never be deleted
+func isProtectedNamespace(name string) bool {
+	return slices.Contains(cleaner.SystemNamespaces, name) || slices.Contains(protectedNamespaces, name)
+}
+
+// readRuleConfig loads the rules specified in the rule config file, followed by those of any enabled presets
+func readRuleConfig() []Rule {
+	rules := []Rule{}
+	bytes := readConfig(ruleConfigPath, RulesToApply)
+	if bytes != nil {
+		if err := json.Unmarshal(bytes, &rules); err != nil {
+			panic(fmt.Errorf("%w: %w", cleaner.ErrConfigInvalid, err))
+		}
+		aliases := readAliases()
+		for i := range rules {
+			gvr, err := aliases.Resolve(rules[i].GroupVersionResource)
+			if err != nil {
+				panic(err)
+			}
+			rules[i].GroupVersionResource = gvr
+		}
+	}
+	return append(rules, presetRules()...)
+}
+
+// sweepRules lists the resources selected by each configured rule once, deleting those that match
+func sweepRules(ctx context.Context, dynamic dynamic.Interface) {
+	for _, rule := range readRuleConfig() {
+		log.Info("Sweeping resources matching rule", "rule", rule.String())
+		list, err := dynamic.Resource(rule.GroupVersionResource).Namespace(rule.Namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: rule.LabelSelector,
+			FieldSelector: rule.FieldSelector,
+		})
+		if err != nil {
+			log.Error(err, "failed to list resources", "rule", rule.String())
+			continue
+		}
+		now := time.Now()
+		for i := range list.Items {
+			obj := &list.Items[i]
+			if obj.GetDeletionTimestamp() != nil || !rule.matches(obj, now) {
+				continue
+			}
+			if rule.OwnerGone && !ownerGone(ctx, dynamic, rule.GroupVersionResource, obj) {
+				continue
+			}
+			if !policyAllows(ctx, rule.GroupVersionResource, obj) {
+				continue
+			}
+			deleteRuleMatch(ctx, dynamic, ruleMatch{
+				gvr: rule.GroupVersionResource, namespace: obj.GetNamespace(), name: obj.GetName(), uid: obj.GetUID(),
+			})
+		}
+	}
+}
+
+// ruleMatch identifies an object matched by a rule and queued for deletion
+type ruleMatch struct {
+	gvr       schema.GroupVersionResource
+	namespace string
+	name      string
+	uid       types.UID
+}
+
+// runWatch continuously deletes objects matching the configured rules as they appear. Deletions
+// are throttled by a token bucket rate limiter shared across all rules.
+func runWatch(ctx context.Context, dynamic dynamic.Interface) {
+	rules := readRuleConfig()
+	if len(rules) == 0 {
+		panic(fmt.Errorf("watch mode enabled, but no rules found in %s and no presets enabled", ruleConfigPath))
+	}
+
+	queue := workqueue.New()
+	defer queue.ShutDown()
+
+	for _, rule := range rules {
+		factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(
+			dynamic, watchResyncPeriod, rule.Namespace, func(opts *metav1.ListOptions) {
+				opts.LabelSelector = rule.LabelSelector
+				opts.FieldSelector = rule.FieldSelector
+			},
+		)
+		enqueue := func(o interface{}) {
+			obj, ok := o.(*unstructured.Unstructured)
+			if !ok || obj.GetDeletionTimestamp() != nil || !rule.matches(obj, time.Now()) {
+				return
+			}
+			if rule.OwnerGone && !ownerGone(ctx, dynamic, rule.GroupVersionResource, obj) {
+				return
+			}
+			if !policyAllows(ctx, rule.GroupVersionResource, obj) {
+				return
+			}
+			queue.Add(ruleMatch{gvr: rule.GroupVersionResource, namespace: obj.GetNamespace(), name: obj.GetName(), uid: obj.GetUID()})
+		}
+		informer := factory.ForResource(rule.GroupVersionResource).Informer()
+		if _, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    enqueue,
+			UpdateFunc: func(_, o interface{}) { enqueue(o) },
+		}); err != nil {
+			panic(err)
+		}
+		factory.Start(ctx.Done())
+		log.Info("Watching for resources matching rule", "rule", rule.String())
+	}
+
+	limiter := flowcontrol.NewTokenBucketRateLimiter(watchDeleteQPS, watchDeleteBurst)
+	go func() {
+		<-ctx.Done()
+		queue.ShutDown()
+	}()
+	for {
+		item, shutdown := queue.Get()
+		if shutdown {
+			return
+		}
+		m := item.(ruleMatch)
+		if err := limiter.Wait(ctx); err != nil {
+			queue.Done(item)
+			return
+		}
+		deleteRuleMatch(ctx, dynamic, m)
+		queue.Done(item)
+	}
+}
+
+// deleteRuleMatch deletes an object matched by a rule. The UID precondition ensures
+// a newer object recreated under the same name is never deleted by mistake.
+func deleteRuleMatch(ctx context.Context, dynamic dynamic.Interface, m ruleMatch) {
+	log.Info("Deleting resource matching rule", "name", m.name, "namespace", m.namespace, "gvr", m.gvr.String())
+	err := retryMutation(ctx, func(ctx context.Context) error {
+		return dynamic.Resource(m.gvr).Namespace(m.namespace).Delete(ctx, m.name, metav1.DeleteOptions{
+			PropagationPolicy: &propagationPolicy,
+			Preconditions:     &metav1.Preconditions{UID: &m.uid},
+		})
+	})
+	if err != nil {
+		if apierrors.IsNotFound(err) || apierrors.IsConflict(err) {
+			return
+		}
+		log.Error(err, "resource deletion failed")
+		return
+	}
+	log.Info("Resource deletion successful")
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestRuleMatches(t *testing.T) {
+	now := time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)
+
+	pod := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]interface{}{
+			"name":      "evicted-pod",
+			"namespace": "default",
+		},
+		"status": map[string]interface{}{
+			"phase":     "Failed",
+			"reason":    "Evicted",
+			"startTime": "2024-01-10T11:00:00.000000Z",
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": "False"},
+				map[string]interface{}{"type": "DisruptionTarget", "status": "True", "lastTransitionTime": "2024-01-10T11:00:00Z"},
+			},
+		},
+	}}
+	pod.SetCreationTimestamp(metav1.NewTime(now.Add(-1 * time.Hour)))
+
+	tests := []struct {
+		name     string
+		rule     Rule
+		expected bool
+	}{
+		{
+			name:     "no conditions",
+			rule:     Rule{},
+			expected: true,
+		},
+		{
+			name: "matching conditions",
+			rule: Rule{Conditions: []RuleCondition{
+				{Path: "status.phase", Values: []string{"Failed"}},
+				{Path: "status.reason", Values: []string{"Evicted", "Shutdown"}},
+			}},
+			expected: true,
+		},
+		{
+			name: "non-matching condition",
+			rule: Rule{Conditions: []RuleCondition{
+				{Path: "status.phase", Values: []string{"Failed"}},
+				{Path: "status.reason", Values: []string{"Shutdown"}},
+			}},
+			expected: false,
+		},
+		{
+			name:     "field present",
+			rule:     Rule{Conditions: []RuleCondition{{Path: "status.reason"}}},
+			expected: true,
+		},
+		{
+			name:     "missing field",
+			rule:     Rule{Conditions: []RuleCondition{{Path: "status.message", Values: []string{""}}}},
+			expected: false,
+		},
+		{
+			name:     "field absent",
+			rule:     Rule{Conditions: []RuleCondition{{Path: "status.message", Absent: true}}},
+			expected: true,
+		},
+		{
+			name:     "field not absent",
+			rule:     Rule{Conditions: []RuleCondition{{Path: "status.reason", Absent: true}}},
+			expected: false,
+		},
+		{
+			name:     "timestamp old enough",
+			rule:     Rule{Conditions: []RuleCondition{{Path: "status.startTime", OlderThanSeconds: 1800}}},
+			expected: true,
+		},
+		{
+			name:     "timestamp too recent",
+			rule:     Rule{Conditions: []RuleCondition{{Path: "status.startTime", OlderThanSeconds: 7200}}},
+			expected: false,
+		},
+		{
+			name:     "not a timestamp",
+			rule:     Rule{Conditions: []RuleCondition{{Path: "status.phase", OlderThanSeconds: 1}}},
+			expected: false,
+		},
+		{
+			name: "list element matches",
+			rule: Rule{Conditions: []RuleCondition{
+				{Path: "status.conditions[type=DisruptionTarget].status", Values: []string{"True"}},
+				{Path: "status.conditions[type=DisruptionTarget].lastTransitionTime", OlderThanSeconds: 1800},
+			}},
+			expected: true,
+		},
+		{
+			name:     "list element does not match",
+			rule:     Rule{Conditions: []RuleCondition{{Path: "status.conditions[type=Ready].status", Values: []string{"True"}}}},
+			expected: false,
+		},
+		{
+			name:     "list element missing",
+			rule:     Rule{Conditions: []RuleCondition{{Path: "status.conditions[type=PodScheduled].status"}}},
+			expected: false,
+		},
+		{
+			name:     "list element absent",
+			rule:     Rule{Conditions: []RuleCondition{{Path: "status.conditions[type=PodScheduled]", Absent: true}}},
+			expected: true,
+		},
+		{
+			name:     "not a list",
+			rule:     Rule{Conditions: []RuleCondition{{Path: "status.phase[type=Failed]"}}},
+			expected: false,
+		},
+		{
+			name:     "name pattern matches",
+			rule:     Rule{NamePattern: "evicted-*"},
+			expected: true,
+		},
+		{
+			name:     "name pattern does not match",
+			rule:     Rule{NamePattern: "e2e-*"},
+			expected: false,
+		},
+		{
+			name:     "old enough",
+			rule:     Rule{MinAgeSeconds: 1800},
+			expected: true,
+		},
+		{
+			name:     "too young",
+			rule:     Rule{MinAgeSeconds: 7200},
+			expected: false,
+		},
+		{
+			name:     "no expiry annotations",
+			rule:     Rule{Expired: true},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if matches := tt.rule.matches(pod, now); matches != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, matches)
+			}
+		})
+	}
+}
+
+func TestRuleMatchesProtectedNamespace(t *testing.T) {
+	protectedNamespaces = []string{"spectro-system"}
+	defer func() { protectedNamespaces = nil }()
+
+	rule := Rule{GroupVersionResource: namespacesGVR, NamePattern: "*"}
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{name: "kube-system", expected: false},
+		{name: "default", expected: false},
+		{name: "spectro-system", expected: false},
+		{name: "e2e-1234", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ns := &unstructured.Unstructured{Object: map[string]interface{}{}}
+			ns.SetName(tt.name)
+			if matches := rule.matches(ns, time.Now()); matches != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, matches)
+			}
+		})
+	}
+}
+
+func TestIsExpired(t *testing.T) {
+	now := time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expected    bool
+	}{
+		{
+			name:     "no annotations",
+			expected: false,
+		},
+		{
+			name:        "expires-at elapsed",
+			annotations: map[string]string{ExpiresAtAnnotation: "2024-01-10T11:59:59Z"},
+			expected:    true,
+		},
+		{
+			name:        "expires-at pending",
+			annotations: map[string]string{ExpiresAtAnnotation: "2024-01-10T12:00:01Z"},
+			expected:    false,
+		},
+		{
+			name:        "ttl elapsed",
+			annotations: map[string]string{TTLAnnotation: "30m"},
+			expected:    true,
+		},
+		{
+			name:        "ttl pending",
+			annotations: map[string]string{TTLAnnotation: "2h"},
+			expected:    false,
+		},
+		{
+			name:        "expires-at takes precedence over ttl",
+			annotations: map[string]string{ExpiresAtAnnotation: "2024-01-11T00:00:00Z", TTLAnnotation: "30m"},
+			expected:    false,
+		},
+		{
+			name:        "invalid value",
+			annotations: map[string]string{TTLAnnotation: "one day"},
+			expected:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+			obj.SetName("ephemeral")
+			obj.SetCreationTimestamp(metav1.NewTime(now.Add(-1 * time.Hour)))
+			obj.SetAnnotations(tt.annotations)
+
+			if expired := isExpired(obj, now); expired != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, expired)
+			}
+		})
+	}
+}
+
+func TestOwnerGone(t *testing.T) {
+	pod := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]interface{}{
+			"name":      "controller-7d4b9c-x2x9q",
+			"namespace": "default",
+		},
+	}}
+
+	tests := []struct {
+		name      string
+		holder    string
+		ownerRefs []metav1.OwnerReference
+		pods      []runtime.Object
+		expected  bool
+	}{
+		{
+			name:     "holder running",
+			holder:   "controller-7d4b9c-x2x9q_0f8f3f4e-0d4e-4b8e-9a3e-2f4c8e1a6b7d",
+			pods:     []runtime.Object{pod},
+			expected: false,
+		},
+		{
+			name:     "holder gone",
+			holder:   "controller-7d4b9c-x2x9q_0f8f3f4e-0d4e-4b8e-9a3e-2f4c8e1a6b7d",
+			expected: true,
+		},
+		{
+			name:     "holder gone without suffix",
+			holder:   "controller-7d4b9c-x2x9q",
+			expected: true,
+		},
+		{
+			name:     "no holder",
+			expected: false,
+		},
+		{
+			name:     "holder not a pod name",
+			holder:   "Controller On Host",
+			expected: false,
+		},
+		{
+			name:      "owned",
+			holder:    "controller-7d4b9c-x2x9q",
+			ownerRefs: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "controller", UID: "1234"}},
+			expected:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lease := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "coordination.k8s.io/v1",
+				"kind":       "Lease",
+				"spec":       map[string]interface{}{},
+			}}
+			lease.SetName("controller-leader-election")
+			lease.SetNamespace("default")
+			lease.SetOwnerReferences(tt.ownerRefs)
+			if tt.holder != "" {
+				if err := unstructured.SetNestedField(lease.Object, tt.holder, "spec", "holderIdentity"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			dynamic := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), tt.pods...)
+			if gone := ownerGone(context.Background(), dynamic, leasesGVR, lease); gone != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, gone)
+			}
+		})
+	}
+}
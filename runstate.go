@@ -0,0 +1,65 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// runCompleted reports whether a prior run recorded completing the resource config with the given
+// hash in the run-state ConfigMap, e.g., before it was restarted by a Job's backoff policy
+func runCompleted(ctx context.Context, client ctrlclient.Client, namespace, hash string) bool {
+	cm := &corev1.ConfigMap{}
+	key := types.NamespacedName{Namespace: namespace, Name: runStateConfigMap}
+	if err := client.Get(ctx, key, cm); err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Error(err, "failed to get run state", "configMap", runStateConfigMap, "namespace", namespace)
+		}
+		return false
+	}
+	completedAt, ok := cm.Data[hash]
+	if ok {
+		log.Info("Resource config already completed by a prior run", "configHash", hash, "completedAt", completedAt)
+	}
+	return ok
+}
+
+// recordRunCompleted records that the resource config with the given hash has been completed in the
+// run-state ConfigMap. The ConfigMap is owned by the spectro-cleanup Pod/DaemonSet/Job, so it is
+// garbage collected once spectro-cleanup has self destructed.
+func recordRunCompleted(ctx context.Context, client ctrlclient.Client, ownerRef metav1.OwnerReference, namespace, hash string) {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: runStateConfigMap, Namespace: namespace}}
+	_, err := controllerutil.CreateOrUpdate(ctx, client, cm, func() error {
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[hash] = time.Now().UTC().Format(time.RFC3339)
+		cm.OwnerReferences = []metav1.OwnerReference{ownerRef}
+		return nil
+	})
+	if err != nil {
+		log.Error(err, "failed to record run state", "configMap", runStateConfigMap, "namespace", namespace)
+		return
+	}
+	log.Info("Recorded run state", "configMap", runStateConfigMap, "namespace", namespace, "configHash", hash)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunState(t *testing.T) {
+	runStateConfigMap = "spectro-cleanup-run-state"
+	defer func() { runStateConfigMap = "" }()
+
+	ctx := context.Background()
+	client := fake.NewClientBuilder().WithScheme(scheme).Build()
+	owner := metav1.OwnerReference{APIVersion: "batch/v1", Kind: "Job", Name: "spectro-cleanup", UID: "uid"}
+
+	if runCompleted(ctx, client, "default", "hash") {
+		t.Error("expected no completed run before recording one")
+	}
+	recordRunCompleted(ctx, client, owner, "default", "hash")
+	if !runCompleted(ctx, client, "default", "hash") {
+		t.Error("expected a completed run after recording one")
+	}
+	if runCompleted(ctx, client, "default", "other-hash") {
+		t.Error("expected no completed run for a different resource config")
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	// a Wednesday
+	from := time.Date(2024, time.January, 10, 14, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		expr          string
+		expected      time.Time
+		expectedError bool
+	}{
+		{
+			name:     "daily at 3am",
+			expr:     "0 3 * * *",
+			expected: time.Date(2024, time.January, 11, 3, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "every 15 minutes",
+			expr:     "*/15 * * * *",
+			expected: time.Date(2024, time.January, 10, 14, 45, 0, 0, time.UTC),
+		},
+		{
+			name:     "next minute",
+			expr:     "* * * * *",
+			expected: time.Date(2024, time.January, 10, 14, 31, 0, 0, time.UTC),
+		},
+		{
+			name:     "sunday as 7",
+			expr:     "0 0 * * 7",
+			expected: time.Date(2024, time.January, 14, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekday range and hour list",
+			expr:     "30 9,17 * * 1-5",
+			expected: time.Date(2024, time.January, 10, 17, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "day of month or day of week",
+			expr:     "0 0 1 * 5",
+			expected: time.Date(2024, time.January, 12, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "leap day",
+			expr:     "0 0 29 2 *",
+			expected: time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "never fires",
+			expr:     "0 0 30 2 *",
+			expected: time.Time{},
+		},
+		{
+			name:          "too few fields",
+			expr:          "0 3 * *",
+			expectedError: true,
+		},
+		{
+			name:          "out of range",
+			expr:          "60 * * * *",
+			expectedError: true,
+		},
+		{
+			name:          "invalid step",
+			expr:          "*/0 * * * *",
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := parseCronSchedule(tt.expr)
+			if err != nil && !tt.expectedError {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if err == nil && tt.expectedError {
+				t.Fatalf("expected error, got nil")
+			}
+			if tt.expectedError {
+				return
+			}
+			if next := schedule.next(from); !next.Equal(tt.expected) {
+				t.Errorf("expected next %v, got %v", tt.expected, next)
+			}
+		})
+	}
+}
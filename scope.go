@@ -0,0 +1,145 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/restmapper"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+// resourceScopes records whether each resource served by the cluster is namespaced, the version
+// each resource is served at by its group's preferred version, or else by any other version, which
+// resources can be listed and deleted, and the resources of the kinds served
+type resourceScopes struct {
+	namespaced map[schema.GroupVersionResource]bool
+	preferred  map[schema.GroupResource]schema.GroupVersionResource
+	deletable  map[schema.GroupVersionResource]bool
+	mapper     meta.RESTMapper
+}
+
+// discoverScopes queries the discovery API once for the scope of every served resource. Groups
+// that fail discovery, e.g., those of an unavailable aggregated API, are omitted.
+func discoverScopes(dc discovery.DiscoveryInterface) *resourceScopes {
+	groups, lists, err := discovery.ServerGroupsAndResources(dc)
+	if err != nil {
+		if !discovery.IsGroupDiscoveryFailedError(err) {
+			log.Error(err, "resource discovery failed, resource scopes unknown")
+			return nil
+		}
+		log.Info("WARNING: discovery failed for some API groups", "error", err.Error())
+	}
+
+	preferredVersions := map[string]string{}
+	groupResources := map[string]*restmapper.APIGroupResources{}
+	for _, g := range groups {
+		preferredVersions[g.Name] = g.PreferredVersion.Version
+		groupResources[g.Name] = &restmapper.APIGroupResources{Group: *g, VersionedResources: map[string][]metav1.APIResource{}}
+	}
+	scopes := &resourceScopes{
+		namespaced: map[schema.GroupVersionResource]bool{},
+		preferred:  map[schema.GroupResource]schema.GroupVersionResource{},
+		deletable:  map[schema.GroupVersionResource]bool{},
+	}
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		if g, ok := groupResources[gv.Group]; ok {
+			g.VersionedResources[gv.Version] = list.APIResources
+		}
+		for _, r := range list.APIResources {
+			// skip subresources, e.g., pods/log
+			if strings.Contains(r.Name, "/") {
+				continue
+			}
+			gvr := gv.WithResource(r.Name)
+			scopes.namespaced[gvr] = r.Namespaced
+			scopes.deletable[gvr] = slices.Contains(r.Verbs, "list") && slices.Contains(r.Verbs, "delete")
+			if _, ok := scopes.preferred[gvr.GroupResource()]; !ok || gv.Version == preferredVersions[gv.Group] {
+				scopes.preferred[gvr.GroupResource()] = gvr
+			}
+		}
+	}
+	var served []*restmapper.APIGroupResources
+	for _, g := range groupResources {
+		served = append(served, g)
+	}
+	scopes.mapper = restmapper.NewDiscoveryRESTMapper(served)
+	return scopes
+}
+
+// resolve prepares resource config entries for the scope of their resources. Entries setting allResources
+// are expanded into an entry per namespaced resource that can be listed and deleted, and entries setting
+// manifests into an entry per object of the manifests. Namespaces are removed
+// from entries of cluster-scoped resources, so that delete-all entries list them once rather than
+// failing, and named entries of namespaced resources without a namespace get the default namespace.
+// Entries of versions the cluster no longer serves, e.g., removed beta versions, are updated to the
+// version the resource is still served at. Entries of resources whose scope is unknown are left untouched.
+func (s *resourceScopes) resolve(objs []cleaner.DeleteObj) []cleaner.DeleteObj {
+	if s == nil {
+		return objs
+	}
+	objs = cleaner.ExpandAllResources(objs, s.namespacedDeletable())
+	if s.mapper != nil {
+		expanded, err := cleaner.ExpandManifests(objs, s.mapper)
+		if err != nil {
+			panic(err)
+		}
+		objs = expanded
+	}
+	for i := range objs {
+		obj := &objs[i]
+		namespaced, ok := s.namespaced[obj.GroupVersionResource]
+		if !ok {
+			served, ok := s.preferred[obj.GroupVersionResource.GroupResource()]
+			if !ok {
+				continue
+			}
+			log.Info("WARNING: resource entry version not served, using the preferred version", "name", obj.Name,
+				"namespace", obj.Namespace, "gvr", obj.GroupVersionResource.String(), "version", served.Version)
+			obj.GroupVersionResource = served
+			namespaced = s.namespaced[served]
+		}
+		if !namespaced && obj.Namespace != "" {
+			log.Info("WARNING: ignoring namespace of cluster-scoped resource entry", "name", obj.Name,
+				"namespace", obj.Namespace, "gvr", obj.GroupVersionResource.String())
+			obj.Namespace = ""
+		}
+		if namespaced && obj.Namespace == "" && obj.Name != "" {
+			obj.Namespace = defaultNamespace
+		}
+	}
+	return objs
+}
+
+// namespacedDeletable returns the preferred versions of the namespaced resources that can be listed and deleted
+func (s *resourceScopes) namespacedDeletable() []schema.GroupVersionResource {
+	var gvrs []schema.GroupVersionResource
+	for _, gvr := range s.preferred {
+		if s.namespaced[gvr] && s.deletable[gvr] {
+			gvrs = append(gvrs, gvr)
+		}
+	}
+	return gvrs
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+func TestResourceScopesResolve(t *testing.T) {
+	defaultNamespace = "cleanup"
+	defer func() { defaultNamespace = "" }()
+
+	configMaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	clusterRoles := schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}
+	unknown := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	clusterRolesBeta := schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1beta1", Resource: "clusterroles"}
+	scopes := &resourceScopes{
+		namespaced: map[schema.GroupVersionResource]bool{configMaps: true, clusterRoles: false},
+		preferred: map[schema.GroupResource]schema.GroupVersionResource{
+			configMaps.GroupResource():   configMaps,
+			clusterRoles.GroupResource(): clusterRoles,
+		},
+	}
+
+	objs := []cleaner.DeleteObj{
+		{GroupVersionResource: configMaps, Name: "no-namespace"},
+		{GroupVersionResource: configMaps, Name: "explicit-namespace", Namespace: "other"},
+		{GroupVersionResource: configMaps, LabelSelector: "app=web"},
+		{GroupVersionResource: clusterRoles, Name: "cluster-scoped"},
+		{GroupVersionResource: clusterRoles, Name: "cluster-scoped-with-namespace", Namespace: "other"},
+		{GroupVersionResource: clusterRolesBeta, Name: "removed-version", Namespace: "other"},
+		{GroupVersionResource: unknown, Name: "unmapped"},
+		{GroupVersionResource: unknown, Name: "unmapped-with-namespace", Namespace: "other"},
+	}
+	expected := []string{"cleanup", "other", "", "", "", "", "", "other"}
+
+	objs = scopes.resolve(objs)
+	for i, obj := range objs {
+		if obj.Namespace != expected[i] {
+			t.Errorf("expected namespace %q for %s, got %q", expected[i], obj.Name, obj.Namespace)
+		}
+	}
+	if objs[5].GroupVersionResource != clusterRoles {
+		t.Errorf("expected %s for %s, got %s", clusterRoles, objs[5].Name, objs[5].GroupVersionResource)
+	}
+}
+
+func TestResourceScopesResolveAllResources(t *testing.T) {
+	configMaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	bindings := schema.GroupVersionResource{Version: "v1", Resource: "bindings"}
+	clusterRoles := schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}
+	scopes := &resourceScopes{
+		namespaced: map[schema.GroupVersionResource]bool{configMaps: true, bindings: true, clusterRoles: false},
+		preferred: map[schema.GroupResource]schema.GroupVersionResource{
+			configMaps.GroupResource():   configMaps,
+			bindings.GroupResource():     bindings,
+			clusterRoles.GroupResource(): clusterRoles,
+		},
+		deletable: map[schema.GroupVersionResource]bool{configMaps: true, bindings: false, clusterRoles: true},
+	}
+
+	objs := scopes.resolve([]cleaner.DeleteObj{{AllResources: true, Namespace: "widgets", LabelSelector: "app=widget"}})
+	expected := []cleaner.DeleteObj{{GroupVersionResource: configMaps, Namespace: "widgets", LabelSelector: "app=widget"}}
+	if !reflect.DeepEqual(objs, expected) {
+		t.Errorf("expected %v, got %v", expected, objs)
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+// cleanupCompleteCondition is the condition set on the status resource once the cleanup is done
+const cleanupCompleteCondition = "CleanupComplete"
+
+// parseStatusResource parses a CLEANUP_STATUS_RESOURCE reference, <resource>.<version>.<group>/<name>,
+// e.g., clusters.v1beta1.cluster.x-k8s.io/my-cluster. Core resources omit the group, e.g., configmaps.v1./my-config.
+func parseStatusResource(s string) (schema.GroupVersionResource, string, error) {
+	resource, name, ok := strings.Cut(s, "/")
+	gvr, _ := schema.ParseResourceArg(resource)
+	if !ok || name == "" || gvr == nil || gvr.Resource == "" || gvr.Version == "" {
+		return schema.GroupVersionResource{}, "", fmt.Errorf("%w: invalid status resource %q, expected <resource>.<version>.<group>/<name>", cleaner.ErrConfigInvalid, s)
+	}
+	return *gvr, name, nil
+}
+
+// reportCompletion sets the CleanupComplete condition of the status resource, if configured, summarizing
+// the result of the resource cleanup, so that the controller owning it is notified via the API. The
+// condition is False if the cleanup failed. Failing to report the completion doesn't fail the cleanup.
+func reportCompletion(ctx context.Context, dynamic dynamic.Interface, result *cleaner.Result, cleanupErr error) {
+	if statusName == "" {
+		return
+	}
+	condition := completionCondition(result, cleanupErr)
+	resource := dynamic.Resource(statusGVR).Namespace(statusNamespace)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := resource.Get(ctx, statusName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		conditions, _, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
+		if err != nil {
+			return err
+		}
+		condition["observedGeneration"] = obj.GetGeneration()
+		if err := unstructured.SetNestedSlice(obj.Object, setCondition(conditions, condition), "status", "conditions"); err != nil {
+			return err
+		}
+		_, err = resource.UpdateStatus(ctx, obj, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		log.Error(err, "failed to report cleanup completion", "gvr", statusGVR.String(), "name", statusName, "namespace", statusNamespace)
+		return
+	}
+	log.Info("Reported cleanup completion", "gvr", statusGVR.String(), "name", statusName, "namespace", statusNamespace,
+		"status", condition["status"], "message", condition["message"])
+}
+
+// completionCondition returns the CleanupComplete condition summarizing a resource cleanup. The result
+// is nil if a prior run already completed the resource config.
+func completionCondition(result *cleaner.Result, cleanupErr error) map[string]interface{} {
+	condition := map[string]interface{}{
+		"type":               cleanupCompleteCondition,
+		"status":             string(metav1.ConditionTrue),
+		"reason":             "CleanupSucceeded",
+		"lastTransitionTime": time.Now().UTC().Format(time.RFC3339),
+	}
+	switch {
+	case cleanupErr != nil:
+		condition["status"] = string(metav1.ConditionFalse)
+		condition["reason"] = "CleanupFailed"
+		condition["message"] = cleanupErr.Error()
+	case result == nil:
+		condition["message"] = "Cleanup already completed by a prior run"
+	default:
+		deleted := 0
+		for _, e := range result.Entries {
+			deleted += len(e.Deleted)
+		}
+		condition["message"] = fmt.Sprintf("%d entries succeeded, %d failed, %d skipped; %d resources deleted in %s",
+			result.Count(cleaner.StatusSucceeded), result.Count(cleaner.StatusFailed), result.Count(cleaner.StatusSkipped),
+			deleted, result.Duration.Round(time.Millisecond))
+	}
+	return condition
+}
+
+// setCondition replaces the condition of the same type in conditions, or else appends it. The
+// lastTransitionTime is retained unless the condition's status changes. Other conditions, and the
+// fields of conditions that don't follow metav1.Condition, are left untouched.
+func setCondition(conditions []interface{}, condition map[string]interface{}) []interface{} {
+	for i, c := range conditions {
+		existing, ok := c.(map[string]interface{})
+		if !ok || existing["type"] != condition["type"] {
+			continue
+		}
+		if existing["status"] == condition["status"] && existing["lastTransitionTime"] != nil {
+			condition["lastTransitionTime"] = existing["lastTransitionTime"]
+		}
+		conditions[i] = condition
+		return conditions
+	}
+	return append(conditions, condition)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+func TestParseStatusResource(t *testing.T) {
+	tests := []struct {
+		resource     string
+		expectedGVR  schema.GroupVersionResource
+		expectedName string
+		expectedErr  bool
+	}{
+		{
+			resource:     "clusters.v1beta1.cluster.x-k8s.io/my-cluster",
+			expectedGVR:  schema.GroupVersionResource{Group: "cluster.x-k8s.io", Version: "v1beta1", Resource: "clusters"},
+			expectedName: "my-cluster",
+		},
+		{
+			resource:     "configmaps.v1./my-config",
+			expectedGVR:  schema.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+			expectedName: "my-config",
+		},
+		{resource: "clusters.v1beta1.cluster.x-k8s.io", expectedErr: true},
+		{resource: "clusters.v1beta1.cluster.x-k8s.io/", expectedErr: true},
+		{resource: "clusters/my-cluster", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.resource, func(t *testing.T) {
+			gvr, name, err := parseStatusResource(tt.resource)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			if gvr != tt.expectedGVR || name != tt.expectedName {
+				t.Errorf("expected %v %s, got %v %s", tt.expectedGVR, tt.expectedName, gvr, name)
+			}
+		})
+	}
+}
+
+func TestReportCompletion(t *testing.T) {
+	defaultStatusGVR, defaultStatusName, defaultStatusNamespace := statusGVR, statusName, statusNamespace
+	defer func() {
+		statusGVR, statusName, statusNamespace = defaultStatusGVR, defaultStatusName, defaultStatusNamespace
+	}()
+	statusGVR = schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "environments"}
+	statusName, statusNamespace = "dev", "envs"
+
+	result := &cleaner.Result{Entries: []cleaner.EntryResult{
+		{Status: cleaner.StatusSucceeded, Deleted: []types.NamespacedName{{Name: "a"}, {Name: "b"}}},
+		{Status: cleaner.StatusFailed},
+	}}
+	tests := []struct {
+		name            string
+		result          *cleaner.Result
+		err             error
+		expectedStatus  string
+		expectedReason  string
+		expectedMessage string
+	}{
+		{
+			name:            "succeeded",
+			result:          result,
+			expectedStatus:  "True",
+			expectedReason:  "CleanupSucceeded",
+			expectedMessage: "1 entries succeeded, 1 failed, 0 skipped; 2 resources deleted in 0s",
+		},
+		{
+			name:            "failed",
+			result:          result,
+			err:             errors.New("must delete"),
+			expectedStatus:  "False",
+			expectedReason:  "CleanupFailed",
+			expectedMessage: "must delete",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := &unstructured.Unstructured{}
+			env.SetAPIVersion("example.com/v1")
+			env.SetKind("Environment")
+			env.SetName("dev")
+			env.SetNamespace("envs")
+			env.SetGeneration(3)
+			ready := map[string]interface{}{"type": "Ready", "status": "True", "severity": "Info"}
+			if err := unstructured.SetNestedSlice(env.Object, []interface{}{ready}, "status", "conditions"); err != nil {
+				t.Fatal(err)
+			}
+			dynamic := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), env)
+
+			reportCompletion(context.Background(), dynamic, tt.result, tt.err)
+
+			obj, err := dynamic.Resource(statusGVR).Namespace("envs").Get(context.Background(), "dev", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+			if len(conditions) != 2 {
+				t.Fatalf("expected 2 conditions, got %v", conditions)
+			}
+			if conditions[0].(map[string]interface{})["severity"] != "Info" {
+				t.Errorf("expected the Ready condition to be retained, got %v", conditions[0])
+			}
+			condition := conditions[1].(map[string]interface{})
+			if condition["type"] != cleanupCompleteCondition || condition["status"] != tt.expectedStatus || condition["reason"] != tt.expectedReason {
+				t.Errorf("expected %s condition %s %s, got %v", cleanupCompleteCondition, tt.expectedStatus, tt.expectedReason, condition)
+			}
+			if condition["message"] != tt.expectedMessage {
+				t.Errorf("expected message %q, got %q", tt.expectedMessage, condition["message"])
+			}
+			if condition["observedGeneration"] != int64(3) {
+				t.Errorf("expected observedGeneration 3, got %v", condition["observedGeneration"])
+			}
+		})
+	}
+}
+
+func TestSetCondition(t *testing.T) {
+	existing := func(status string) []interface{} {
+		return []interface{}{map[string]interface{}{"type": cleanupCompleteCondition, "status": status, "lastTransitionTime": "2024-01-01T00:00:00Z"}}
+	}
+
+	tests := []struct {
+		name               string
+		conditions         []interface{}
+		status             string
+		expectedTransition string
+	}{
+		{
+			name:               "unchanged status retains transition time",
+			conditions:         existing("True"),
+			status:             "True",
+			expectedTransition: "2024-01-01T00:00:00Z",
+		},
+		{
+			name:               "changed status updates transition time",
+			conditions:         existing("False"),
+			status:             "True",
+			expectedTransition: "2024-06-01T00:00:00Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition := map[string]interface{}{"type": cleanupCompleteCondition, "status": tt.status, "lastTransitionTime": "2024-06-01T00:00:00Z"}
+			conditions := setCondition(tt.conditions, condition)
+			if len(conditions) != 1 {
+				t.Fatalf("expected 1 condition, got %v", conditions)
+			}
+			if transition := conditions[0].(map[string]interface{})["lastTransitionTime"]; transition != tt.expectedTransition {
+				t.Errorf("expected %s, got %v", tt.expectedTransition, transition)
+			}
+		})
+	}
+}
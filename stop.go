@@ -0,0 +1,64 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Exit codes of a one-shot cleanup that was stopped before completing
+const (
+	// ExitCodeRunDeadline is the exit code when the maximum run duration elapses
+	ExitCodeRunDeadline = 3
+	// ExitCodeInterrupted is the exit code when spectro-cleanup receives SIGTERM or SIGINT,
+	// e.g., when its Pod is preempted or evicted
+	ExitCodeInterrupted = 4
+	// ExitCodeDeclined is the exit code when the plan of an interactive run isn't confirmed
+	ExitCodeDeclined = 5
+	// ExitCodeGuardUnmet is the exit code when a guard's condition doesn't hold, so that a Job
+	// retries the cleanup until the uninstall reaches the point the guards declare
+	ExitCodeGuardUnmet = 6
+)
+
+// withStop returns a context for a one-shot cleanup that is cancelled on SIGTERM or SIGINT,
+// and bounded by maxRunDuration, if set
+func withStop(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, stopSignals := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	if maxRunDuration <= 0 {
+		return ctx, stopSignals
+	}
+	ctx, cancel := context.WithTimeout(ctx, maxRunDuration)
+	return ctx, func() {
+		cancel()
+		stopSignals()
+	}
+}
+
+// exitIfStopped exits if a one-shot cleanup was interrupted, or its maximum run duration has
+// elapsed. By then, no further deletions are issued, and any in-flight ones have completed.
+func exitIfStopped(ctx context.Context) {
+	switch err := ctx.Err(); {
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Info("WARNING: maximum run duration elapsed before cleanup completed, exiting", "maxRunDuration", maxRunDuration.String())
+		os.Exit(ExitCodeRunDeadline)
+	case errors.Is(err, context.Canceled):
+		log.Info("WARNING: interrupted before cleanup completed, exiting")
+		os.Exit(ExitCodeInterrupted)
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+// parseTenantLabel parses a CLEANUP_TENANT_LABEL, key=value
+func parseTenantLabel(s string) (map[string]string, error) {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || len(validation.IsQualifiedName(key)) > 0 || value == "" || len(validation.IsValidLabelValue(value)) > 0 {
+		return nil, fmt.Errorf("%w: invalid tenant label %q, expected key=value", cleaner.ErrConfigInvalid, s)
+	}
+	return map[string]string{key: value}, nil
+}
+
+// tenantConflicts returns the configured cleanups that delete resources outside the resource config,
+// and so can't be confined to a tenant
+func tenantConflicts() []string {
+	var conflicts []string
+	scheduledRules := cleanupSchedule != nil && (configExists(ruleConfigPath) || len(presets) > 0)
+	for env, enabled := range map[string]bool{
+		"CLEANUP_WATCH_ENABLED":              enableWatch,
+		"CLEANUP_SCHEDULE with rules":        scheduledRules,
+		"CLEANUP_PLUGIN_CONFIG_PATH":         configExists(pluginConfigPath),
+		"CLEANUP_ORPHAN_DELETE_ENABLED":      deleteOrphans,
+		"CLEANUP_REPLICASET_HISTORY_LIMIT":   rsHistoryLimit >= 0,
+		"CLEANUP_HELM_HISTORY_LIMIT":         helmHistoryLimit >= 0,
+		"CLEANUP_PVC_DELETE_ENABLED":         deletePVCs,
+		"CLEANUP_NODE_PROVIDER_IDS":          len(nodeProviderIDs) > 0,
+		"CLEANUP_TLS_EXPIRED_DAYS":           tlsExpiredDays >= 0,
+		"CLEANUP_TLS_CERT_MANAGER_ENABLED":   tlsCertManager,
+		"CLEANUP_DANGLING_WEBHOOKS_ENABLED":  danglingWebhooks,
+		"CLEANUP_ORPHAN_APISERVICES_ENABLED": orphanAPIServices,
+	} {
+		if enabled {
+			conflicts = append(conflicts, env)
+		}
+	}
+	slices.Sort(conflicts)
+	return conflicts
+}
+
+// configExists reports whether a config file is present, i.e., whether it will be acted upon
+func configExists(path string) bool {
+	_, err := os.Stat(path)
+	return !errors.Is(err, fs.ErrNotExist)
+}
+
+// verifyFinalTenant ensures that the final entry, i.e., the spectro-cleanup Pod/DaemonSet/Job, carries the
+// tenant label, if set. Otherwise, the final entry would be skipped, and spectro-cleanup never self destruct.
+func verifyFinalTenant(ctx context.Context, dynamic dynamic.Interface, obj cleaner.DeleteObj) error {
+	if len(tenantLabels) == 0 {
+		return nil
+	}
+	final, err := dynamic.Resource(obj.GroupVersionResource).Namespace(obj.Namespace).Get(ctx, obj.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if !labels.SelectorFromSet(tenantLabels).Matches(labels.Set(final.GetLabels())) {
+		return fmt.Errorf("%w: the final entry %s %s/%s lacks the tenant label %s, so spectro-cleanup would never self destruct",
+			cleaner.ErrConfigInvalid, obj.GroupVersionResource.Resource, obj.Namespace, obj.Name, tenantLabelStr)
+	}
+	return nil
+}
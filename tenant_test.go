@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+func TestParseTenantLabel(t *testing.T) {
+	tests := []struct {
+		label       string
+		expected    map[string]string
+		expectedErr bool
+	}{
+		{label: "tenant=blue", expected: map[string]string{"tenant": "blue"}},
+		{label: "example.com/tenant=blue", expected: map[string]string{"example.com/tenant": "blue"}},
+		{label: "tenant", expectedErr: true},
+		{label: "tenant=", expectedErr: true},
+		{label: "=blue", expectedErr: true},
+		{label: "tenant=blue,green", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			labels, err := parseTenantLabel(tt.label)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			if !reflect.DeepEqual(labels, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, labels)
+			}
+		})
+	}
+}
+
+func TestTenantConflicts(t *testing.T) {
+	defaultSchedule, defaultRuleConfig, defaultPluginConfig := cleanupSchedule, ruleConfigPath, pluginConfigPath
+	defaultPresets, defaultTLSCertManager := presets, tlsCertManager
+	defaultRSLimit, defaultHelmLimit, defaultTLSDays := rsHistoryLimit, helmHistoryLimit, tlsExpiredDays
+	defer func() {
+		cleanupSchedule, ruleConfigPath, pluginConfigPath = defaultSchedule, defaultRuleConfig, defaultPluginConfig
+		presets, tlsCertManager = defaultPresets, defaultTLSCertManager
+		rsHistoryLimit, helmHistoryLimit, tlsExpiredDays = defaultRSLimit, defaultHelmLimit, defaultTLSDays
+	}()
+	rsHistoryLimit, helmHistoryLimit, tlsExpiredDays = -1, -1, -1
+
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(existing, []byte("[]"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.json")
+	schedule, err := parseCronSchedule("0 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		schedule       *cronSchedule
+		ruleConfig     string
+		pluginConfig   string
+		presets        []string
+		tlsCertManager bool
+		expected       []string
+	}{
+		{
+			name:         "resource config only",
+			schedule:     schedule,
+			ruleConfig:   missing,
+			pluginConfig: missing,
+		},
+		{
+			name:         "rule config without schedule",
+			ruleConfig:   existing,
+			pluginConfig: missing,
+		},
+		{
+			name:         "scheduled rule config",
+			schedule:     schedule,
+			ruleConfig:   existing,
+			pluginConfig: missing,
+			expected:     []string{"CLEANUP_SCHEDULE with rules"},
+		},
+		{
+			name:         "scheduled presets",
+			schedule:     schedule,
+			ruleConfig:   missing,
+			pluginConfig: missing,
+			presets:      []string{"completed-jobs"},
+			expected:     []string{"CLEANUP_SCHEDULE with rules"},
+		},
+		{
+			name:           "plugins and cert-manager",
+			ruleConfig:     missing,
+			pluginConfig:   existing,
+			tlsCertManager: true,
+			expected:       []string{"CLEANUP_PLUGIN_CONFIG_PATH", "CLEANUP_TLS_CERT_MANAGER_ENABLED"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleanupSchedule, ruleConfigPath, pluginConfigPath = tt.schedule, tt.ruleConfig, tt.pluginConfig
+			presets, tlsCertManager = tt.presets, tt.tlsCertManager
+			if conflicts := tenantConflicts(); !reflect.DeepEqual(conflicts, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, conflicts)
+			}
+		})
+	}
+}
+
+func TestVerifyFinalTenant(t *testing.T) {
+	defaultTenantLabels, defaultTenantLabelStr := tenantLabels, tenantLabelStr
+	defer func() { tenantLabels, tenantLabelStr = defaultTenantLabels, defaultTenantLabelStr }()
+	tenantLabels, tenantLabelStr = map[string]string{"tenant": "blue"}, "tenant=blue"
+
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		expectedErr bool
+	}{
+		{name: "labeled", labels: map[string]string{"tenant": "blue"}},
+		{name: "unlabeled", expectedErr: true},
+		{name: "other tenant", labels: map[string]string{"tenant": "green"}, expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &unstructured.Unstructured{}
+			job.SetAPIVersion("batch/v1")
+			job.SetKind("Job")
+			job.SetName("spectro-cleanup")
+			job.SetNamespace("default")
+			job.SetLabels(tt.labels)
+			dynamic := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), job)
+
+			obj := cleaner.DeleteObj{
+				GroupVersionResource: schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"},
+				Name:                 "spectro-cleanup",
+				Namespace:            "default",
+			}
+			err := verifyFinalTenant(context.Background(), dynamic, obj)
+			if errors.Is(err, cleaner.ErrConfigInvalid) != tt.expectedErr {
+				t.Errorf("expected error %v, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
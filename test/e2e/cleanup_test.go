@@ -0,0 +1,124 @@
+//go:build e2e
+
+package e2e_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/internal/e2e"
+	"github.com/spectrocloud-labs/spectro-cleanup/pkg/cleaner"
+)
+
+var (
+	configMapsGVR = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	widgetsGVR    = schema.GroupVersionResource{Group: "e2e.spectrocloud.com", Version: "v1", Resource: "widgets"}
+)
+
+func TestLabelSelector(t *testing.T) {
+	ns := cluster.Namespace(t)
+	cluster.Apply(t, ns, "testdata/configmaps.yaml")
+
+	entry := cleaner.DeleteObj{GroupVersionResource: configMapsGVR, Namespace: ns, LabelSelector: "app=a", MustDelete: true}
+	if _, err := cluster.Cleaner(cleaner.Options{}).CleanupResources(context.Background(), []cleaner.DeleteObj{entry}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for name, expected := range map[string]bool{"app-a": false, "app-a-config": false, "app-b": true} {
+		if exists := cluster.Exists(t, configMapsGVR, ns, name); exists != expected {
+			t.Errorf("expected %s to exist %v, got %v", name, expected, exists)
+		}
+	}
+}
+
+func TestClusterScoped(t *testing.T) {
+	cluster.Apply(t, "", "testdata/widgets.yaml")
+
+	entry := cleaner.DeleteObj{GroupVersionResource: widgetsGVR, LabelSelector: "e2e.spectrocloud.com/fixture=widgets", MustDelete: true}
+	result, err := cluster.Cleaner(cleaner.Options{}).CleanupResources(context.Background(), []cleaner.DeleteObj{entry})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if deleted := len(result.Entries[0].Deleted); deleted != 2 {
+		t.Errorf("expected 2 deleted, got %d", deleted)
+	}
+	if count := cluster.Count(t, widgetsGVR, "", "e2e.spectrocloud.com/fixture=widgets"); count != 0 {
+		t.Errorf("expected no widgets, got %d", count)
+	}
+}
+
+func TestManyResources(t *testing.T) {
+	const count = 600
+	ns := cluster.Namespace(t)
+	for i := 0; i < count; i++ {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("bulk-%d", i), Namespace: ns, Labels: map[string]string{"bulk": "true"}}}
+		if err := cluster.Client.Create(context.Background(), cm); err != nil {
+			t.Fatalf("failed to create ConfigMap: %v", err)
+		}
+	}
+
+	entry := cleaner.DeleteObj{GroupVersionResource: configMapsGVR, Namespace: ns, LabelSelector: "bulk=true", MustDelete: true, ConfirmHighRisk: true}
+	result, err := cluster.Cleaner(cleaner.Options{HighRiskThreshold: 100}).CleanupResources(context.Background(), []cleaner.DeleteObj{entry})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if deleted := len(result.Entries[0].Deleted); deleted != count {
+		t.Errorf("expected %d deleted, got %d", count, deleted)
+	}
+	if remaining := cluster.Count(t, configMapsGVR, ns, "bulk=true"); remaining != 0 {
+		t.Errorf("expected no ConfigMaps, got %d", remaining)
+	}
+}
+
+func TestFinalizers(t *testing.T) {
+	ns := cluster.Namespace(t)
+	cluster.Apply(t, ns, "testdata/finalizers.yaml")
+
+	// the finalizer blocks the deletion until the timeout
+	entry := cleaner.DeleteObj{GroupVersionResource: configMapsGVR, Namespace: ns, Name: "finalized", MustDelete: true}
+	c := cluster.Cleaner(cleaner.Options{DeletionTimeout: 2 * time.Second})
+	if _, err := c.CleanupResources(context.Background(), []cleaner.DeleteObj{entry}); !errors.Is(err, cleaner.ErrDeletionTimeout) {
+		t.Fatalf("expected %v, got %v", cleaner.ErrDeletionTimeout, err)
+	}
+	if !cluster.Exists(t, configMapsGVR, ns, "finalized") {
+		t.Fatal("expected finalized to remain until its finalizer is removed")
+	}
+
+	entry.Strategy = cleaner.StrategyFinalizerStrip
+	if _, err := c.CleanupResources(context.Background(), []cleaner.DeleteObj{entry}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cluster.Exists(t, configMapsGVR, ns, "finalized") {
+		t.Error("expected finalized to be deleted")
+	}
+}
+
+func TestOwnerReferences(t *testing.T) {
+	if !cluster.Existing() {
+		t.Skip("envtest runs no garbage collector, set USE_EXISTING_CLUSTER=true to run against a kind cluster")
+	}
+	ns := cluster.Namespace(t)
+	owner := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "owner", Namespace: ns}}
+	if err := cluster.Client.Create(context.Background(), owner); err != nil {
+		t.Fatalf("failed to create owner: %v", err)
+	}
+	dependent := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "dependent", Namespace: ns, OwnerReferences: []metav1.OwnerReference{
+		{APIVersion: "v1", Kind: "ConfigMap", Name: owner.Name, UID: owner.UID},
+	}}}
+	if err := cluster.Client.Create(context.Background(), dependent); err != nil {
+		t.Fatalf("failed to create dependent: %v", err)
+	}
+
+	entry := cleaner.DeleteObj{GroupVersionResource: configMapsGVR, Namespace: ns, Name: "owner", MustDelete: true}
+	if _, err := cluster.Cleaner(cleaner.Options{}).CleanupResources(context.Background(), []cleaner.DeleteObj{entry}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	e2e.Eventually(t, time.Minute, func() bool { return !cluster.Exists(t, configMapsGVR, ns, "dependent") },
+		"expected the dependent to be garbage collected")
+}
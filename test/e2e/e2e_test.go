@@ -0,0 +1,28 @@
+//go:build e2e
+
+package e2e_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/spectrocloud-labs/spectro-cleanup/internal/e2e"
+)
+
+// cluster is the API server shared by every end-to-end test
+var cluster *e2e.Cluster
+
+func TestMain(m *testing.M) {
+	var err error
+	cluster, err = e2e.Start("testdata/crds")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	if err := cluster.Stop(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,172 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// UnusedSinceAnnotation records when a PVC was first observed not to be mounted by any Pod.
+// It is removed again once a Pod mounts the claim.
+const UnusedSinceAnnotation = "cleanup.spectrocloud.com/unused-since"
+
+// cleanupUnusedPVCs reports, and optionally deletes, the PVCs in each target namespace that
+// have not been mounted by any Pod for at least pvcUnusedSeconds. Claims belonging to a
+// StatefulSet are never considered unused, as they are retained across scale downs by design.
+func cleanupUnusedPVCs(ctx context.Context, client ctrlclient.Client) {
+	for _, ns := range pvcNamespaces {
+		log.Info("Searching for unused PVCs", "namespace", ns)
+		inNs := ctrlclient.InNamespace(ns)
+
+		pods := &corev1.PodList{}
+		if err := client.List(ctx, pods, inNs); err != nil {
+			log.Error(err, "failed to list Pods", "namespace", ns)
+			continue
+		}
+		mounted := mountedClaims(pods.Items)
+
+		statefulSets := &appsv1.StatefulSetList{}
+		if err := client.List(ctx, statefulSets, inNs); err != nil {
+			log.Error(err, "failed to list StatefulSets", "namespace", ns)
+			continue
+		}
+
+		pvcs := &corev1.PersistentVolumeClaimList{}
+		if err := client.List(ctx, pvcs, inNs); err != nil {
+			log.Error(err, "failed to list PVCs", "namespace", ns)
+			continue
+		}
+
+		now := time.Now()
+		for i := range pvcs.Items {
+			pvc := &pvcs.Items[i]
+			if pvc.DeletionTimestamp != nil || isStatefulSetClaim(pvc, statefulSets.Items) {
+				continue
+			}
+			if mounted.Has(pvc.Name) {
+				if _, ok := pvc.Annotations[UnusedSinceAnnotation]; ok {
+					setUnusedSince(ctx, client, pvc, "")
+				}
+				continue
+			}
+			handleUnusedPVC(ctx, client, pvc, now)
+		}
+	}
+}
+
+// handleUnusedPVC marks a PVC that is not mounted by any Pod, reporting or deleting it once
+// it has been unused for at least pvcUnusedSeconds
+func handleUnusedPVC(ctx context.Context, client ctrlclient.Client, pvc *corev1.PersistentVolumeClaim, now time.Time) {
+	since, ok := unusedSince(pvc)
+	if !ok {
+		since = now
+		if !setUnusedSince(ctx, client, pvc, now.UTC().Format(time.RFC3339)) {
+			return
+		}
+	}
+	unusedFor := now.Sub(since)
+	if unusedFor < time.Duration(pvcUnusedSeconds)*time.Second {
+		return
+	}
+
+	log.Info("Found unused PVC", "pvc", pvc.Name, "namespace", pvc.Namespace, "unusedFor", unusedFor.Round(time.Second).String())
+	if !deletePVCs || !policyAllowsObject(ctx, client, pvc) {
+		return
+	}
+	if err := retryMutation(ctx, func(ctx context.Context) error { return client.Delete(ctx, pvc) }); ctrlclient.IgnoreNotFound(err) != nil {
+		log.Error(err, "PVC deletion failed", "pvc", pvc.Name, "namespace", pvc.Namespace)
+		return
+	}
+	log.Info("PVC deletion successful", "pvc", pvc.Name, "namespace", pvc.Namespace)
+}
+
+// setUnusedSince sets the unused-since annotation of a PVC, or removes it if value is empty
+func setUnusedSince(ctx context.Context, client ctrlclient.Client, pvc *corev1.PersistentVolumeClaim, value string) bool {
+	patch := ctrlclient.MergeFrom(pvc.DeepCopy())
+	if value == "" {
+		delete(pvc.Annotations, UnusedSinceAnnotation)
+	} else {
+		metav1.SetMetaDataAnnotation(&pvc.ObjectMeta, UnusedSinceAnnotation, value)
+	}
+	if err := client.Patch(ctx, pvc, patch); err != nil {
+		log.Error(err, "failed to annotate PVC", "pvc", pvc.Name, "namespace", pvc.Namespace)
+		return false
+	}
+	return true
+}
+
+// unusedSince parses a PVC's unused-since annotation
+func unusedSince(pvc *corev1.PersistentVolumeClaim) (time.Time, bool) {
+	value, ok := pvc.Annotations[UnusedSinceAnnotation]
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		log.Info("WARNING: invalid unused-since annotation, resetting", "value", value, "pvc", pvc.Name, "namespace", pvc.Namespace)
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// mountedClaims returns the names of the PVCs mounted by any non-terminated Pod
+func mountedClaims(pods []corev1.Pod) sets.Set[string] {
+	claims := sets.New[string]()
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for _, v := range pod.Spec.Volumes {
+			if v.PersistentVolumeClaim != nil {
+				claims.Insert(v.PersistentVolumeClaim.ClaimName)
+			}
+			if v.Ephemeral != nil {
+				claims.Insert(pod.Name + "-" + v.Name)
+			}
+		}
+	}
+	return claims
+}
+
+// isStatefulSetClaim reports whether a PVC is owned by a StatefulSet, or was created from one
+// of a StatefulSet's volumeClaimTemplates, i.e., is named <template>-<statefulset>-<ordinal>
+func isStatefulSetClaim(pvc *corev1.PersistentVolumeClaim, statefulSets []appsv1.StatefulSet) bool {
+	for _, ref := range pvc.OwnerReferences {
+		if ref.Kind == "StatefulSet" {
+			return true
+		}
+	}
+	for _, sts := range statefulSets {
+		for _, tmpl := range sts.Spec.VolumeClaimTemplates {
+			ordinal, ok := strings.CutPrefix(pvc.Name, tmpl.Name+"-"+sts.Name+"-")
+			if !ok {
+				continue
+			}
+			if _, err := strconv.ParseUint(ordinal, 10, 32); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
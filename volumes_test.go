@@ -0,0 +1,90 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func TestMountedClaims(t *testing.T) {
+	claimVolume := func(claim string) corev1.Volume {
+		return corev1.Volume{Name: "data", VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim},
+		}}
+	}
+	pods := []corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "running"},
+			Spec:       corev1.PodSpec{Volumes: []corev1.Volume{claimVolume("in-use")}},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pending"},
+			Spec: corev1.PodSpec{Volumes: []corev1.Volume{
+				{Name: "scratch", VolumeSource: corev1.VolumeSource{Ephemeral: &corev1.EphemeralVolumeSource{}}},
+			}},
+			Status: corev1.PodStatus{Phase: corev1.PodPending},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "completed"},
+			Spec:       corev1.PodSpec{Volumes: []corev1.Volume{claimVolume("released")}},
+			Status:     corev1.PodStatus{Phase: corev1.PodSucceeded},
+		},
+	}
+
+	expected := []string{"in-use", "pending-scratch"}
+	if got := sets.List(mountedClaims(pods)); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestIsStatefulSetClaim(t *testing.T) {
+	statefulSets := []appsv1.StatefulSet{{
+		ObjectMeta: metav1.ObjectMeta{Name: "db"},
+		Spec: appsv1.StatefulSetSpec{VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
+			{ObjectMeta: metav1.ObjectMeta{Name: "data"}},
+		}},
+	}}
+
+	tests := []struct {
+		name     string
+		pvc      corev1.PersistentVolumeClaim
+		expected bool
+	}{
+		{
+			name:     "claim template",
+			pvc:      corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data-db-2"}},
+			expected: true,
+		},
+		{
+			name: "owned by statefulset",
+			pvc: corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+				Name:            "data-cache-0",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "StatefulSet", Name: "cache"}},
+			}},
+			expected: true,
+		},
+		{
+			name:     "non-numeric ordinal",
+			pvc:      corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data-db-backup"}},
+			expected: false,
+		},
+		{
+			name:     "standalone claim",
+			pvc:      corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "scratch"}},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isStatefulSetClaim(&tt.pvc, statefulSets); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// cleanupDanglingWebhooks deletes Validating/MutatingWebhookConfigurations whose webhooks are all
+// backed by Services that no longer exist. With failurePolicy Fail, such configurations reject
+// every matching API request, which commonly breaks clusters after a partial uninstall.
+func cleanupDanglingWebhooks(ctx context.Context, client ctrlclient.Client) {
+	if !danglingWebhooks {
+		return
+	}
+
+	log.Info("Searching for dangling webhook configurations")
+	serviceExists := func(ref *admissionregistrationv1.ServiceReference) (bool, error) {
+		err := client.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, &corev1.Service{})
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	validating := &admissionregistrationv1.ValidatingWebhookConfigurationList{}
+	if err := client.List(ctx, validating); err != nil {
+		log.Error(err, "failed to list ValidatingWebhookConfigurations")
+	}
+	for i := range validating.Items {
+		cfg := &validating.Items[i]
+		clientConfigs := make([]admissionregistrationv1.WebhookClientConfig, 0, len(cfg.Webhooks))
+		for _, w := range cfg.Webhooks {
+			clientConfigs = append(clientConfigs, w.ClientConfig)
+		}
+		handleWebhookConfiguration(ctx, client, cfg, "validatingWebhookConfiguration", clientConfigs, serviceExists)
+	}
+
+	mutating := &admissionregistrationv1.MutatingWebhookConfigurationList{}
+	if err := client.List(ctx, mutating); err != nil {
+		log.Error(err, "failed to list MutatingWebhookConfigurations")
+	}
+	for i := range mutating.Items {
+		cfg := &mutating.Items[i]
+		clientConfigs := make([]admissionregistrationv1.WebhookClientConfig, 0, len(cfg.Webhooks))
+		for _, w := range cfg.Webhooks {
+			clientConfigs = append(clientConfigs, w.ClientConfig)
+		}
+		handleWebhookConfiguration(ctx, client, cfg, "mutatingWebhookConfiguration", clientConfigs, serviceExists)
+	}
+}
+
+// handleWebhookConfiguration deletes a webhook configuration if it is dangling
+func handleWebhookConfiguration(ctx context.Context, client ctrlclient.Client, cfg ctrlclient.Object, kind string,
+	clientConfigs []admissionregistrationv1.WebhookClientConfig, serviceExists func(*admissionregistrationv1.ServiceReference) (bool, error)) {
+	if cfg.GetDeletionTimestamp() != nil {
+		return
+	}
+	dangling, err := isDangling(clientConfigs, serviceExists)
+	if err != nil {
+		log.Error(err, "failed to check webhook Services", kind, cfg.GetName())
+		return
+	}
+	if !dangling || !policyAllowsObject(ctx, client, cfg) {
+		return
+	}
+
+	log.Info("Deleting dangling webhook configuration", kind, cfg.GetName())
+	if err := retryMutation(ctx, func(ctx context.Context) error { return client.Delete(ctx, cfg) }); ctrlclient.IgnoreNotFound(err) != nil {
+		log.Error(err, "webhook configuration deletion failed", kind, cfg.GetName())
+		return
+	}
+	log.Info("Webhook configuration deletion successful", kind, cfg.GetName())
+}
+
+// isDangling reports whether every webhook is backed by a Service that no longer exists. Webhooks
+// called via URL cannot be checked, so a configuration with any such webhook is never dangling.
+func isDangling(clientConfigs []admissionregistrationv1.WebhookClientConfig,
+	serviceExists func(*admissionregistrationv1.ServiceReference) (bool, error)) (bool, error) {
+	if len(clientConfigs) == 0 {
+		return false, nil
+	}
+	for _, c := range clientConfigs {
+		if c.Service == nil {
+			return false, nil
+		}
+		exists, err := serviceExists(c.Service)
+		if err != nil || exists {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+)
+
+func TestIsDangling(t *testing.T) {
+	url := "https://webhook.example.com/validate"
+	serviceConfig := func(name string) admissionregistrationv1.WebhookClientConfig {
+		return admissionregistrationv1.WebhookClientConfig{
+			Service: &admissionregistrationv1.ServiceReference{Namespace: "system", Name: name},
+		}
+	}
+	serviceExists := func(ref *admissionregistrationv1.ServiceReference) (bool, error) {
+		switch ref.Name {
+		case "live":
+			return true, nil
+		case "unreachable":
+			return false, errors.New("connection refused")
+		}
+		return false, nil
+	}
+
+	tests := []struct {
+		name          string
+		clientConfigs []admissionregistrationv1.WebhookClientConfig
+		expected      bool
+		expectedError bool
+	}{
+		{
+			name:          "all services missing",
+			clientConfigs: []admissionregistrationv1.WebhookClientConfig{serviceConfig("gone"), serviceConfig("also-gone")},
+			expected:      true,
+		},
+		{
+			name:          "one service exists",
+			clientConfigs: []admissionregistrationv1.WebhookClientConfig{serviceConfig("gone"), serviceConfig("live")},
+			expected:      false,
+		},
+		{
+			name:          "url webhook",
+			clientConfigs: []admissionregistrationv1.WebhookClientConfig{serviceConfig("gone"), {URL: &url}},
+			expected:      false,
+		},
+		{
+			name:          "no webhooks",
+			clientConfigs: nil,
+			expected:      false,
+		},
+		{
+			name:          "lookup error",
+			clientConfigs: []admissionregistrationv1.WebhookClientConfig{serviceConfig("unreachable")},
+			expected:      false,
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dangling, err := isDangling(tt.clientConfigs, serviceExists)
+			if err != nil && !tt.expectedError {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if err == nil && tt.expectedError {
+				t.Fatalf("expected error, got nil")
+			}
+			if dangling != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, dangling)
+			}
+		})
+	}
+}